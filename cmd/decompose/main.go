@@ -5,17 +5,21 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/s0rg/set"
 
@@ -23,6 +27,7 @@ import (
 	"github.com/s0rg/decompose/internal/client"
 	"github.com/s0rg/decompose/internal/cluster"
 	"github.com/s0rg/decompose/internal/graph"
+	"github.com/s0rg/decompose/internal/node"
 )
 
 const (
@@ -33,6 +38,9 @@ const (
 	defaultProto  = "all"
 	defaultOutput = "-"
 	defaultDiff   = 3
+	defaultWorker = 4
+	defaultSample = 10 * time.Second
+	dockerHostEnv = "DOCKER_HOST"
 )
 
 // build-time values.
@@ -47,14 +55,32 @@ var (
 	fHelp, fLocal        bool
 	fNoLoops, fNoOrphans bool
 	fDeep, fCompress     bool
+	fPodman, fBare       bool
+	fInbound, fShares    bool
+	fCompose, fMesh      bool
+	fConntrack, fSchema  bool
+	fHeader              bool
 	fProto, fFormat      string
 	fOut, fFollow        string
 	fMeta, fCluster      string
-	fSkipEnv             string
-	fLoad                []string
+	fSkipEnv, fChain     string
+	fSidecar, fCRI       string
+	fHostsFile, fProxies string
+	fStates              string
+	fSince, fUntil       string
+	fDiff, fDiffReport   string
+	fWorkers, fSamples   int
+	fTimeout, fCTimeout  time.Duration
+	fInterval            time.Duration
+	fMinConfidence       float64
+	fLoad, fHosts        []string
 
 	knownBuilders string
 	ErrUnknown    = errors.New("unknown")
+	ErrNotRoot    = errors.New("linux root required")
+	ErrNotLinux   = errors.New("linux required")
+	ErrNoLoad     = errors.New("-load required")
+	ErrNoLocal    = errors.New("local docker host is not scanned")
 )
 
 func version() string {
@@ -84,15 +110,25 @@ func setupFlags() {
 	flag.BoolVar(&fSilent, "silent", false, "suppress progress messages in stderr")
 	flag.BoolVar(&fVersion, "version", false, "show version")
 	flag.BoolVar(&fHelp, "help", false, "show this help")
+	flag.BoolVar(&fSchema, "schema", false, "show json schema of stream records")
+	flag.BoolVar(&fHeader, "header", false, "start json stream with header record (format version and provenance)")
 	flag.BoolVar(&fLocal, "local", false, "skip external hosts")
 	flag.BoolVar(&fNoLoops, "no-loops", false, "remove connection loops (node to itself) from output")
 	flag.BoolVar(&fNoOrphans, "no-orphans", false, "remove orphaned (not connected) nodes from output")
 	flag.BoolVar(&fDeep, "deep", false, "process-based introspection")
+	flag.BoolVar(&fInbound, "inbound", false, "keep inbound connections from external clients as edges")
+	flag.BoolVar(&fShares, "shared-volumes", false, "connect containers, that mount same volume or bind path")
 	flag.BoolVar(&fCompress, "compress", false, "compress graph")
+	flag.BoolVar(&fConntrack, "conntrack", false, "add NATed and short-lived flows from host conntrack table (linux root only)")
+	flag.BoolVar(&fMesh, "mesh", false, "collapse service-mesh sidecar hops into direct edges")
+	flag.BoolVar(&fCompose, "compose", false, "group compose replicas and swarm tasks into services, projects and stacks become clusters")
+	flag.BoolVar(&fBare, "bare", false, "no container runtime: group host processes by network namespaces (linux only)")
+	flag.BoolVar(&fPodman, "podman", false, "use podman api socket (CONTAINER_HOST or rootless/rootful default) and pods info")
 
 	flag.StringVar(&fOut, "out", defaultOutput, "output: filename or \"-\" for stdout")
 	flag.StringVar(&fMeta, "meta", "", "json file with metadata for enrichment")
 	flag.StringVar(&fProto, "proto", defaultProto, "protocol to scan: tcp,udp,unix or all")
+	flag.StringVar(&fStates, "states", "", "keep tcp connections in these transient states, i.e. syn_sent,time_wait, comma-separated or all")
 	flag.StringVar(&fFollow, "follow", "", "follow only this container by name(s), comma-separated or from @file")
 	flag.StringVar(
 		&fCluster,
@@ -102,6 +138,41 @@ func setupFlags() {
 			"similarity is float in (0.0, 1.0] range",
 	)
 
+	flag.StringVar(
+		&fChain,
+		"exec-chain",
+		strings.Join(client.DefaultStrategies, ","),
+		"in-container (non-root mode) strategies to try, in order, comma-separated",
+	)
+	flag.StringVar(
+		&fSidecar,
+		"sidecar",
+		"",
+		"helper image (with netstat/ss inside) to attach to every container, i.e. "+client.DefaultSidecarImage+
+			", allows scanning of scratch/distroless images without root",
+	)
+	flag.StringVar(
+		&fCRI,
+		"cri",
+		"",
+		"scan kubernetes node via cri runtime endpoint, i.e. "+client.DefaultCRIHost+" (linux root only)",
+	)
+	flag.IntVar(&fWorkers, "workers", defaultWorker, "number of containers to scan concurrently")
+	flag.DurationVar(&fTimeout, "timeout", 0, "scan deadline, partial graph is written on expiration, 0 - no limit")
+	flag.DurationVar(&fCTimeout, "container-timeout", 0, "per-container scan deadline, 0 - no limit")
+	flag.IntVar(&fSamples, "samples", 1, "number of scans to merge, catches intermittent connections")
+	flag.DurationVar(&fInterval, "interval", defaultSample, "pause between samples")
+	flag.StringVar(&fDiff, "diff", "", "baseline json stream to compare -load results with, graph marks added and removed elements")
+	flag.StringVar(&fDiffReport, "diff-report", "", "write -diff change report to file: markdown for .md names, json otherwise")
+	flag.StringVar(&fSince, "since", "", "for -load: skip nodes and edges, last seen before: RFC3339 time, date or duration ago, i.e. 72h")
+	flag.StringVar(&fUntil, "until", "", "for -load: skip nodes and edges, first seen after: RFC3339 time, date or duration ago")
+	flag.Float64Var(
+		&fMinConfidence,
+		"min-confidence",
+		0,
+		"for -load of several snapshots: drop edges, seen in lesser share of them, float in [0.0, 1.0] range",
+	)
+
 	flag.StringVar(&fFormat, "format", builder.KindJSON, "output format: "+knownBuilders)
 
 	flag.StringVar(
@@ -111,6 +182,19 @@ func setupFlags() {
 		"environment variables name(s) to skip from output, case-independent, comma-separated",
 	)
 
+	flag.StringVar(
+		&fProxies,
+		"mesh-proxies",
+		strings.Join(graph.DefaultMeshProxies, ","),
+		"mesh sidecar process, container or image names for -mesh, comma-separated",
+	)
+	flag.StringVar(&fHostsFile, "hosts-file", "", "file with docker hosts to scan, one per line")
+	flag.Func("host", "docker host to scan, i.e. tcp://10.0.0.2:2376, can be used multiple times", func(v string) error {
+		fHosts = append(fHosts, v)
+
+		return nil
+	})
+
 	flag.Func("load", "load json stream, can be used multiple times", func(v string) error {
 		res, err := filepath.Glob(v)
 		if err != nil {
@@ -252,6 +336,26 @@ func loadSet(v string) (rv set.Unordered[string]) {
 	return rv
 }
 
+// parseTime parses time window bound: RFC3339 time, date or duration before now.
+func parseTime(v string) (rv time.Time, ok bool) {
+	if v == "" {
+		return rv, true
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return rv, false
+	}
+
+	return time.Now().Add(-d), true
+}
+
 func prepareConfig() (
 	cfg *graph.Config,
 	nwr graph.NamedWriter,
@@ -269,11 +373,44 @@ func prepareConfig() (
 
 	nwr = bildr
 
+	var header *node.Header
+
+	if hb, ok := bildr.(graph.HeaderBuilder); ok && fHeader {
+		header = &node.Header{
+			Schema:  node.SchemaVersion,
+			Version: GitTag,
+		}
+
+		hb.SetHeader(header)
+	}
+
 	proto, ok := graph.ParseNetProto(fProto)
 	if !ok {
 		return nil, nil, fmt.Errorf("%w protocol: %s", ErrUnknown, fProto)
 	}
 
+	var states set.Unordered[string]
+
+	if fStates != "" {
+		if states, ok = graph.ParseTCPStates(fStates); !ok {
+			return nil, nil, fmt.Errorf("%w states: %s", ErrUnknown, fStates)
+		}
+	}
+
+	since, ok := parseTime(fSince)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w since: %s", ErrUnknown, fSince)
+	}
+
+	until, ok := parseTime(fUntil)
+	if !ok || (!until.IsZero() && until.Before(since)) {
+		return nil, nil, fmt.Errorf("%w until: %s", ErrUnknown, fUntil)
+	}
+
+	if fMinConfidence < 0 || fMinConfidence > 1 {
+		return nil, nil, fmt.Errorf("%w min-confidence: %v", ErrUnknown, fMinConfidence)
+	}
+
 	meta := graph.NewMetaLoader()
 
 	if fMeta != "" {
@@ -297,6 +434,18 @@ func prepareConfig() (
 		bildr, nwr = cb, cb
 	}
 
+	if fMesh {
+		mc := graph.NewMeshCollapser(bildr, strings.Split(fProxies, ","))
+
+		bildr, nwr = mc, mc
+	}
+
+	if fCompose {
+		cg := graph.NewComposeGrouper(bildr, fNoLoops)
+
+		bildr, nwr = cg, cg
+	}
+
 	if fNoOrphans {
 		cb := graph.NewOrphansInspector(bildr)
 
@@ -310,14 +459,23 @@ func prepareConfig() (
 	}
 
 	cfg = &graph.Config{
-		Builder:   bildr,
-		Meta:      meta,
-		Proto:     proto,
-		Follow:    loadSet(fFollow),
-		OnlyLocal: fLocal,
-		Deep:      fDeep,
-		NoLoops:   fNoLoops,
-		SkipEnv:   skipKeys,
+		Builder:       bildr,
+		Meta:          meta,
+		Header:        header,
+		Proto:         proto,
+		Follow:        loadSet(fFollow),
+		States:        states,
+		OnlyLocal:     fLocal,
+		Deep:          fDeep,
+		Inbound:       fInbound,
+		Shares:        fShares,
+		NoLoops:       fNoLoops,
+		SkipEnv:       skipKeys,
+		Samples:       fSamples,
+		Interval:      fInterval,
+		MinConfidence: fMinConfidence,
+		Since:         since,
+		Until:         until,
 	}
 
 	return cfg, nwr, nil
@@ -331,11 +489,16 @@ func run() error {
 
 	var act string
 
-	if len(fLoad) > 0 {
+	switch {
+	case fDiff != "":
+		log.Printf("Comparing %d file(s) with: %s", len(fLoad), fDiff)
+
+		act, err = "diff", doDiff(cfg, fDiff, fLoad)
+	case len(fLoad) > 0:
 		log.Printf("Loading %d file(s)", len(fLoad))
 
 		act, err = "load", doLoad(cfg, fLoad)
-	} else {
+	default:
 		log.Println("Building graph")
 
 		act, err = "build", doBuild(cfg)
@@ -373,37 +536,297 @@ func doLoad(
 	return nil
 }
 
-func doBuild(
+func doDiff(
 	cfg *graph.Config,
+	baseline string,
+	files []string,
 ) error {
+	if len(files) == 0 {
+		return ErrNoLoad
+	}
+
+	dif := graph.NewDiff(cfg)
+
+	if err := feed(baseline, dif.Baseline); err != nil {
+		return fmt.Errorf("baseline %s: %w", baseline, err)
+	}
+
+	for _, fn := range files {
+		if err := feed(fn, dif.Current); err != nil {
+			return fmt.Errorf("load %s: %w", fn, err)
+		}
+	}
+
+	rep, err := dif.Build()
+	if err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+
+	log.Println("Changes:", rep.Summary())
+
+	if fDiffReport == "" {
+		return nil
+	}
+
+	report := rep.WriteJSON
+	if strings.HasSuffix(fDiffReport, ".md") {
+		report = rep.WriteMarkdown
+	}
+
+	if err = write(fDiffReport, report); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+
+	return nil
+}
+
+func newCRIClient(
+	cfg *graph.Config,
+) (client.HostClient, error) {
+	if runtime.GOOS != linuxOS || os.Geteuid() != 0 {
+		return nil, fmt.Errorf("cri: %w", ErrNotRoot)
+	}
+
+	if cfg.Proto.Has(graph.UNIX) {
+		log.Println("[-] Unix-connections are not supported for cri, ignoring")
+
+		cfg.Proto ^= graph.UNIX
+	}
+
+	cli, err := client.NewCRI(
+		client.WithCRIDialer(client.DialCRI(fCRI)),
+		client.WithWorkers(fWorkers),
+		client.WithTimeout(fCTimeout),
+		client.WithNsenterFn(client.Nsenter),
+		client.WithInodesFn(client.Inodes),
+		client.WithCgroupFn(client.CgroupPids),
+		client.WithStates(cfg.States),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cri: %w", err)
+	}
+
+	return cli, nil
+}
+
+func newBareClient(
+	cfg *graph.Config,
+) (client.HostClient, error) {
+	if runtime.GOOS != linuxOS {
+		return nil, fmt.Errorf("bare: %w", ErrNotLinux)
+	}
+
+	if cfg.Proto.Has(graph.UNIX) {
+		log.Println("[-] Unix-connections are not supported for bare mode, ignoring")
+
+		cfg.Proto ^= graph.UNIX
+	}
+
+	return client.NewBare(
+		client.ProcRoot(),
+		client.WithWorkers(fWorkers),
+		client.WithStates(cfg.States),
+	), nil
+}
+
+func newDockerClient(
+	cfg *graph.Config,
+) (client.HostClient, error) {
 	opts := []client.Option{
 		client.WithClientCreator(client.Default),
+		client.WithWorkers(fWorkers),
+		client.WithTimeout(fCTimeout),
+		client.WithExecChain(strings.Split(fChain, ",")...),
+		client.WithStates(cfg.States),
+	}
+
+	host := os.Getenv(dockerHostEnv)
+
+	if fPodman {
+		host = client.PodmanHost(os.Geteuid())
+
+		pods, err := client.PodmanPods(host)
+		if err != nil {
+			return nil, fmt.Errorf("podman: %w", err)
+		}
+
+		opts = append(opts,
+			client.WithClientCreator(client.DefaultHost(host)),
+			client.WithPodsFn(pods),
+		)
 	}
 
 	mode := client.InContainer
 
-	if runtime.GOOS == linuxOS && os.Geteuid() == 0 {
+	hosts, err := dockerHosts()
+	if err != nil {
+		return nil, fmt.Errorf("hosts: %w", err)
+	}
+
+	switch {
+	case fSidecar != "":
+		mode = client.Sidecar
+
+		opts = append(opts, client.WithSidecarImage(fSidecar))
+	case len(hosts) == 0 && runtime.GOOS == linuxOS && os.Geteuid() == 0:
 		mode = client.LinuxNsenter
 
 		opts = append(opts,
 			client.WithNsenterFn(client.Nsenter),
 			client.WithInodesFn(client.Inodes),
+			client.WithNetnsFn(client.Netns),
 		)
-	} else if cfg.Proto.Has(graph.UNIX) {
+	}
+
+	if mode != client.LinuxNsenter && cfg.Proto.Has(graph.UNIX) {
 		log.Println("[-] Unix-connections requested in non-root mode, ignoring")
 
 		cfg.Proto ^= graph.UNIX
 	}
 
-	cli, err := client.NewDocker(append(opts, client.WithMode(mode))...)
+	opts = append(opts, client.WithMode(mode))
+
+	if len(hosts) == 0 {
+		// single host stays unnamed, its addresses only resolve published ports
+		_, ips, err := client.ResolveHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("host: %w", err)
+		}
+
+		cli, err := client.NewDocker(append(opts, client.WithHost("", ips...))...)
+		if err != nil {
+			return nil, fmt.Errorf("docker: %w", err)
+		}
+
+		return cli, nil
+	}
+
+	multi := client.NewMulti()
+
+	for _, host := range hosts {
+		name, ips, err := client.ResolveHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("host %s: %w", host, err)
+		}
+
+		cli, err := client.NewDocker(append(slices.Clone(opts),
+			client.WithClientCreator(client.DefaultHost(host)),
+			client.WithHost(name, ips...),
+		)...)
+		if err != nil {
+			_ = multi.Close()
+
+			return nil, fmt.Errorf("docker %s: %w", host, err)
+		}
+
+		multi.Add(name, cli)
+	}
+
+	return multi, nil
+}
+
+func dockerHosts() (rv []string, err error) {
+	rv = slices.Clone(fHosts)
+
+	if fHostsFile == "" {
+		return rv, nil
+	}
+
+	err = feed(fHostsFile, func(r io.Reader) error {
+		sc := bufio.NewScanner(r)
+
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				rv = append(rv, line)
+			}
+		}
+
+		return sc.Err()
+	})
+
+	return rv, err
+}
+
+// flowsHost returns name, local host is scanned under, as its conntrack table holds flows
+// of its containers only, single host stays unnamed.
+func flowsHost() (name string, err error) {
+	if fBare || fCRI != "" {
+		return "", nil
+	}
+
+	hosts, err := dockerHosts()
+	if err != nil || len(hosts) == 0 {
+		return "", err
+	}
+
+	name, ok := client.LocalHost(hosts)
+	if !ok {
+		return "", ErrNoLocal
+	}
+
+	return name, nil
+}
+
+// scannedHosts returns names of scanned hosts for stream header.
+func scannedHosts() (rv []string) {
+	if !fBare && fCRI == "" {
+		if hosts, err := dockerHosts(); err == nil && len(hosts) > 0 {
+			return hosts
+		}
+
+		if host := os.Getenv(dockerHostEnv); host != "" && !fPodman {
+			return []string{host}
+		}
+	}
+
+	name, err := os.Hostname()
+	if err != nil {
+		return nil
+	}
+
+	return []string{name}
+}
+
+func doBuild(
+	cfg *graph.Config,
+) (err error) {
+	var cli client.HostClient
+
+	switch {
+	case fBare:
+		cli, err = newBareClient(cfg)
+	case fCRI != "":
+		cli, err = newCRIClient(cfg)
+	default:
+		cli, err = newDockerClient(cfg)
+	}
+
 	if err != nil {
 		return fmt.Errorf("client: %w", err)
 	}
 
 	defer cli.Close()
 
+	if fConntrack {
+		if runtime.GOOS != linuxOS || os.Geteuid() != 0 {
+			return fmt.Errorf("conntrack: %w", ErrNotRoot)
+		}
+
+		host, err := flowsHost()
+		if err != nil {
+			return fmt.Errorf("conntrack: %w", err)
+		}
+
+		cfg.Flows = client.NewConntrack(client.ProcRoot(), host)
+	}
+
 	method := cli.Mode()
 
+	if hdr := cfg.Header; hdr != nil {
+		hdr.Mode = method
+		hdr.Hosts = scannedHosts()
+	}
+
 	if cfg.Deep {
 		method += " / deep"
 	}
@@ -411,7 +834,17 @@ func doBuild(
 	log.Println("Starting with method:", method)
 	log.Println("Scanning for:", cfg.Proto.String())
 
-	if err = graph.Build(cfg, cli); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if fTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, fTimeout)
+		defer cancel()
+	}
+
+	if err = graph.Build(ctx, cfg, cli); err != nil {
 		return fmt.Errorf("graph: %w", err)
 	}
 
@@ -438,6 +871,17 @@ func main() {
 		return
 	}
 
+	if fSchema {
+		schema, err := node.Schema()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(string(schema))
+
+		return
+	}
+
 	if fSilent {
 		log.SetOutput(io.Discard)
 	}
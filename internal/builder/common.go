@@ -2,6 +2,7 @@ package builder
 
 import (
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/s0rg/decompose/internal/node"
@@ -11,7 +12,7 @@ func joinConnections(conns []*node.Connection, sep string) (rv string) {
 	tmp := make([]string, 0, len(conns))
 
 	for _, c := range conns {
-		tmp = append(tmp, c.Port.Label())
+		tmp = append(tmp, withChange(withState(c.Port.Label(), c.State), c.Change))
 	}
 
 	slices.Sort(tmp)
@@ -25,7 +26,7 @@ func joinListeners(ports map[string][]*node.Port, sep string) (rv string) {
 
 	for _, plist := range ports {
 		for _, p := range plist {
-			tmp = append(tmp, p.Label())
+			tmp = append(tmp, withChange(p.Label(), p.Change))
 		}
 	}
 
@@ -34,3 +35,58 @@ func joinListeners(ports map[string][]*node.Port, sep string) (rv string) {
 
 	return strings.Join(tmp, sep)
 }
+
+func joinExposed(exposed []*node.Exposed, sep string) (rv string) {
+	tmp := make([]string, len(exposed))
+
+	for i, e := range exposed {
+		tmp[i] = e.Label()
+	}
+
+	return strings.Join(tmp, sep)
+}
+
+// withState marks label of transient connection with its tcp state.
+func withState(label, state string) string {
+	if state == "" {
+		return label
+	}
+
+	return label + " (" + state + ")"
+}
+
+// withChange marks label of added or removed element.
+func withChange(label, change string) string {
+	switch change {
+	case node.ChangeAdded:
+		return "+" + label
+	case node.ChangeRemoved:
+		return "-" + label
+	}
+
+	return label
+}
+
+// changeColor returns color for added or removed element, removed ones are also dashed,
+// as red is taken by multi-homed nodes and connection attempts.
+func changeColor(change string) (color string, ok bool) {
+	switch change {
+	case node.ChangeAdded:
+		return "green", true
+	case node.ChangeRemoved:
+		return "darkgray", true
+	}
+
+	return "", false
+}
+
+const (
+	rareEdge     = 0.5 // edges, seen in lesser share of snapshots, are drawn dashed
+	maxEdgeWidth = 3.0
+	minEdgeWidth = 0.5
+)
+
+// edgeWidth returns line width for edge persistence (share of snapshots, edge was seen in).
+func edgeWidth(confidence float64) string {
+	return strconv.FormatFloat(max(confidence*maxEdgeWidth, minEdgeWidth), 'f', 1, 64)
+}
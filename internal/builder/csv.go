@@ -44,7 +44,7 @@ func (c *CSV) Write(w io.Writer) error {
 
 	c.j.Sorted(func(n *node.JSON, _ bool) {
 		_ = cw.Write([]string{
-			n.Name,
+			withChange(n.Name, n.Change),
 			joinListeners(n.Listen, "\r\n"),
 			renderOutbounds(n.Connected),
 		})
@@ -1,25 +1,44 @@
 package builder
 
 import (
+	"cmp"
 	"io"
+	"maps"
 	"slices"
+	"strings"
 
 	"github.com/emicklei/dot"
 
 	"github.com/s0rg/decompose/internal/node"
 )
 
+const (
+	networkPrefix = "network:"
+	meshSuffix    = " (mesh)"
+	flowSuffix    = " (conntrack)"
+	mixedChange   = "mixed"
+)
+
 type DOT struct {
-	g     *dot.Graph
-	edges map[string]map[string][]string
+	g        *dot.Graph
+	edges    map[string]map[string][]string
+	shares   map[string]map[string][]string
+	attempts map[string]map[string][]string
+	weights  map[string]map[string]float64
+	changes  map[string]map[string]string
+	networks []*node.Network
 }
 
 func NewDOT() *DOT {
 	g := dot.NewGraph(dot.Directed)
 
 	return &DOT{
-		g:     g,
-		edges: make(map[string]map[string][]string),
+		g:        g,
+		edges:    make(map[string]map[string][]string),
+		shares:   make(map[string]map[string][]string),
+		attempts: make(map[string]map[string][]string),
+		weights:  make(map[string]map[string]float64),
+		changes:  make(map[string]map[string]string),
 	}
 }
 
@@ -30,10 +49,14 @@ func (d *DOT) Name() string {
 func (d *DOT) AddNode(n *node.Node) error {
 	label, color := renderNode(n)
 
-	d.g.Node(n.ID).Attr(
+	dn := d.g.Node(n.ID).Attr(
 		"color", color,
 	).Label(label)
 
+	if n.Change == node.ChangeRemoved {
+		dn.Attr("style", "dashed")
+	}
+
 	return nil
 }
 
@@ -46,26 +69,109 @@ func (d *DOT) AddEdge(e *node.Edge) {
 		return
 	}
 
-	d.addEdge(e.SrcID, e.DstID, e.Port.Label())
+	if e.IsShare() {
+		addLabel(d.shares, e.SrcID, e.DstID, withChange(e.Port.Label(), e.Change))
+
+		return
+	}
+
+	label := withChange(withState(e.Port.Label(), e.State), e.Change)
+
+	if e.IsAttempt() {
+		addLabel(d.attempts, e.SrcID, e.DstID, label)
+
+		return
+	}
+
+	if e.Total > 0 {
+		addWeight(d.weights, e.SrcID, e.DstID, e.Confidence())
+	}
+
+	addChange(d.changes, e.SrcID, e.DstID, e.Change)
+
+	switch {
+	case e.IsMesh():
+		label += meshSuffix
+	case e.IsConntrack():
+		label += flowSuffix
+	}
+
+	addLabel(d.edges, e.SrcID, e.DstID, label)
+}
+
+func (d *DOT) AddNetwork(n *node.Network) {
+	d.networks = append(d.networks, n)
 }
 
 func (d *DOT) Write(w io.Writer) error {
 	d.buildEdges()
+	d.buildShares()
+	d.buildAttempts()
+	d.buildNetworks()
 	d.g.Write(w)
 
 	return nil
 }
 
-func (d *DOT) addEdge(src, dst, label string) {
-	dmap, ok := d.edges[src]
+func addLabel(edges map[string]map[string][]string, src, dst, label string) {
+	dmap, ok := edges[src]
 	if !ok {
 		dmap = make(map[string][]string)
-		d.edges[src] = dmap
+		edges[src] = dmap
 	}
 
 	dmap[dst] = append(dmap[dst], label)
 }
 
+// addWeight keeps highest confidence of edges between nodes.
+func addWeight(weights map[string]map[string]float64, src, dst string, confidence float64) {
+	dmap, ok := weights[src]
+	if !ok {
+		dmap = make(map[string]float64)
+		weights[src] = dmap
+	}
+
+	dmap[dst] = max(dmap[dst], confidence)
+}
+
+// addChange keeps change of edges between nodes, edges with different changes are mixed.
+func addChange(changes map[string]map[string]string, src, dst, change string) {
+	dmap, ok := changes[src]
+	if !ok {
+		dmap = make(map[string]string)
+		changes[src] = dmap
+	}
+
+	if cur, ok := dmap[dst]; ok && cur != change {
+		change = mixedChange
+	}
+
+	dmap[dst] = change
+}
+
+// change returns change of all edges between nodes in any direction.
+func (d *DOT) change(src, dst string) (rv string) {
+	fwd, fok := d.changes[src][dst]
+	rev, rok := d.changes[dst][src]
+
+	switch {
+	case !rok:
+		return fwd
+	case !fok, fwd == rev:
+		return rev
+	}
+
+	return mixedChange
+}
+
+// persistence returns confidence of edge between nodes in any direction, if it is known.
+func (d *DOT) persistence(src, dst string) (rv float64, ok bool) {
+	fwd, fok := d.weights[src][dst]
+	rev, rok := d.weights[dst][src]
+
+	return max(fwd, rev), fok || rok
+}
+
 func (d *DOT) buildEdges() {
 	order := make([]string, 0, len(d.edges))
 
@@ -99,11 +205,115 @@ func (d *DOT) buildEdges() {
 				}
 			}
 
-			d.g.Edge(src, dst, ports...)
+			edge := d.g.Edge(src, dst, ports...)
+
+			change := d.change(srcID, dstID)
+
+			if color, ok := changeColor(change); ok {
+				edge.Attr("color", color)
+			}
+
+			conf, known := d.persistence(srcID, dstID)
+			if known {
+				edge.Attr("penwidth", edgeWidth(conf))
+			}
+
+			if change == node.ChangeRemoved || (known && conf < rareEdge) {
+				edge.Attr("style", "dashed")
+			}
+		}
+	}
+}
+
+// buildShares draws shared volumes as dashed undirected edges.
+func (d *DOT) buildShares() {
+	for _, srcID := range slices.Sorted(maps.Keys(d.shares)) {
+		src, _ := d.g.FindNodeById(srcID)
+		dmap := d.shares[srcID]
+
+		for _, dstID := range slices.Sorted(maps.Keys(dmap)) {
+			dst, _ := d.g.FindNodeById(dstID)
+
+			d.g.Edge(src, dst, dmap[dstID]...).Attr(
+				"style", "dashed",
+			).Attr(
+				"color", "darkgreen",
+			).Attr(
+				"dir", "none",
+			)
+		}
+	}
+}
+
+// buildAttempts draws connections, that were never established, as red dotted edges.
+func (d *DOT) buildAttempts() {
+	for _, srcID := range slices.Sorted(maps.Keys(d.attempts)) {
+		src, _ := d.g.FindNodeById(srcID)
+		dmap := d.attempts[srcID]
+
+		for _, dstID := range slices.Sorted(maps.Keys(dmap)) {
+			dst, _ := d.g.FindNodeById(dstID)
+
+			d.g.Edge(src, dst, dmap[dstID]...).Attr(
+				"style", "dotted",
+			).Attr(
+				"color", "red",
+			)
 		}
 	}
 }
 
+// buildNetworks draws networks as nodes, attached with dotted lines, nodes that
+// bridge several networks are highlighted.
+func (d *DOT) buildNetworks() {
+	slices.SortFunc(d.networks, func(a, b *node.Network) int {
+		return cmp.Compare(a.Label(), b.Label())
+	})
+
+	for _, n := range d.networks {
+		label := networkPrefix + " " + n.Label()
+
+		if n.Driver != "" {
+			label += "\n" + n.Driver
+		}
+
+		if len(n.Subnets) > 0 {
+			label += "\n" + strings.Join(n.Subnets, ", ")
+		}
+
+		nn := d.g.Node(networkPrefix+n.Label()).Attr(
+			"shape", "hexagon",
+		).Attr(
+			"color", "blue",
+		).Label(label)
+
+		if n.Internal {
+			nn.Attr("style", "dashed")
+		}
+
+		for _, m := range n.Members {
+			cn, ok := d.g.FindNodeById(m.ID)
+			if !ok {
+				continue
+			}
+
+			d.g.Edge(cn, nn, m.IP).Attr(
+				"style", "dotted",
+			).Attr(
+				"arrowhead", "none",
+			)
+		}
+	}
+
+	node.MultiHomed(d.networks).Iter(func(id string) bool {
+		if cn, ok := d.g.FindNodeById(id); ok {
+			cn.Attr("color", "red").Attr("penwidth", "2")
+		}
+
+		return true
+	})
+}
+
 func renderNode(n *node.Node) (label, color string) {
 	label, color = n.Name, "black"
 
@@ -112,5 +322,9 @@ func renderNode(n *node.Node) (label, color string) {
 		label = "external: " + n.Name
 	}
 
+	if c, ok := changeColor(n.Change); ok {
+		color, label = c, withChange(label, n.Change)
+	}
+
 	return label, color
 }
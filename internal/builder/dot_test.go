@@ -2,6 +2,7 @@ package builder_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/s0rg/decompose/internal/builder"
@@ -183,6 +184,15 @@ func TestDOTGolden(t *testing.T) {
 		Port:  &node.Port{Kind: "tcp", Value: "2"},
 	})
 
+	bld.AddEdge(&node.Edge{
+		SrcID:   "node-1",
+		DstID:   "node-2",
+		SrcName: "/uploads",
+		DstName: "/data",
+		Kind:    node.EdgeSharesVolume,
+		Port:    &node.Port{Kind: node.KindVolume, Value: "/srv/uploads"},
+	})
+
 	var buf bytes.Buffer
 
 	bld.Write(&buf)
@@ -194,3 +204,254 @@ func TestDOTGolden(t *testing.T) {
 		t.Errorf("Want:\n%s\nGot:\n%s", want, got)
 	}
 }
+
+func TestDOTNetworks(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewDOT()
+
+	for _, id := range []string{"1", "2"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddNetwork(&node.Network{
+		Name:     "front",
+		Driver:   "bridge",
+		Subnets:  []string{"172.20.0.0/16"},
+		Internal: true,
+		Members: []*node.NetworkMember{
+			{ID: "node-1", IP: "172.20.0.2"},
+			{ID: "node-2", IP: "172.20.0.3"},
+			{ID: "node-gone", IP: "172.20.0.4"},
+		},
+	})
+	bld.AddNetwork(&node.Network{
+		Name:    "back",
+		Members: []*node.NetworkMember{{ID: "node-1", IP: "172.30.0.2"}},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{
+		`shape="hexagon"`,
+		`network: front\nbridge\n172.20.0.0/16`,
+		`style="dashed"`,
+		`label="172.30.0.2"`,
+		`penwidth="2"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatal("missing:", want, out)
+		}
+	}
+
+	if strings.Count(out, `style="dotted"`) != 3 || strings.Count(out, `penwidth="2"`) != 1 {
+		t.Fatal("edges/multi-homed:", out)
+	}
+}
+
+func TestDOTMesh(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewDOT()
+
+	for _, id := range []string{"1", "2"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		Kind:  node.EdgeViaMesh,
+		Port:  &node.Port{Kind: "tcp", Value: "8080"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, `label="tcp:8080 (mesh)"`) {
+		t.Fatal("mesh label:", out)
+	}
+}
+
+func TestDOTConntrack(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewDOT()
+
+	for _, id := range []string{"1", "2"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		Kind:  node.EdgeConntrack,
+		Port:  &node.Port{Kind: "tcp", Value: "5432"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, `label="tcp:5432 (conntrack)"`) {
+		t.Fatal("conntrack label:", out)
+	}
+}
+
+func TestDOTStates(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewDOT()
+
+	for _, id := range []string{"1", "2"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		State: node.StateSynSent,
+		Port:  &node.Port{Kind: "tcp", Value: "5432"},
+	})
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		State: "TIME_WAIT",
+		Port:  &node.Port{Kind: "tcp", Value: "6379"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	if !strings.Contains(out, `color="red",label="tcp:5432 (SYN_SENT)",style="dotted"`) {
+		t.Fatal("attempt:", out)
+	}
+
+	if !strings.Contains(out, `label="tcp:6379 (TIME_WAIT)"`) {
+		t.Fatal("state label:", out)
+	}
+}
+
+func TestDOTPersistence(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewDOT()
+
+	for _, id := range []string{"1", "2", "3"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		Seen:  4,
+		Total: 4,
+		Port:  &node.Port{Kind: "tcp", Value: "5432"},
+	})
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-3",
+		Seen:  1,
+		Total: 4,
+		Port:  &node.Port{Kind: "tcp", Value: "6379"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	if !strings.Contains(out, `label="tcp:5432",penwidth="3.0"`) {
+		t.Fatal("persistent:", out)
+	}
+
+	if !strings.Contains(out, `label="tcp:6379",penwidth="0.8",style="dashed"`) {
+		t.Fatal("rare:", out)
+	}
+}
+
+func TestDOTDiff(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewDOT()
+
+	for id, change := range map[string]string{"1": "", "2": node.ChangeAdded, "3": node.ChangeRemoved} {
+		_ = bld.AddNode(&node.Node{
+			ID:     "node-" + id,
+			Name:   id,
+			Change: change,
+			Ports:  &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID:  "node-1",
+		DstID:  "node-2",
+		Change: node.ChangeAdded,
+		Port:   &node.Port{Kind: "tcp", Value: "6379"},
+	})
+	bld.AddEdge(&node.Edge{
+		SrcID:  "node-1",
+		DstID:  "node-3",
+		Change: node.ChangeRemoved,
+		Port:   &node.Port{Kind: "tcp", Value: "5432"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{
+		`color="green",label="+tcp:6379"`,
+		`color="darkgray",label="-tcp:5432",style="dashed"`,
+		`label="+2"`,
+		`color="darkgray",label="-3",style="dashed"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatal("no", want, "in:", out)
+		}
+	}
+}
@@ -11,7 +11,9 @@ import (
 )
 
 type JSON struct {
-	state map[string]*node.JSON
+	state    map[string]*node.JSON
+	header   *node.Header
+	networks []*node.Network
 }
 
 func NewJSON() *JSON {
@@ -46,13 +48,36 @@ func (j *JSON) AddEdge(e *node.Edge) {
 		con = make([]*node.Connection, 0, 1)
 	}
 
+	// external hosts are seen, while connections to them are
+	for _, n := range []*node.JSON{src, dst} {
+		if n.IsExternal {
+			n.Extend(e.Timespan)
+		}
+	}
+
 	src.Connected[dst.Name] = append(con, &node.Connection{
-		Src:  e.SrcName,
-		Dst:  e.DstName,
-		Port: e.Port,
+		Timespan: e.Timespan,
+		Src:      e.SrcName,
+		Dst:      e.DstName,
+		Port:     e.Port,
+		Kind:     e.Kind,
+		State:    e.State,
+		Change:   e.Change,
+		Samples:  e.Samples,
+		Seen:     e.Seen,
+		Total:    e.Total,
 	})
 }
 
+// SetHeader sets provenance header, it is written as first record of stream.
+func (j *JSON) SetHeader(h *node.Header) {
+	j.header = h
+}
+
+func (j *JSON) AddNetwork(n *node.Network) {
+	j.networks = append(j.networks, n)
+}
+
 func (j *JSON) Sorted(fn func(*node.JSON, bool)) {
 	nodes := make([]*node.JSON, 0, len(j.state))
 
@@ -73,6 +98,12 @@ func (j *JSON) Write(w io.Writer) (err error) {
 	jw := json.NewEncoder(w)
 	jw.SetIndent("", "  ")
 
+	if j.header != nil {
+		if err = jw.Encode(&node.HeaderJSON{Header: j.header}); err != nil {
+			return fmt.Errorf("encode header: %w", err)
+		}
+	}
+
 	j.Sorted(func(n *node.JSON, _ bool) {
 		err = jw.Encode(n)
 	})
@@ -81,5 +112,15 @@ func (j *JSON) Write(w io.Writer) (err error) {
 		return fmt.Errorf("encode: %w", err)
 	}
 
+	slices.SortFunc(j.networks, func(a, b *node.Network) int {
+		return cmp.Compare(a.Label(), b.Label())
+	})
+
+	for _, n := range j.networks {
+		if err = jw.Encode(&node.NetworkJSON{Name: n.Label(), Network: n}); err != nil {
+			return fmt.Errorf("encode network: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/s0rg/decompose/internal/builder"
 	"github.com/s0rg/decompose/internal/graph"
@@ -210,3 +211,131 @@ func TestJSONWriteError(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestJSONNetworks(t *testing.T) {
+	t.Parallel()
+
+	bldr := builder.NewJSON()
+
+	_ = bldr.AddNode(&node.Node{ID: "1", Name: "1", Ports: &node.Ports{}})
+
+	bldr.AddNetwork(&node.Network{Name: "front", Host: "host-b"})
+	bldr.AddNetwork(&node.Network{
+		Name:    "back",
+		Driver:  "bridge",
+		Members: []*node.NetworkMember{{ID: "1", Name: "1", IP: "172.20.0.2", Aliases: []string{"db"}}},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bldr.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	jr := json.NewDecoder(&buf)
+
+	var items []*node.JSON
+
+	for jr.More() {
+		var n node.JSON
+
+		if err := jr.Decode(&n); err != nil {
+			t.Fatal(err)
+		}
+
+		items = append(items, &n)
+	}
+
+	if len(items) != 3 || items[0].Network != nil {
+		t.Fatal("items:", len(items))
+	}
+
+	if n := items[1].Network; n == nil || n.Name != "back" || n.Members[0].Aliases[0] != "db" {
+		t.Fatal("back:", items[1])
+	}
+
+	if n := items[2]; n.Name != "host-b/front" || n.Network == nil || n.Network.Host != "host-b" {
+		t.Fatal("front:", items[2])
+	}
+}
+
+func TestJSONTimespan(t *testing.T) {
+	t.Parallel()
+
+	bldr := builder.NewJSON()
+	seen := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	_ = bldr.AddNode(&node.Node{
+		Timespan: node.Stamp(seen),
+		ID:       "node-1",
+		Name:     "1",
+		Ports:    &node.Ports{},
+	})
+	_ = bldr.AddNode(node.External("ext"))
+
+	bldr.AddEdge(&node.Edge{
+		Timespan: node.Stamp(seen),
+		SrcID:    "node-1",
+		DstID:    "ext",
+		Port:     &node.Port{Kind: "tcp", Value: "443"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bldr.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// node, its connection and external host
+	if got := strings.Count(buf.String(), `"first_seen": "2024-01-02T03:04:05Z"`); got != 3 {
+		t.Fatal("first_seen:", got, buf.String())
+	}
+}
+
+func TestJSONHeader(t *testing.T) {
+	t.Parallel()
+
+	bldr := builder.NewJSON()
+
+	_ = bldr.AddNode(&node.Node{
+		ID:    "node-1",
+		Name:  "1",
+		Ports: &node.Ports{},
+	})
+
+	var hb graph.HeaderBuilder = bldr
+
+	hb.SetHeader(&node.Header{
+		Schema:  node.SchemaVersion,
+		Version: "v1.2.3",
+		Mode:    "nsenter",
+	})
+
+	var buf bytes.Buffer
+
+	if err := bldr.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&buf)
+
+	var rec node.JSON
+
+	if err := dec.Decode(&rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Header == nil || rec.Header.Schema != node.SchemaVersion || rec.Header.Mode != "nsenter" {
+		t.Fatal("header:", rec.Header)
+	}
+
+	rec = node.JSON{}
+
+	if err := dec.Decode(&rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Header != nil || rec.Name != "1" {
+		t.Fatal("node:", rec.Name, rec.Header)
+	}
+}
@@ -1,26 +1,34 @@
 package builder
 
 import (
+	"cmp"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"hash/fnv"
 	"io"
 	"slices"
+	"strings"
 
 	"github.com/s0rg/decompose/internal/node"
 )
 
 type PlantUML struct {
-	nodes map[string]*node.Node
-	conns map[string]map[string][]*node.Port
-	order []string
+	nodes   map[string]*node.Node
+	conns   map[string]map[string][]*node.Port
+	shares  []*node.Edge
+	states  []*node.Edge
+	order   []string
+	weights map[string]float64
+	changes map[string]string
 }
 
 func NewPlantUML() *PlantUML {
 	return &PlantUML{
-		nodes: make(map[string]*node.Node),
-		conns: make(map[string]map[string][]*node.Port),
+		nodes:   make(map[string]*node.Node),
+		conns:   make(map[string]map[string][]*node.Port),
+		weights: make(map[string]float64),
+		changes: make(map[string]string),
 	}
 }
 
@@ -46,10 +54,32 @@ func (p *PlantUML) AddEdge(e *node.Edge) {
 		return
 	}
 
+	if e.IsShare() {
+		p.shares = append(p.shares, e)
+
+		return
+	}
+
+	if e.State != "" {
+		p.states = append(p.states, e)
+
+		return
+	}
+
 	if !e.Port.Local && nsrc.Cluster != ndst.Cluster {
 		e.SrcID, e.DstID = nsrc.Cluster, ndst.Cluster
 	}
 
+	key := weightKey(e.SrcID, e.DstID, e.Port)
+
+	if e.Total > 0 {
+		p.weights[key] = max(p.weights[key], e.Confidence())
+	}
+
+	if e.Change != "" {
+		p.changes[key] = e.Change
+	}
+
 	mdst, ok := p.conns[e.SrcID]
 	if !ok {
 		mdst = make(map[string][]*node.Port)
@@ -75,6 +105,8 @@ func (p *PlantUML) Write(w io.Writer) error {
 	fmt.Fprintln(w, "")
 
 	p.writeEdges(w)
+	p.writeShares(w)
+	p.writeStates(w)
 
 	fmt.Fprintln(w, "@enduml")
 
@@ -97,8 +129,9 @@ func (p *PlantUML) writeNodes(w io.Writer) {
 
 		np := []*node.Port{}
 
-		fmt.Fprintf(w, "component \"%s\" as %s {\n", nod.Name,
+		fmt.Fprintf(w, "component \"%s\" as %s%s {\n", nod.Name,
 			makeID(nod.Cluster, nod.Name),
+			changeFill(nod.Change),
 		)
 
 		nod.Ports.Iter(func(process string, ports []*node.Port) {
@@ -109,7 +142,7 @@ func (p *PlantUML) writeNodes(w io.Writer) {
 
 			for _, prt := range ports {
 				fmt.Fprintf(w, "  portin \"%s\" as %s\n",
-					prt.Label(),
+					withChange(prt.Label(), prt.Change),
 					makeID(nod.Cluster, nod.Name, process, prt.Label()),
 				)
 
@@ -123,7 +156,7 @@ func (p *PlantUML) writeNodes(w io.Writer) {
 
 		for _, prt := range np {
 			fmt.Fprintf(w, " portin \"%s\" as %s\n",
-				prt.Label(),
+				withChange(prt.Label(), prt.Change),
 				makeID(nod.Cluster, nod.Name, prt.Label()),
 			)
 		}
@@ -135,12 +168,12 @@ func (p *PlantUML) writeNodes(w io.Writer) {
 		fmt.Fprintln(w, "cloud \"Externals\" as ext {")
 
 		for _, nod := range cloud {
-			fmt.Fprintf(w, " component \"%s\" as %s {\n", nod.Name, makeID("ext", nod.Name))
+			fmt.Fprintf(w, " component \"%s\" as %s%s {\n", nod.Name, makeID("ext", nod.Name), changeFill(nod.Change))
 
 			nod.Ports.Iter(func(_ string, ports []*node.Port) {
 				for _, prt := range ports {
 					fmt.Fprintf(w, "  portin \"%s\" as %s\n",
-						prt.Label(),
+						withChange(prt.Label(), prt.Change),
 						makeID("ext", nod.Name, prt.Label()),
 					)
 				}
@@ -212,10 +245,11 @@ func (p *PlantUML) writeEdges(w io.Writer) {
 						makeID(nsrc.Cluster, nsrc.Name, dstp, prt.Label()),
 					)
 				} else {
-					fmt.Fprintf(w, "%s -----> %s: %s\n",
+					fmt.Fprintf(w, "%s %s %s: %s\n",
 						makeID(nsrc.Cluster, nsrc.Name),
+						p.arrow(src, dst, prt),
 						makeID(ndst.Cluster, ndst.Name, prt.Label()),
-						prt.Label(),
+						withChange(prt.Label(), p.changes[weightKey(src, dst, prt)]),
 					)
 				}
 			}
@@ -223,6 +257,103 @@ func (p *PlantUML) writeEdges(w io.Writer) {
 	}
 }
 
+// arrow returns edge arrow, styled by edge change and persistence, if they are known.
+func (p *PlantUML) arrow(src, dst string, prt *node.Port) string {
+	const plain = "----->"
+
+	var (
+		key   = weightKey(src, dst, prt)
+		style []string
+	)
+
+	change := p.changes[key]
+
+	if color, ok := changeColor(change); ok {
+		style = append(style, "#"+color)
+	}
+
+	conf, known := p.weights[key]
+
+	if change == node.ChangeRemoved || (known && conf < rareEdge) {
+		style = append(style, "dashed")
+	}
+
+	if known {
+		style = append(style, "thickness="+edgeWidth(conf))
+	}
+
+	if len(style) == 0 {
+		return plain
+	}
+
+	return "-[" + strings.Join(style, ",") + "]---->"
+}
+
+// changeFill returns background of added or removed component.
+func changeFill(change string) string {
+	switch change {
+	case node.ChangeAdded:
+		return " #palegreen"
+	case node.ChangeRemoved:
+		return " #lightgray;line.dashed"
+	}
+
+	return ""
+}
+
+func weightKey(src, dst string, prt *node.Port) string {
+	return src + "|" + dst + "|" + prt.Label()
+}
+
+// writeShares draws shared volumes as dashed lines between nodes.
+func (p *PlantUML) writeShares(w io.Writer) {
+	slices.SortFunc(p.shares, func(a, b *node.Edge) int {
+		return cmp.Or(
+			cmp.Compare(a.SrcID, b.SrcID),
+			cmp.Compare(a.DstID, b.DstID),
+			cmp.Compare(a.Port.Value, b.Port.Value),
+		)
+	})
+
+	for _, e := range p.shares {
+		nsrc, ndst := p.nodes[e.SrcID], p.nodes[e.DstID]
+
+		fmt.Fprintf(w, "%s .[#darkgreen]. %s: %s\n",
+			makeID(nsrc.Cluster, nsrc.Name),
+			makeID(ndst.Cluster, ndst.Name),
+			withChange(e.Port.Label(), e.Change),
+		)
+	}
+}
+
+// writeStates draws transient connections as dotted arrows, attempts are red.
+func (p *PlantUML) writeStates(w io.Writer) {
+	slices.SortFunc(p.states, func(a, b *node.Edge) int {
+		return cmp.Or(
+			cmp.Compare(a.SrcID, b.SrcID),
+			cmp.Compare(a.DstID, b.DstID),
+			cmp.Compare(a.Port.Label(), b.Port.Label()),
+			cmp.Compare(a.State, b.State),
+		)
+	})
+
+	for _, e := range p.states {
+		nsrc, ndst := p.nodes[e.SrcID], p.nodes[e.DstID]
+
+		arrow := "..>"
+		if e.IsAttempt() {
+			arrow = ".[#red].>"
+		}
+
+		fmt.Fprintf(w, "%s %s %s: %s\n",
+			makeID(nsrc.Cluster, nsrc.Name),
+			arrow,
+			makeID(ndst.Cluster, ndst.Name),
+			withChange(withState(e.Port.Label(), e.State), e.Change),
+		)
+	}
+}
+
 func makeID(parts ...string) (rv string) {
 	h := fnv.New64a()
 
@@ -2,6 +2,7 @@ package builder_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/s0rg/decompose/internal/builder"
@@ -217,6 +218,15 @@ func TestPumlGolden(t *testing.T) {
 		Port:  &node.Port{},
 	})
 
+	bld.AddEdge(&node.Edge{
+		SrcID:   "node-1",
+		DstID:   "node-2",
+		SrcName: "/uploads",
+		DstName: "/data",
+		Kind:    node.EdgeSharesVolume,
+		Port:    &node.Port{Kind: node.KindVolume, Value: "/srv/uploads"},
+	})
+
 	var buf bytes.Buffer
 
 	bld.Write(&buf)
@@ -228,3 +238,133 @@ func TestPumlGolden(t *testing.T) {
 		t.Errorf("Want:\n%s\nGot:\n%s", want, got)
 	}
 }
+
+func TestPumlStates(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewPlantUML()
+
+	for _, id := range []string{"1", "2"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		State: node.StateSynSent,
+		Port:  &node.Port{Kind: "tcp", Value: "5432"},
+	})
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-2",
+		DstID: "node-1",
+		State: "CLOSE_WAIT",
+		Port:  &node.Port{Kind: "tcp", Value: "80"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	if !strings.Contains(out, ".[#red].> ") || !strings.Contains(out, ": tcp:5432 (SYN_SENT)") {
+		t.Fatal("attempt:", out)
+	}
+
+	if !strings.Contains(out, " ..> ") || !strings.Contains(out, ": tcp:80 (CLOSE_WAIT)") {
+		t.Fatal("state:", out)
+	}
+}
+
+func TestPumlPersistence(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewPlantUML()
+
+	for _, id := range []string{"1", "2", "3"} {
+		_ = bld.AddNode(&node.Node{
+			ID:    "node-" + id,
+			Name:  id,
+			Ports: &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-2",
+		Seen:  4,
+		Total: 4,
+		Port:  &node.Port{Kind: "tcp", Value: "5432"},
+	})
+	bld.AddEdge(&node.Edge{
+		SrcID: "node-1",
+		DstID: "node-3",
+		Seen:  1,
+		Total: 4,
+		Port:  &node.Port{Kind: "tcp", Value: "6379"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	if !strings.Contains(out, " -[thickness=3.0]----> ") {
+		t.Fatal("persistent:", out)
+	}
+
+	if !strings.Contains(out, " -[dashed,thickness=0.8]----> ") {
+		t.Fatal("rare:", out)
+	}
+}
+
+func TestPumlDiff(t *testing.T) {
+	t.Parallel()
+
+	bld := builder.NewPlantUML()
+
+	for id, change := range map[string]string{"1": "", "2": node.ChangeAdded, "3": node.ChangeRemoved} {
+		_ = bld.AddNode(&node.Node{
+			ID:     "node-" + id,
+			Name:   id,
+			Change: change,
+			Ports:  &node.Ports{},
+		})
+	}
+
+	bld.AddEdge(&node.Edge{
+		SrcID:  "node-1",
+		DstID:  "node-2",
+		Change: node.ChangeAdded,
+		Port:   &node.Port{Kind: "tcp", Value: "6379"},
+	})
+	bld.AddEdge(&node.Edge{
+		SrcID:  "node-1",
+		DstID:  "node-3",
+		Change: node.ChangeRemoved,
+		Port:   &node.Port{Kind: "tcp", Value: "5432"},
+	})
+
+	var buf bytes.Buffer
+
+	if err := bld.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{"#palegreen", "#lightgray;line.dashed", "-[#green]", "-[#darkgray,dashed]"} {
+		if !strings.Contains(out, want) {
+			t.Fatal("no", want, "in:", out)
+		}
+	}
+}
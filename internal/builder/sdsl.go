@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"io"
@@ -75,6 +76,10 @@ func (s *Structurizr) AddNode(n *node.Node) error {
 		cont.Tags = append(cont.Tags, "external")
 	}
 
+	if n.Change != "" {
+		cont.Tags = append(cont.Tags, n.Change)
+	}
+
 	if n.Meta != nil {
 		if lines, ok := n.FormatMeta(); ok {
 			cont.Description = strings.Join(lines, " \\\n")
@@ -101,10 +106,13 @@ func (s *Structurizr) AddEdge(e *node.Edge) {
 		e.DstID = systemName
 	}
 
+	// changed and transient connections are styled by their change or tcp state
+	kind := cmp.Or(e.Change, e.Kind, e.State)
+
 	if s.ws.HasSystem(e.SrcID) {
-		rel, ok = s.ws.AddRelation(e.SrcID, e.DstID, e.SrcID, e.DstID)
+		rel, ok = s.ws.AddRelation(e.SrcID, e.DstID, e.SrcID, e.DstID, kind)
 	} else {
-		rel, ok = s.ws.System(systemName).AddRelation(e.SrcID, e.DstID, e.SrcName, e.DstName)
+		rel, ok = s.ws.System(systemName).AddRelation(e.SrcID, e.DstID, e.SrcName, e.DstName, kind)
 	}
 
 	if !ok {
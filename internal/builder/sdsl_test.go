@@ -203,6 +203,15 @@ func TestSDSLGolden(t *testing.T) {
 		Port:  &node.Port{},
 	})
 
+	bld.AddEdge(&node.Edge{
+		SrcID:   "c1",
+		DstID:   "c2",
+		SrcName: "/uploads",
+		DstName: "/data",
+		Kind:    node.EdgeSharesVolume,
+		Port:    &node.Port{Kind: node.KindVolume, Value: "/srv/uploads"},
+	})
+
 	var buf bytes.Buffer
 
 	bld.Write(&buf)
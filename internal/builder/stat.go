@@ -24,6 +24,8 @@ type Stat struct {
 	conns      map[string]set.Unordered[string]
 	ports      map[string]int
 	clusters   map[string]int
+	nodeDiff   map[string]int // change -> nodes count
+	edgeDiff   map[string]int // change -> edges count
 	nodes      int
 	edgesUniq  int
 	edgesTotal int
@@ -35,6 +37,8 @@ func NewStat() *Stat {
 		ports:    make(map[string]int),
 		clusters: make(map[string]int),
 		conns:    make(map[string]set.Unordered[string]),
+		nodeDiff: make(map[string]int),
+		edgeDiff: make(map[string]int),
 	}
 }
 
@@ -43,6 +47,10 @@ func (s *Stat) Name() string {
 }
 
 func (s *Stat) AddNode(n *node.Node) error {
+	if n.Change != "" {
+		s.nodeDiff[n.Change]++
+	}
+
 	if n.IsExternal() {
 		s.externals++
 
@@ -73,6 +81,10 @@ func (s *Stat) AddEdge(e *node.Edge) {
 		s.edgesUniq++
 	}
 
+	if e.Change != "" {
+		s.edgeDiff[e.Change]++
+	}
+
 	s.edgesTotal++
 }
 
@@ -84,6 +96,12 @@ func (s *Stat) Write(w io.Writer) error {
 		fmt.Fprintf(w, "Externals: %d\n", s.externals)
 	}
 
+	for _, change := range []string{node.ChangeAdded, node.ChangeRemoved} {
+		if s.nodeDiff[change]+s.edgeDiff[change] > 0 {
+			fmt.Fprintf(w, "Changes %s: nodes: %d connections: %d\n", change, s.nodeDiff[change], s.edgeDiff[change])
+		}
+	}
+
 	fmt.Fprintln(w, "")
 
 	ports, clusters := s.calcStats()
@@ -64,7 +64,7 @@ func writeNode(w io.Writer, n *node.JSON, last bool) {
 	}
 
 	fmt.Fprint(w, " ")
-	fmt.Fprintln(w, n.Name)
+	fmt.Fprintln(w, withChange(n.Name, n.Change))
 
 	fmt.Fprint(w, next, " ")
 	fmt.Fprintf(w, "external: %t\n", n.IsExternal)
@@ -74,6 +74,16 @@ func writeNode(w io.Writer, n *node.JSON, last bool) {
 		fmt.Fprintln(w, "image:", *n.Image)
 	}
 
+	if n.Cluster != "" {
+		fmt.Fprint(w, next, " ")
+		fmt.Fprintln(w, "cluster:", n.Cluster)
+	}
+
+	if n.Container.Replicas > 0 {
+		fmt.Fprint(w, next, " ")
+		fmt.Fprintln(w, "replicas:", n.Container.Replicas)
+	}
+
 	if len(n.Tags) > 0 {
 		fmt.Fprint(w, next, " ")
 		fmt.Fprintln(w, "tags:", strings.Join(n.Tags, ", "))
@@ -87,6 +97,11 @@ func writeNode(w io.Writer, n *node.JSON, last bool) {
 	fmt.Fprint(w, next, " ")
 	fmt.Fprintln(w, "listen:", joinListeners(n.Listen, ", "))
 
+	if len(n.Container.Exposed) > 0 {
+		fmt.Fprint(w, next, " ")
+		fmt.Fprintln(w, "exposed:", joinExposed(n.Container.Exposed, ", "))
+	}
+
 	if len(n.Networks) > 0 {
 		fmt.Fprint(w, next, " ")
 		fmt.Fprintln(w, "networks:", strings.Join(n.Networks, ", "))
@@ -29,6 +29,10 @@ func TestTreeGolden(t *testing.T) {
 		Container: node.Container{
 			Cmd: []string{"echo", "'test 1'"},
 			Env: []string{"FOO=1"},
+			Exposed: []*node.Exposed{
+				{Kind: "tcp", Port: 8080, Target: 1},
+				{Kind: "udp", Port: 5353, Target: 2},
+			},
 		},
 	})
 	_ = bld.AddNode(&node.Node{
@@ -42,9 +46,11 @@ func TestTreeGolden(t *testing.T) {
 			Info: "info 2",
 			Tags: []string{"2"},
 		},
+		Cluster: "shop",
 		Container: node.Container{
-			Cmd: []string{"echo", "'test 2'"},
-			Env: []string{"FOO=2"},
+			Cmd:      []string{"echo", "'test 2'"},
+			Env:      []string{"FOO=2"},
+			Replicas: 2,
 		},
 	})
 	_ = bld.AddNode(&node.Node{
@@ -3,6 +3,7 @@ package builder
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -11,7 +12,10 @@ import (
 	"github.com/s0rg/set"
 )
 
-const volumeSuffix = "_data"
+const (
+	volumeSuffix = "_data"
+	portsDefault = "tcp"
+)
 
 type compose struct {
 	Services map[string]*service `yaml:"services"`
@@ -26,11 +30,17 @@ type network struct {
 type service struct {
 	Image       string    `yaml:"image"`
 	Expose      yaml.Node `yaml:"expose"`
+	Ports       yaml.Node `yaml:"ports,omitempty"`
 	Links       []string  `yaml:"links"`
 	Volumes     []string  `yaml:"volumes"`
 	Networks    []string  `yaml:"networks"`
 	Environment yaml.Node `yaml:"environment"`
 	Command     []string  `yaml:"command"`
+	Deploy      *deploy   `yaml:"deploy,omitempty"`
+}
+
+type deploy struct {
+	Replicas int `yaml:"replicas"`
 }
 
 type YAML struct {
@@ -54,7 +64,8 @@ func (y *YAML) Name() string {
 }
 
 func (y *YAML) AddNode(n *node.Node) error {
-	if n.IsExternal() {
+	// compose describes current system, thus removed services are left out
+	if n.IsExternal() || n.Change == node.ChangeRemoved {
 		return nil
 	}
 
@@ -67,6 +78,10 @@ func (y *YAML) AddNode(n *node.Node) error {
 		svc.Command = n.Container.Cmd
 	}
 
+	if n.Container.Replicas > 1 {
+		svc.Deploy = &deploy{Replicas: n.Container.Replicas}
+	}
+
 	if len(n.Container.Env) > 0 {
 		yn := yaml.Node{
 			Kind: yaml.SequenceNode,
@@ -95,6 +110,10 @@ func (y *YAML) AddNode(n *node.Node) error {
 
 	n.Ports.Iter(func(_ string, plist []*node.Port) {
 		for _, p := range plist {
+			if p.Change == node.ChangeRemoved {
+				continue
+			}
+
 			svc.Expose.Content = append(svc.Expose.Content, &yaml.Node{
 				Kind:  yaml.ScalarNode,
 				Style: yaml.DoubleQuotedStyle,
@@ -103,6 +122,26 @@ func (y *YAML) AddNode(n *node.Node) error {
 		}
 	})
 
+	if len(n.Container.Exposed) > 0 {
+		svc.Ports = yaml.Node{
+			Kind: yaml.SequenceNode,
+		}
+
+		for _, e := range n.Container.Exposed {
+			port := strconv.Itoa(e.Port) + ":" + strconv.Itoa(e.Target)
+
+			if e.Kind != portsDefault {
+				port += "/" + e.Kind
+			}
+
+			svc.Ports.Content = append(svc.Ports.Content, &yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Style: yaml.DoubleQuotedStyle,
+				Value: port,
+			})
+		}
+	}
+
 	svc.Volumes = make([]string, len(n.Volumes))
 
 	for i, v := range n.Volumes {
@@ -123,6 +162,14 @@ func (y *YAML) AddNode(n *node.Node) error {
 }
 
 func (y *YAML) AddEdge(e *node.Edge) {
+	if e.IsShare() {
+		return // volumes are already in place
+	}
+
+	if e.Change == node.ChangeRemoved {
+		return
+	}
+
 	name, ok := y.idmap[e.SrcID]
 	if !ok {
 		return
@@ -30,6 +30,10 @@ func TestYAMLGolden(t *testing.T) {
 		Container: node.Container{
 			Cmd: []string{"echo", "'test 1'"},
 			Env: []string{"FOO=1"},
+			Exposed: []*node.Exposed{
+				{Kind: "tcp", Port: 8080, Target: 1},
+				{Kind: "udp", Port: 5353, Target: 2},
+			},
 		},
 		Volumes: []*node.Volume{
 			{Type: "volume", Src: "src", Dst: "dst"},
@@ -48,9 +52,11 @@ func TestYAMLGolden(t *testing.T) {
 			Info: "info 2",
 			Tags: []string{"2"},
 		},
+		Cluster: "shop",
 		Container: node.Container{
-			Cmd: []string{"echo", "'test 2'"},
-			Env: []string{"FOO=2"},
+			Cmd:      []string{"echo", "'test 2'"},
+			Env:      []string{"FOO=2"},
+			Replicas: 2,
 		},
 		Volumes: []*node.Volume{
 			{Type: "volume", Src: "src2", Dst: "dst2"},
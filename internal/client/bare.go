@@ -0,0 +1,342 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/procfs"
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	BareNetwork    = "bare"
+	BareNetnsLabel = "decompose.netns"
+
+	bareIDPrefix  = "netns:"
+	bareMode      = "bare-proc"
+	socketPrefix  = "socket:["
+	lxcPayload    = "lxc.payload."
+	lxcLegacy     = "lxc"
+	nspawnPrefix  = "systemd-nspawn@"
+	nspawnSuffix  = ".service"
+	machinePrefix = "machine-"
+	machineSuffix = ".scope"
+	systemdDash   = `\x2d`
+)
+
+type bareGroup struct {
+	Procs []procfs.Proc
+	Inode uint64
+}
+
+// Bare gathers connections without any container runtime: processes from procfs
+// are grouped by their network namespaces, and every group became a container.
+type Bare struct {
+	opt  *options
+	root string
+}
+
+func NewBare(root string, opts ...Option) (rv *Bare) {
+	rv = &Bare{
+		opt:  &options{},
+		root: root,
+	}
+
+	for _, op := range opts {
+		op(rv.opt)
+	}
+
+	if rv.opt.Workers < 1 {
+		rv.opt.Workers = 1
+	}
+
+	return rv
+}
+
+func (b *Bare) Mode() string {
+	return bareMode
+}
+
+func (b *Bare) Close() error {
+	return nil
+}
+
+func (b *Bare) Containers(
+	ctx context.Context,
+	proto graph.NetProto,
+	deep bool,
+	skipkeys []string,
+	progress func(int, int),
+) (rv []*graph.Container, err error) {
+	fs, err := procfs.NewFS(b.root)
+	if err != nil {
+		return nil, fmt.Errorf("procfs: %w", err)
+	}
+
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return nil, fmt.Errorf("procfs/all: %w", err)
+	}
+
+	groups := groupByNetns(procs)
+
+	skeys := make(set.Unordered[string])
+
+	for _, key := range skipkeys {
+		skeys.Add(strings.ToUpper(key))
+	}
+
+	var (
+		results = make([]*graph.Container, len(groups))
+		counter = &progressCounter{report: progress, total: len(groups)}
+	)
+
+	_ = parallel(b.opt.Workers, len(groups), func(i int) error {
+		defer counter.Step()
+
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+
+		con, gerr := b.extractGroup(groups[i], proto, deep, skeys)
+		if gerr != nil {
+			log.Printf("netns: %d error: %v", groups[i].Inode, gerr)
+
+			return nil
+		}
+
+		results[i] = con
+
+		return nil
+	})
+
+	names := make(set.Unordered[string])
+	rv = make([]*graph.Container, 0, len(results))
+
+	for _, con := range results {
+		if con == nil {
+			continue
+		}
+
+		if !names.Add(con.Name) {
+			con.Name += "-" + con.Labels[BareNetnsLabel]
+		}
+
+		rv = append(rv, con)
+	}
+
+	counter.Done()
+
+	return rv, nil
+}
+
+func (b *Bare) extractGroup(
+	grp *bareGroup,
+	proto graph.NetProto,
+	deep bool,
+	skeys set.Unordered[string],
+) (rv *graph.Container, err error) {
+	main := grp.Procs[0]
+	netns := strconv.FormatUint(grp.Inode, 10)
+
+	cmd, err := main.CmdLine()
+	if err != nil {
+		return nil, fmt.Errorf("procfs/cmdline: %w", err)
+	}
+
+	env, _ := main.Environ() // may be forbidden, its ok
+
+	rv = &graph.Container{
+		ID:        bareIDPrefix + netns,
+		Name:      b.groupName(main),
+		Labels:    map[string]string{BareNetnsLabel: netns},
+		Endpoints: b.endpoints(main.PID),
+		Strategy:  StrategyProcNet,
+		Info: &graph.ContainerInfo{
+			Cmd: cmd,
+			Env: filterEnv(env, skeys),
+		},
+	}
+
+	owners := make(map[uint64]string)
+
+	for _, p := range grp.Procs {
+		name, _ := p.Comm()
+
+		targets, _ := p.FileDescriptorTargets()
+
+		for _, t := range targets {
+			if inode, ok := socketInode(t); ok {
+				owners[inode] = name
+			}
+		}
+	}
+
+	nfs, err := procfs.NewFS(filepath.Join(b.root, strconv.Itoa(main.PID)))
+	if err != nil {
+		return nil, fmt.Errorf("procfs/net: %w", err)
+	}
+
+	onconn := func(conn *graph.Connection) {
+		if (!deep && conn.IsLocal()) || !b.opt.matchState(conn) {
+			return
+		}
+
+		var ok bool
+
+		if conn.Process, ok = owners[conn.Inode]; !ok || conn.Inode == 0 {
+			conn.Process = graph.ProcessUnknown
+		}
+
+		rv.AddConnection(conn)
+	}
+
+	if proto.Has(graph.TCP) {
+		scanTCP(nfs, "", onconn)
+	}
+
+	if proto.Has(graph.UDP) {
+		scanUDP(nfs, "", onconn)
+	}
+
+	if proto.Has(graph.UNIX) {
+		scanUNIX(nfs, "", onconn)
+	}
+
+	rv.SortConnections()
+
+	return rv, nil
+}
+
+// groupName names group by its cgroup path (lxc, systemd-nspawn or machined), hostname or main process.
+func (b *Bare) groupName(p procfs.Proc) string {
+	if cgroups, err := p.Cgroups(); err == nil {
+		for _, cg := range cgroups {
+			if name, ok := cgroupName(cg.Path); ok {
+				return name
+			}
+		}
+	}
+
+	host, err := os.ReadFile(filepath.Join(b.root, strconv.Itoa(p.PID), "root", "etc", "hostname"))
+	if name := strings.TrimSpace(string(host)); err == nil && name != "" {
+		return name
+	}
+
+	if name, err := p.Comm(); err == nil && name != "" {
+		return name
+	}
+
+	return strconv.Itoa(p.PID)
+}
+
+func (b *Bare) endpoints(pid int) (rv map[string]string) {
+	rv = make(map[string]string)
+
+	add := func(ip net.IP) {
+		rv[ip.String()] = BareNetwork
+	}
+
+	for name, parse := range map[string]func(io.Reader) error{
+		"fib_trie": func(r io.Reader) error { return parseFibTrie(r, add) },
+		"if_inet6": func(r io.Reader) error { return parseIfInet6(r, add) },
+	} {
+		fd, err := os.Open(filepath.Join(b.root, strconv.Itoa(pid), "net", name))
+		if err != nil {
+			continue
+		}
+
+		if err = parse(fd); err != nil {
+			log.Printf("[-] procfs/%s: %v", name, err)
+		}
+
+		fd.Close()
+	}
+
+	return rv
+}
+
+// groupByNetns groups user-space processes by their network namespaces, groups are
+// ordered by lowest pid.
+func groupByNetns(procs procfs.Procs) (rv []*bareGroup) {
+	slices.SortFunc(procs, func(a, b procfs.Proc) int {
+		return cmp.Compare(a.PID, b.PID)
+	})
+
+	index := make(map[uint64]*bareGroup)
+
+	for _, p := range procs {
+		if cmd, err := p.CmdLine(); err != nil || len(cmd) == 0 {
+			continue // kernel thread or already gone
+		}
+
+		nss, err := p.Namespaces()
+		if err != nil {
+			continue
+		}
+
+		ns, ok := nss[nsNet]
+		if !ok {
+			continue
+		}
+
+		inode := uint64(ns.Inode)
+
+		grp, ok := index[inode]
+		if !ok {
+			grp = &bareGroup{Inode: inode}
+			index[inode] = grp
+
+			rv = append(rv, grp)
+		}
+
+		grp.Procs = append(grp.Procs, p)
+	}
+
+	return rv
+}
+
+func cgroupName(path string) (name string, ok bool) {
+	parts := strings.Split(path, "/")
+
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, lxcPayload):
+			return strings.TrimPrefix(part, lxcPayload), true
+		case part == lxcLegacy && i+1 < len(parts) && parts[i+1] != "":
+			return parts[i+1], true
+		case strings.HasPrefix(part, nspawnPrefix) && strings.HasSuffix(part, nspawnSuffix):
+			return strings.TrimSuffix(strings.TrimPrefix(part, nspawnPrefix), nspawnSuffix), true
+		case strings.HasPrefix(part, machinePrefix) && strings.HasSuffix(part, machineSuffix):
+			name = strings.TrimSuffix(strings.TrimPrefix(part, machinePrefix), machineSuffix)
+
+			return strings.ReplaceAll(name, systemdDash, "-"), true
+		}
+	}
+
+	return "", false
+}
+
+func socketInode(target string) (inode uint64, ok bool) {
+	if !strings.HasPrefix(target, socketPrefix) {
+		return
+	}
+
+	inode, err := strconv.ParseUint(strings.TrimSuffix(target[len(socketPrefix):], "]"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	return inode, true
+}
@@ -0,0 +1,252 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/client"
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	testTCPHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	testUDPHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops\n"
+	testTCPLine   = "   %d: %s %s %s 00000000:00000000 00:00000000 00000000     0        0 %d 1 0000000000000000 100 0 0 10 0\n"
+)
+
+type testProc struct {
+	Files   map[string]string
+	Sockets []uint64
+	Netns   int
+	Pid     int
+}
+
+func tcpLine(n int, local, remote, state string, inode uint64) string {
+	return fmt.Sprintf(testTCPLine, n, local, remote, state, inode)
+}
+
+func makeProcFS(t *testing.T, procs []*testProc) (root string) {
+	t.Helper()
+
+	root = t.TempDir()
+
+	write := func(path, body string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	link := func(target, path string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.Pid))
+
+		link("net:["+strconv.Itoa(p.Netns)+"]", filepath.Join(dir, "ns", "net"))
+
+		if err := os.MkdirAll(filepath.Join(dir, "fd"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, s := range p.Sockets {
+			link("socket:["+strconv.FormatUint(s, 10)+"]", filepath.Join(dir, "fd", strconv.Itoa(i+3)))
+		}
+
+		files := map[string]string{
+			"net/tcp":  testTCPHeader,
+			"net/tcp6": testTCPHeader,
+			"net/udp":  testUDPHeader,
+			"net/udp6": testUDPHeader,
+		}
+
+		for name, body := range p.Files {
+			files[name] = body
+		}
+
+		for name, body := range files {
+			write(filepath.Join(dir, name), body)
+		}
+	}
+
+	return root
+}
+
+func TestBareContainers(t *testing.T) {
+	t.Parallel()
+
+	root := makeProcFS(t, []*testProc{
+		{
+			Pid:   1,
+			Netns: 100,
+			Files: map[string]string{
+				"cmdline":           "/sbin/init\x00",
+				"comm":              "systemd\n",
+				"cgroup":            "0::/init.scope\n",
+				"root/etc/hostname": "myhost\n",
+			},
+		},
+		{
+			Pid:   2,
+			Netns: 100,
+			Files: map[string]string{
+				"cmdline": "",
+				"comm":    "kthreadd\n",
+			},
+		},
+		{
+			Pid:     10,
+			Netns:   200,
+			Sockets: []uint64{555},
+			Files: map[string]string{
+				"cmdline": "nginx\x00-g\x00daemon off;\x00",
+				"comm":    "nginx\n",
+				"cgroup":  "0::/lxc.payload.web/system.slice/nginx.service\n",
+				"environ": "A=1\x00SECRET=2\x00",
+				"net/tcp": testTCPHeader +
+					tcpLine(0, "0503000A:0050", "00000000:0000", "0A", 555) +
+					tcpLine(1, "0503000A:0051", "00000000:0000", "0A", 777) +
+					tcpLine(2, "0100007F:1F90", "00000000:0000", "0A", 888),
+				"net/fib_trie": `Main:
+  +-- 0.0.0.0/0 3 0 5
+     |-- 0.0.0.0
+        /0 universe UNICAST
+     +-- 10.0.3.0/24 2 0 2
+        |-- 10.0.3.0
+           /24 link UNICAST
+        |-- 10.0.3.5
+           /32 host LOCAL
+     |-- 127.0.0.1
+        /32 host LOCAL
+Local:
+     |-- 10.0.3.5
+        /32 host LOCAL
+`,
+				"net/if_inet6": "fd000000000000000000000000000005 02 40 00 80 eth0\n" +
+					"fe800000000000000000000000000001 02 40 20 80 eth0\n",
+			},
+		},
+		{
+			Pid:   11,
+			Netns: 200,
+			Files: map[string]string{
+				"cmdline": "worker\x00",
+				"comm":    "worker\n",
+			},
+		},
+		{
+			Pid:   20,
+			Netns: 300,
+			Files: map[string]string{
+				"cmdline": "postgres\x00",
+				"comm":    "postgres\n",
+				"cgroup":  "0::/machine.slice/systemd-nspawn@db.service/payload\n",
+			},
+		},
+		{
+			Pid:   30,
+			Netns: 400,
+			Files: map[string]string{
+				"cmdline": "app\x00",
+				"comm":    "app\n",
+				"cgroup":  `0::/machine.slice/machine-my\x2dapp.scope` + "\n",
+			},
+		},
+		{
+			Pid:   40,
+			Netns: 500,
+			Files: map[string]string{
+				"cmdline": "app\x00",
+				"comm":    "app\n",
+				"cgroup":  "0::/user.slice\n",
+			},
+		},
+		{
+			Pid:   50,
+			Netns: 600,
+			Files: map[string]string{
+				"cmdline": "app\x00",
+				"comm":    "app\n",
+				"cgroup":  "0::/user.slice\n",
+			},
+		},
+	})
+
+	cli := client.NewBare(root, client.WithWorkers(2))
+
+	if cli.Mode() != "bare-proc" || cli.Close() != nil {
+		t.Fail()
+	}
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, []string{"secret"}, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	names := make([]string, len(rv))
+
+	for i, c := range rv {
+		names[i] = c.Name
+	}
+
+	want := []string{"myhost", "web", "db", "my-app", "app", "app-600"}
+
+	if len(names) != len(want) {
+		t.Fatal("names:", names)
+	}
+
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatal("names:", names)
+		}
+	}
+
+	web := rv[1]
+
+	if web.ID != "netns:200" || web.Labels[client.BareNetnsLabel] != "200" {
+		t.Fatal("web:", web.ID, web.Labels)
+	}
+
+	if len(web.Endpoints) != 2 || web.Endpoints["10.0.3.5"] != client.BareNetwork || web.Endpoints["fd00::5"] == "" {
+		t.Fatal("endpoints:", web.Endpoints)
+	}
+
+	if len(web.Info.Cmd) != 3 || len(web.Info.Env) != 1 {
+		t.Fatal("info:", web.Info)
+	}
+
+	procs := make(map[string]int)
+
+	web.IterListeners(func(c *graph.Connection) {
+		procs[c.Process]++
+	})
+
+	// loopback listener is skipped, as not deep
+	if web.ConnectionsCount() != 2 || procs["nginx"] != 1 || procs[graph.ProcessUnknown] != 1 {
+		t.Fatal("connections:", web.ConnectionsCount(), procs)
+	}
+}
+
+func TestBareContainersError(t *testing.T) {
+	t.Parallel()
+
+	cli := client.NewBare(filepath.Join(t.TempDir(), "not-exist"))
+
+	if _, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const conntrackTable = "1/net/nf_conntrack" // host network namespace
+
+// DefaultConntrackDump is a netlink conntrack dump command, used when procfs table is not available.
+var DefaultConntrackDump = []string{"conntrack", "-L"}
+
+// Conntrack reads tracked flows from host conntrack table.
+type Conntrack struct {
+	host string
+	path string
+	dump []string
+}
+
+// NewConntrack creates flows source for local host, that is scanned under given name,
+// flows are resolved against its containers only.
+func NewConntrack(procRoot, host string, dump ...string) *Conntrack {
+	if len(dump) == 0 {
+		dump = DefaultConntrackDump
+	}
+
+	return &Conntrack{
+		host: host,
+		path: filepath.Join(procRoot, conntrackTable),
+		dump: dump,
+	}
+}
+
+func (c *Conntrack) Flows(cb func(*graph.Flow)) error {
+	fn := func(f *graph.Flow) {
+		f.Host = c.host
+
+		cb(f)
+	}
+
+	fd, err := os.Open(c.path)
+
+	switch {
+	case err == nil:
+		defer fd.Close()
+
+		if err = graph.ParseConntrack(fd, fn); err != nil {
+			return fmt.Errorf("parse '%s': %w", c.path, err)
+		}
+
+		return nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("open: %w", err)
+	}
+
+	out, err := exec.Command(c.dump[0], c.dump[1:]...).Output()
+	if err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+
+	if err = graph.ParseConntrack(bytes.NewReader(out), fn); err != nil {
+		return fmt.Errorf("parse dump: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package client_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/client"
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const testConntrack = `ipv4     2 tcp      6 431999 ESTABLISHED src=172.20.0.2 dst=172.20.0.3 sport=40000 dport=5432 src=172.20.0.3 dst=172.20.0.2 sport=5432 dport=40000 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 udp      17 28 src=172.20.0.2 dst=8.8.8.8 sport=5353 dport=53 [UNREPLIED] src=8.8.8.8 dst=192.168.1.2 sport=53 dport=5353 mark=0 zone=0 use=2
+`
+
+func TestConntrackFile(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	table := filepath.Join(root, "1", "net", "nf_conntrack")
+
+	if err := os.MkdirAll(filepath.Dir(table), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(table, []byte(testConntrack), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var flows []*graph.Flow
+
+	if err := client.NewConntrack(root, "host-a").Flows(func(f *graph.Flow) {
+		flows = append(flows, f)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 2 || flows[0].ReplySport != 5432 || flows[1].Proto != graph.UDP || flows[0].Host != "host-a" {
+		t.Fatal("flows:", flows)
+	}
+}
+
+func TestConntrackDump(t *testing.T) {
+	t.Parallel()
+
+	dump := filepath.Join(t.TempDir(), "dump")
+
+	if err := os.WriteFile(dump, []byte(testConntrack), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+
+	if err := client.NewConntrack(t.TempDir(), "", "cat", dump).Flows(func(_ *graph.Flow) {
+		count++
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Fatal("count:", count)
+	}
+
+	if err := client.NewConntrack(t.TempDir(), "", "false").Flows(func(_ *graph.Flow) {}); err == nil {
+		t.Fatal("no error")
+	}
+}
@@ -0,0 +1,426 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/s0rg/set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	DefaultCRIHost = "unix:///run/containerd/containerd.sock"
+
+	criInfoKey = "info"
+)
+
+var (
+	ErrCRINoDial    = errors.New("cri: no dialer")
+	ErrCRINoNsenter = errors.New("cri: nsenter required")
+	ErrCRINoPid     = errors.New("cri: no pid")
+)
+
+type (
+	dialCRI func() (*grpc.ClientConn, error)
+
+	// criInfo is a part of verbose container status, reported by containerd and cri-o.
+	criInfo struct {
+		RuntimeSpec struct {
+			Process struct {
+				Args []string `json:"args"`
+				Env  []string `json:"env"`
+			} `json:"process"`
+		} `json:"runtimeSpec"`
+		Pid int `json:"pid"`
+	}
+
+	criContainer struct {
+		Con     *graph.Container
+		Sandbox string
+		Process string
+		Pid     int
+		Skipped bool
+	}
+)
+
+type CRI struct {
+	opt  *options
+	conn *grpc.ClientConn
+	cli  runtimeapi.RuntimeServiceClient
+}
+
+// DialCRI creates dialer for cri runtime service at given endpoint, i.e. DefaultCRIHost.
+func DialCRI(endpoint string) func() (*grpc.ClientConn, error) {
+	return func() (*grpc.ClientConn, error) {
+		return grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+}
+
+func NewCRI(opts ...Option) (rv *CRI, err error) {
+	rv = &CRI{
+		opt: &options{},
+	}
+
+	for _, op := range opts {
+		op(rv.opt)
+	}
+
+	if rv.opt.Workers < 1 {
+		rv.opt.Workers = 1
+	}
+
+	switch {
+	case rv.opt.Dial == nil:
+		return nil, fmt.Errorf("options: %w", ErrCRINoDial)
+	case rv.opt.Nsenter == nil:
+		return nil, fmt.Errorf("options: %w", ErrCRINoNsenter)
+	}
+
+	if rv.conn, err = rv.opt.Dial(); err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	rv.cli = runtimeapi.NewRuntimeServiceClient(rv.conn)
+
+	return rv, nil
+}
+
+func (c *CRI) Mode() string {
+	return "cri / " + LinuxNsenter.String()
+}
+
+func (c *CRI) Close() (err error) {
+	if err = c.conn.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CRI) Containers(
+	ctx context.Context,
+	proto graph.NetProto,
+	deep bool,
+	skipkeys []string,
+	progress func(int, int),
+) (rv []*graph.Container, err error) {
+	sandboxes, err := c.cli.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{
+		Filter: &runtimeapi.PodSandboxFilter{
+			State: &runtimeapi.PodSandboxStateValue{State: runtimeapi.PodSandboxState_SANDBOX_READY},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sandboxes: %w", err)
+	}
+
+	containers, err := c.cli.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("containers: %w", err)
+	}
+
+	skeys := make(set.Unordered[string])
+
+	for _, key := range skipkeys {
+		skeys.Add(strings.ToUpper(key))
+	}
+
+	pods := make(map[string]*runtimeapi.PodSandbox, len(sandboxes.Items))
+
+	for _, s := range sandboxes.Items {
+		pods[s.Id] = s
+	}
+
+	results := make([]*criContainer, len(containers.Containers))
+
+	_ = parallel(c.opt.Workers, len(containers.Containers), func(i int) error {
+		cc := containers.Containers[i]
+
+		pod, ok := pods[cc.PodSandboxId]
+		if !ok || ctx.Err() != nil {
+			return nil
+		}
+
+		cctx, cancel := c.opt.containerContext(ctx)
+		defer cancel()
+
+		res, cerr := c.extractInfo(cctx, cc, pod, skeys)
+
+		switch {
+		case cerr == nil:
+		case errors.Is(cerr, context.Canceled):
+			// scan is interrupted, graph is built from already scanned containers
+			return nil
+		default:
+			log.Printf("container: %s %s error: %v", cc.Metadata.GetName(), cc.Id, cerr)
+
+			return nil
+		}
+
+		results[i] = res
+
+		return nil
+	})
+
+	var (
+		order  []string
+		groups = make(map[string][]*criContainer)
+	)
+
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+
+		if _, ok := groups[res.Sandbox]; !ok {
+			order = append(order, res.Sandbox)
+		}
+
+		groups[res.Sandbox] = append(groups[res.Sandbox], res)
+	}
+
+	counter := &progressCounter{report: progress, total: len(order)}
+
+	_ = parallel(c.opt.Workers, len(order), func(i int) error {
+		defer counter.Step()
+
+		id := order[i]
+
+		if ctx.Err() != nil {
+			skipMembers(groups[id])
+
+			return nil
+		}
+
+		cctx, cancel := c.opt.containerContext(ctx)
+		defer cancel()
+
+		serr := c.scanSandbox(cctx, pods[id], groups[id], proto, deep)
+
+		switch {
+		case serr == nil:
+		case isTimeout(serr):
+			log.Printf("sandbox: %s %s timed out: %v", pods[id].Metadata.GetName(), id, serr)
+
+			for _, m := range groups[id] {
+				m.Con.TimedOut = true
+			}
+		case errors.Is(serr, context.Canceled):
+			// scan is interrupted, members of unscanned sandbox are skipped
+			skipMembers(groups[id])
+		default:
+			log.Printf("sandbox: %s %s error: %v", pods[id].Metadata.GetName(), id, serr)
+		}
+
+		return nil
+	})
+
+	rv = make([]*graph.Container, 0, len(results))
+
+	for _, res := range results {
+		if res == nil || res.Skipped {
+			continue
+		}
+
+		res.Con.SortConnections()
+
+		rv = append(rv, res.Con)
+	}
+
+	counter.Done()
+
+	return rv, nil
+}
+
+func (c *CRI) extractInfo(
+	ctx context.Context,
+	cc *runtimeapi.Container,
+	pod *runtimeapi.PodSandbox,
+	skeys set.Unordered[string],
+) (rv *criContainer, err error) {
+	status, err := c.cli.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{
+		ContainerId: cc.Id,
+		Verbose:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("status: %w", err)
+	}
+
+	var info criInfo
+
+	if err = json.Unmarshal([]byte(status.Info[criInfoKey]), &info); err != nil {
+		return nil, fmt.Errorf("info: %w", err)
+	}
+
+	if info.Pid == 0 {
+		return nil, ErrCRINoPid
+	}
+
+	meta := pod.GetMetadata()
+
+	labels := maps.Clone(pod.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	maps.Copy(labels, cc.Labels)
+
+	labels[graph.CRIPodLabel] = meta.GetName()
+	labels[graph.CRINamespaceLabel] = meta.GetNamespace()
+
+	mounts := make([]container.MountPoint, len(status.GetStatus().GetMounts()))
+
+	for i, m := range status.GetStatus().GetMounts() {
+		mounts[i] = container.MountPoint{
+			Type:        mount.TypeBind,
+			Source:      m.HostPath,
+			Destination: m.ContainerPath,
+		}
+	}
+
+	image := status.GetStatus().GetImage().GetImage()
+	if image == "" {
+		image = cc.GetImage().GetImage()
+	}
+
+	rv = &criContainer{
+		Sandbox: pod.Id,
+		Pid:     info.Pid,
+		Process: graph.ProcessUnknown,
+		Con: &graph.Container{
+			ID:        cc.Id,
+			Name:      meta.GetNamespace() + "/" + meta.GetName() + "/" + cc.Metadata.GetName(),
+			Image:     image,
+			Labels:    labels,
+			Endpoints: make(map[string]string),
+			Volumes:   extractVolumesInfo(mounts),
+			Strategy:  StrategyNsenter,
+			Info: &graph.ContainerInfo{
+				Cmd: info.RuntimeSpec.Process.Args,
+				Env: filterEnv(info.RuntimeSpec.Process.Env, skeys),
+			},
+		},
+	}
+
+	if args := info.RuntimeSpec.Process.Args; len(args) > 0 {
+		rv.Process = filepath.Base(args[0])
+	}
+
+	return rv, nil
+}
+
+// scanSandbox reads socket tables of pod shared network namespace once, sockets are attributed
+// to pod containers by descriptors inodes of all their processes, those with unknown owners are
+// dropped, unless pod has single container.
+func (c *CRI) scanSandbox(
+	ctx context.Context,
+	pod *runtimeapi.PodSandbox,
+	members []*criContainer,
+	proto graph.NetProto,
+	deep bool,
+) error {
+	status, err := c.cli.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{
+		PodSandboxId: pod.Id,
+	})
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+
+	netw := status.GetStatus().GetNetwork()
+	ips := []string{netw.GetIp()}
+
+	for _, ip := range netw.GetAdditionalIps() {
+		ips = append(ips, ip.GetIp())
+	}
+
+	ips = slices.DeleteFunc(ips, func(ip string) bool { return ip == "" })
+	owners := make(map[uint64]*criContainer)
+
+	for _, m := range members {
+		for _, ip := range ips {
+			m.Con.Endpoints[graph.NormalizeAddr(ip)] = pod.GetMetadata().GetNamespace()
+		}
+
+		if c.opt.Inodes == nil {
+			continue
+		}
+
+		for _, pid := range c.containerPids(m) {
+			if err = c.opt.Inodes(pid, func(inode uint64) {
+				owners[inode] = m
+			}); err != nil {
+				log.Printf("container: %s inodes %d: %v", m.Con.Name, pid, err)
+			}
+		}
+
+		if err = ctx.Err(); err != nil {
+			return fmt.Errorf("inodes: %w", err)
+		}
+	}
+
+	if err = c.opt.Nsenter(members[0].Pid, proto, func(_ int, conn *graph.Connection) {
+		if (!deep && conn.IsLocal()) || !c.opt.matchState(conn) {
+			return
+		}
+
+		owner, ok := owners[conn.Inode]
+
+		switch {
+		case ok && conn.Inode != 0:
+			conn.Process = owner.Process
+		case len(members) == 1:
+			owner, conn.Process = members[0], graph.ProcessUnknown
+		default:
+			// any pod member may hold it, guessing may put edge on wrong container
+			return
+		}
+
+		owner.Con.AddConnection(conn)
+	}); err != nil {
+		return fmt.Errorf("nsenter: %w", err)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("nsenter: %w", err)
+	}
+
+	return nil
+}
+
+// containerPids returns pids of all container processes, main one is used, if they cannot be listed.
+func (c *CRI) containerPids(m *criContainer) (pids []int) {
+	if c.opt.Cgroup == nil {
+		return []int{m.Pid}
+	}
+
+	pids, err := c.opt.Cgroup(m.Pid)
+	if err != nil || len(pids) == 0 {
+		log.Printf("container: %s cgroup: %v", m.Con.Name, err)
+
+		return []int{m.Pid}
+	}
+
+	return pids
+}
+
+func skipMembers(members []*criContainer) {
+	for _, m := range members {
+		m.Skipped = true
+	}
+}
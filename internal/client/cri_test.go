@@ -0,0 +1,324 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/s0rg/decompose/internal/client"
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+type criMock struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+
+	Err        error
+	Sandboxes  []*runtimeapi.PodSandbox
+	Containers []*runtimeapi.Container
+	Info       map[string]string
+}
+
+func (cm *criMock) ListPodSandbox(
+	_ context.Context,
+	_ *runtimeapi.ListPodSandboxRequest,
+) (*runtimeapi.ListPodSandboxResponse, error) {
+	if cm.Err != nil {
+		return nil, cm.Err
+	}
+
+	return &runtimeapi.ListPodSandboxResponse{Items: cm.Sandboxes}, nil
+}
+
+func (cm *criMock) ListContainers(
+	_ context.Context,
+	_ *runtimeapi.ListContainersRequest,
+) (*runtimeapi.ListContainersResponse, error) {
+	return &runtimeapi.ListContainersResponse{Containers: cm.Containers}, nil
+}
+
+func (cm *criMock) ContainerStatus(
+	_ context.Context,
+	req *runtimeapi.ContainerStatusRequest,
+) (*runtimeapi.ContainerStatusResponse, error) {
+	return &runtimeapi.ContainerStatusResponse{
+		Status: &runtimeapi.ContainerStatus{
+			Id:    req.ContainerId,
+			Image: &runtimeapi.ImageSpec{Image: "repo/" + req.ContainerId},
+			Mounts: []*runtimeapi.Mount{
+				{ContainerPath: "/data", HostPath: "/var/data"},
+			},
+		},
+		Info: map[string]string{"info": cm.Info[req.ContainerId]},
+	}, nil
+}
+
+func (cm *criMock) PodSandboxStatus(
+	_ context.Context,
+	_ *runtimeapi.PodSandboxStatusRequest,
+) (*runtimeapi.PodSandboxStatusResponse, error) {
+	return &runtimeapi.PodSandboxStatusResponse{
+		Status: &runtimeapi.PodSandboxStatus{
+			Network: &runtimeapi.PodSandboxNetworkStatus{
+				Ip:            "10.0.0.5",
+				AdditionalIps: []*runtimeapi.PodIP{{Ip: "fd00::5"}},
+			},
+		},
+	}, nil
+}
+
+func testCRIDialer(t *testing.T, srv runtimeapi.RuntimeServiceServer) func() (*grpc.ClientConn, error) {
+	t.Helper()
+
+	const bufSize = 1 << 20
+
+	lis := bufconn.Listen(bufSize)
+	gs := grpc.NewServer()
+
+	runtimeapi.RegisterRuntimeServiceServer(gs, srv)
+
+	go func() { _ = gs.Serve(lis) }()
+
+	t.Cleanup(gs.Stop)
+
+	return func() (*grpc.ClientConn, error) {
+		return grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+	}
+}
+
+func testCRIMock() *criMock {
+	return &criMock{
+		Sandboxes: []*runtimeapi.PodSandbox{
+			{
+				Id:       "sb-1",
+				Metadata: &runtimeapi.PodSandboxMetadata{Name: "web", Namespace: "prod"},
+				Labels:   map[string]string{"app": "web"},
+			},
+		},
+		Containers: []*runtimeapi.Container{
+			{Id: "c1", PodSandboxId: "sb-1", Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"}},
+			{Id: "c2", PodSandboxId: "sb-1", Metadata: &runtimeapi.ContainerMetadata{Name: "app"}},
+			{Id: "c3", PodSandboxId: "sb-gone", Metadata: &runtimeapi.ContainerMetadata{Name: "orphan"}},
+			{Id: "c4", PodSandboxId: "sb-1", Metadata: &runtimeapi.ContainerMetadata{Name: "no-pid"}},
+		},
+		Info: map[string]string{
+			"c1": `{"pid": 10, "runtimeSpec": {"process": {"args": ["/usr/sbin/nginx"], "env": ["A=1", "SECRET=2"]}}}`,
+			"c2": `{"pid": 20, "runtimeSpec": {"process": {"args": ["app", "-v"]}}}`,
+			"c4": `{}`,
+		},
+	}
+}
+
+func TestCRIOptionsError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := client.NewCRI(); !errors.Is(err, client.ErrCRINoDial) {
+		t.Fatal("dial:", err)
+	}
+
+	if _, err := client.NewCRI(
+		client.WithCRIDialer(testCRIDialer(t, testCRIMock())),
+	); !errors.Is(err, client.ErrCRINoNsenter) {
+		t.Fatal("nsenter:", err)
+	}
+
+	testErr := errors.New("test-err")
+
+	if _, err := client.NewCRI(
+		client.WithCRIDialer(func() (*grpc.ClientConn, error) {
+			return nil, testErr
+		}),
+		client.WithNsenterFn(func(int, graph.NetProto, func(int, *graph.Connection)) error {
+			return nil
+		}),
+	); !errors.Is(err, testErr) {
+		t.Fatal("create:", err)
+	}
+}
+
+func TestCRIContainers(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+
+	testEnter := func(pid int, _ graph.NetProto, fn func(int, *graph.Connection)) error {
+		calls.Add(1)
+
+		if pid != 10 {
+			t.Error("pid:", pid)
+		}
+
+		for _, inode := range []uint64{1, 2, 3, 5} {
+			fn(pid, &graph.Connection{
+				Proto:   graph.TCP,
+				Inode:   inode,
+				SrcIP:   net.ParseIP("10.0.0.5"),
+				SrcPort: 80 + int(inode),
+				Listen:  true,
+			})
+		}
+
+		fn(pid, &graph.Connection{
+			Proto:   graph.TCP,
+			Inode:   4,
+			SrcIP:   net.ParseIP("127.0.0.1"),
+			SrcPort: 9000,
+			Listen:  true,
+		})
+
+		return nil
+	}
+
+	testInodes := func(pid int, fn func(uint64)) error {
+		switch pid {
+		case 10:
+			fn(1)
+		case 20:
+			fn(2)
+		case 21:
+			fn(3)
+		}
+
+		return nil
+	}
+
+	// app has worker process, that holds own socket
+	testCgroup := func(pid int) ([]int, error) {
+		if pid == 20 {
+			return []int{20, 21}, nil
+		}
+
+		return []int{pid}, nil
+	}
+
+	cli, err := client.NewCRI(
+		client.WithCRIDialer(testCRIDialer(t, testCRIMock())),
+		client.WithNsenterFn(testEnter),
+		client.WithInodesFn(testInodes),
+		client.WithCgroupFn(testCgroup),
+		client.WithWorkers(2),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	defer cli.Close()
+
+	if cli.Mode() != "cri / linux-nsenter" {
+		t.Fail()
+	}
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, []string{"secret"}, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 2 || calls.Load() != 1 {
+		t.Fatal("result:", len(rv), calls.Load())
+	}
+
+	nginx, app := rv[0], rv[1]
+
+	if nginx.Name != "prod/web/nginx" || nginx.Image != "repo/c1" || nginx.Strategy != client.StrategyNsenter {
+		t.Fatal("nginx:", nginx.Name, nginx.Image)
+	}
+
+	if nginx.Labels[graph.CRIPodLabel] != "web" ||
+		nginx.Labels[graph.CRINamespaceLabel] != "prod" ||
+		nginx.Labels["app"] != "web" {
+		t.Fatal("labels:", nginx.Labels)
+	}
+
+	if nginx.Endpoints["10.0.0.5"] != "prod" || nginx.Endpoints["fd00::5"] != "prod" {
+		t.Fatal("endpoints:", nginx.Endpoints)
+	}
+
+	if len(nginx.Info.Env) != 1 || len(nginx.Volumes) != 1 || nginx.Volumes[0].Src != "/var/data" {
+		t.Fatal("info:", nginx.Info.Env, nginx.Volumes)
+	}
+
+	// socket with unknown owner is not attributed to any pod member
+	if nginx.ConnectionsCount() != 1 || app.ConnectionsCount() != 2 {
+		t.Fatal("connections:", nginx.ConnectionsCount(), app.ConnectionsCount())
+	}
+
+	procs := make(map[string]int)
+
+	nginx.IterListeners(func(c *graph.Connection) {
+		procs[c.Process]++
+	})
+
+	if procs["nginx"] != 1 || procs[graph.ProcessUnknown] != 0 {
+		t.Fatal("processes:", procs)
+	}
+}
+
+func TestCRIContainersError(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test-err")
+
+	cm := testCRIMock()
+	cm.Err = testErr
+
+	cli, err := client.NewCRI(
+		client.WithCRIDialer(testCRIDialer(t, cm)),
+		client.WithNsenterFn(func(int, graph.NetProto, func(int, *graph.Connection)) error {
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	defer cli.Close()
+
+	if _, err = cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress); err == nil {
+		t.Fatal("no error")
+	}
+}
+
+func TestCRIContainersTimeout(t *testing.T) {
+	t.Parallel()
+
+	testEnter := func(pid int, _ graph.NetProto, fn func(int, *graph.Connection)) error {
+		fn(pid, &graph.Connection{Proto: graph.TCP, SrcIP: net.ParseIP("10.0.0.5"), SrcPort: 80, Listen: true})
+
+		// socket tables are read slower, than sandbox timeout
+		time.Sleep(50 * time.Millisecond)
+
+		return nil
+	}
+
+	cli, err := client.NewCRI(
+		client.WithCRIDialer(testCRIDialer(t, testCRIMock())),
+		client.WithNsenterFn(testEnter),
+		client.WithTimeout(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	defer cli.Close()
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 2 || !rv[0].TimedOut || !rv[1].TimedOut {
+		t.Fatal("result:", rv)
+	}
+}
@@ -4,11 +4,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/client"
@@ -20,22 +20,32 @@ import (
 
 const (
 	pingTimeout = time.Second
-	procENV     = "IN_DOCKER_PROC_ROOT"
-	procDefault = "/proc"
-
-	// from net/tcp_states.h.
-	tcpEstablished = uint64(1)
-	tcpListen      = uint64(10)
 )
 
-var procROOT = procDefault
+var (
+	procROOT = procDefault
+
+	ErrNoNetns = errors.New("no network namespace")
+)
 
 func Default() (rv DockerClient, err error) {
+	return connect(client.FromEnv)
+}
+
+// DefaultHost creates client for docker-compatible api at given host, tls certificates
+// are loaded from DOCKER_CERT_PATH (verified, if DOCKER_TLS_VERIFY is set).
+func DefaultHost(host string) func() (DockerClient, error) {
+	return func() (DockerClient, error) {
+		// tls transport must be set before host, as host configures it
+		return connect(client.WithTLSClientConfigFromEnv(), client.WithHost(host))
+	}
+}
+
+func connect(opts ...client.Opt) (rv DockerClient, err error) {
 	var dc *client.Client
 
 	dc, err = client.NewClientWithOpts(
-		client.FromEnv,
-		client.WithAPIVersionNegotiation(),
+		append(opts, client.WithAPIVersionNegotiation())...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("docker: %w", err)
@@ -48,144 +58,11 @@ func Default() (rv DockerClient, err error) {
 		return nil, fmt.Errorf("ping: %w", err)
 	}
 
-	if root := os.Getenv(procENV); root != "" {
-		procROOT = filepath.Join(root, procDefault)
-	}
+	procROOT = ProcRoot()
 
 	return dc, nil
 }
 
-func checkState(state uint64) (listener, valid bool) {
-	if state == tcpListen {
-		return true, true
-	}
-
-	if state == tcpEstablished {
-		return false, true
-	}
-
-	return false, false
-}
-
-func scanTCP(
-	pfs procfs.FS,
-	name string,
-	onconn func(*graph.Connection),
-) {
-	tcp4, err := pfs.NetTCP()
-	if err != nil {
-		log.Printf("[-] procfs/tcp: %v", err)
-	} else {
-		for _, s := range tcp4 {
-			listener, ok := checkState(s.St)
-			if !ok {
-				continue
-			}
-
-			onconn(&graph.Connection{
-				Process: name,
-				Inode:   s.Inode,
-				SrcIP:   s.LocalAddr,
-				DstIP:   s.RemAddr,
-				SrcPort: int(s.LocalPort),
-				DstPort: int(s.RemPort),
-				Proto:   graph.TCP,
-				Listen:  listener,
-			})
-		}
-	}
-
-	tcp6, err := pfs.NetTCP6()
-	if err != nil {
-		log.Printf("[-] procfs/tcp6: %v", err)
-
-		return
-	}
-
-	for _, s := range tcp6 {
-		listener, ok := checkState(s.St)
-		if !ok {
-			continue
-		}
-
-		onconn(&graph.Connection{
-			Process: name,
-			Inode:   s.Inode,
-			SrcIP:   s.LocalAddr,
-			DstIP:   s.RemAddr,
-			SrcPort: int(s.LocalPort),
-			DstPort: int(s.RemPort),
-			Proto:   graph.TCP,
-			Listen:  listener,
-		})
-	}
-}
-
-func scanUDP(
-	pfs procfs.FS,
-	name string,
-	onconn func(*graph.Connection),
-) {
-	udp4, err := pfs.NetUDP()
-	if err != nil {
-		log.Printf("[-] procfs/udp: %v", err)
-	} else {
-		for _, s := range udp4 {
-			onconn(&graph.Connection{
-				Process: name,
-				Inode:   s.Inode,
-				SrcIP:   s.LocalAddr,
-				DstIP:   s.RemAddr,
-				SrcPort: int(s.LocalPort),
-				DstPort: int(s.RemPort),
-				Proto:   graph.UDP,
-			})
-		}
-	}
-
-	udp6, err := pfs.NetUDP6()
-	if err != nil {
-		log.Printf("[-] procfs/udp6: %v", err)
-
-		return
-	}
-
-	for _, s := range udp6 {
-		onconn(&graph.Connection{
-			Process: name,
-			Inode:   s.Inode,
-			SrcIP:   s.LocalAddr,
-			DstIP:   s.RemAddr,
-			SrcPort: int(s.LocalPort),
-			DstPort: int(s.RemPort),
-			Proto:   graph.UDP,
-		})
-	}
-}
-
-func scanUNIX(
-	pfs procfs.FS,
-	name string,
-	onconn func(*graph.Connection),
-) {
-	unix, err := pfs.NetUNIX()
-	if err != nil {
-		log.Printf("[-] procfs/unix: %v", err)
-
-		return
-	}
-
-	for _, s := range unix.Rows {
-		onconn(&graph.Connection{
-			Process: name,
-			Inode:   s.Inode,
-			Path:    s.Path,
-			Listen:  s.Flags != 0,
-			Proto:   graph.UNIX,
-		})
-	}
-}
-
 func processInfo(pid int) (
 	name string,
 	err error,
@@ -243,6 +120,89 @@ func Inodes(
 	return nil
 }
 
+// CgroupPids returns pids of all processes from cgroup (or its children) of given one.
+func CgroupPids(pid int) (pids []int, err error) {
+	pfs, err := procfs.NewFS(procROOT)
+	if err != nil {
+		return nil, fmt.Errorf("procfs: %w", err)
+	}
+
+	proc, err := pfs.Proc(pid)
+	if err != nil {
+		return nil, fmt.Errorf("procfs/pid: %w", err)
+	}
+
+	cgroups, err := proc.Cgroups()
+	if err != nil {
+		return nil, fmt.Errorf("procfs/cgroups: %w", err)
+	}
+
+	root, ok := mainCgroup(cgroups)
+	if !ok || root.Path == "/" {
+		// root cgroup holds every process on host
+		return []int{pid}, nil
+	}
+
+	procs, err := pfs.AllProcs()
+	if err != nil {
+		return nil, fmt.Errorf("procfs/all: %w", err)
+	}
+
+	for _, p := range procs {
+		// process may be already gone
+		cgs, err := p.Cgroups()
+		if err != nil {
+			continue
+		}
+
+		if cg, ok := mainCgroup(cgs); ok && cg.HierarchyID == root.HierarchyID &&
+			(cg.Path == root.Path || strings.HasPrefix(cg.Path, root.Path+"/")) {
+			pids = append(pids, p.PID)
+		}
+	}
+
+	return pids, nil
+}
+
+// mainCgroup returns unified (v2) cgroup or first one of v1 hierarchies.
+func mainCgroup(cgroups []procfs.Cgroup) (rv procfs.Cgroup, ok bool) {
+	for _, cg := range cgroups {
+		if cg.HierarchyID == 0 {
+			return cg, true
+		}
+	}
+
+	if len(cgroups) == 0 {
+		return rv, false
+	}
+
+	return cgroups[0], true
+}
+
+func Netns(pid int) (inode uint64, err error) {
+	pfs, err := procfs.NewFS(procROOT)
+	if err != nil {
+		return 0, fmt.Errorf("procfs: %w", err)
+	}
+
+	proc, err := pfs.Proc(pid)
+	if err != nil {
+		return 0, fmt.Errorf("procfs/pid: %w", err)
+	}
+
+	nss, err := proc.Namespaces()
+	if err != nil {
+		return 0, fmt.Errorf("procfs/ns: %w", err)
+	}
+
+	ns, ok := nss[nsNet]
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", ErrNoNetns, pid)
+	}
+
+	return uint64(ns.Inode), nil
+}
+
 func Nsenter(
 	pid int,
 	proto graph.NetProto,
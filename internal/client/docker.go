@@ -8,13 +8,20 @@ import (
 	"io"
 	"log"
 	"maps"
+	"net"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/s0rg/set"
 
 	"github.com/s0rg/decompose/internal/graph"
@@ -23,14 +30,24 @@ import (
 const (
 	stateRunning = "running"
 	runcLstatErr = "no such file or directory"
+
+	// exit codes for exec-ed binary, that cannot be found or executed.
+	exitNotExecutable = 126
+	exitNotFound      = 127
 )
 
-var ErrModeNone = errors.New("mode not set")
+var (
+	ErrModeNone    = errors.New("mode not set")
+	ErrCmdNotFound = errors.New("command not found")
+)
 
 type (
 	createClient func() (DockerClient, error)
 	nsenter      func(int, graph.NetProto, func(int, *graph.Connection)) error
 	inodes       func(int, func(uint64)) error
+	netns        func(int) (uint64, error)
+	cgroup       func(int) ([]int, error)
+	pods         func(context.Context) ([]*Pod, error)
 )
 
 type DockerClient interface {
@@ -38,28 +55,65 @@ type DockerClient interface {
 	ContainerInspect(context.Context, string) (container.InspectResponse, error)
 	ContainerExecCreate(context.Context, string, container.ExecOptions) (container.ExecCreateResponse, error)
 	ContainerExecAttach(context.Context, string, container.ExecStartOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(context.Context, string) (container.ExecInspect, error)
 	ContainerTop(ctx context.Context, containerID string, arguments []string) (container.TopResponse, error)
+	ContainerCreate(
+		ctx context.Context,
+		config *container.Config,
+		hostConfig *container.HostConfig,
+		networkingConfig *network.NetworkingConfig,
+		platform *ocispec.Platform,
+		containerName string,
+	) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+	NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+	ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error)
 	Close() error
 }
 
+// sampleTarget holds discovered container, its sockets are re-read on every next sample.
+type sampleTarget struct {
+	Keep func(int, *graph.Connection) bool
+	Name string
+}
+
 type Docker struct {
-	opt *options
-	cli DockerClient
+	opt     *options
+	cli     DockerClient
+	sampled map[string]*sampleTarget
+	chain   []*execStrategy
+	mu      sync.Mutex
 }
 
 func NewDocker(opts ...Option) (rv *Docker, err error) {
 	rv = &Docker{
-		opt: &options{},
+		opt:     &options{},
+		sampled: make(map[string]*sampleTarget),
 	}
 
 	for _, op := range opts {
 		op(rv.opt)
 	}
 
+	if rv.opt.Workers < 1 {
+		rv.opt.Workers = 1
+	}
+
 	if rv.opt.Mode == None {
 		return nil, fmt.Errorf("options: %w", ErrModeNone)
 	}
 
+	if rv.opt.Sidecar == "" {
+		rv.opt.Sidecar = DefaultSidecarImage
+	}
+
+	if rv.chain, err = makeChain(rv.opt.Chain); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
 	if rv.cli, err = rv.opt.Create(); err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -89,12 +143,37 @@ func (d *Docker) Containers(
 		skeys.Add(strings.ToUpper(key))
 	}
 
+	members, infra, err := d.podMembers(ctx, containers)
+	if err != nil {
+		return nil, err
+	}
+
+	// interrupted pull leaves all containers unscanned, as with interrupted scan
+	if d.opt.Mode == Sidecar {
+		if err = d.pullSidecar(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+
 	rv = make([]*graph.Container, 0, len(containers))
 
 	var inodes *InodesMap
 
-	if proto.Has(graph.UNIX) {
+	// nsenter mode attributes sockets to processes by descriptors inodes
+	if proto.Has(graph.UNIX) || (d.opt.Mode == LinuxNsenter && d.opt.Inodes != nil) {
 		if inodes, err = d.collectInodes(ctx, containers, func(idx int, err error) (stop bool) {
+			if errors.Is(err, context.Canceled) {
+				return false
+			}
+
+			if isTimeout(err) {
+				c := &containers[idx]
+
+				log.Printf("container: %s %s inodes timed out", c.Names[0], c.ID)
+
+				return false
+			}
+
 			if strings.Contains(err.Error(), runcLstatErr) {
 				c := &containers[idx]
 				c.State = ""
@@ -110,27 +189,59 @@ func (d *Docker) Containers(
 		}
 	}
 
-	cmap := make(map[string]*graph.Container)
+	var (
+		cmap     = make(map[string]*graph.Container)
+		nets     = d.inspectNetworks(ctx, containers)
+		vips     = d.serviceVIPs(ctx, containers)
+		gateways = extractGateways(containers)
+		results  = make([]*graph.Container, len(containers))
+		counter  = &progressCounter{report: progress, total: len(containers)}
+	)
+
+	_ = parallel(d.opt.Workers, len(containers), func(i int) error {
+		defer counter.Step()
 
-	for i := 0; i < len(containers); i++ {
 		c := &containers[i]
 
-		if c.State != stateRunning {
-			continue
+		if c.State != stateRunning || isSidecar(c) || infra.Has(c.ID) || ctx.Err() != nil {
+			return nil
 		}
 
-		con, cerr := d.extractInfo(ctx, c, proto, deep, skeys, inodes)
-		if cerr != nil {
+		cctx, cancel := d.opt.containerContext(ctx)
+		defer cancel()
+
+		con, cerr := d.extractInfo(cctx, c, proto, deep, skeys, inodes, nets, members[c.ID])
+
+		switch {
+		case cerr == nil:
+		case isTimeout(cerr):
+			log.Printf("container: %s %s timed out: %v", c.Names[0], c.ID, cerr)
+
+			con.TimedOut = true
+		case errors.Is(cerr, context.Canceled):
+			// scan is interrupted, graph is built from already scanned containers
+			return nil
+		default:
 			log.Printf("container: %s %s error: %v", c.Names[0], c.ID, cerr)
 
+			return nil
+		}
+
+		con.Published = extractPublished(c.Ports, d.opt.HostIPs, gateways)
+		con.VIPs = vips[c.Labels[graph.SwarmServiceIDLabel]]
+		results[i] = con
+
+		return nil
+	})
+
+	for _, con := range results {
+		if con == nil {
 			continue
 		}
 
-		cmap[c.ID] = con
+		cmap[con.ID] = con
 
 		rv = append(rv, con)
-
-		progress(i, len(containers))
 	}
 
 	if proto.Has(graph.UNIX) {
@@ -149,11 +260,120 @@ func (d *Docker) Containers(
 		})
 	}
 
-	progress(len(containers), len(containers))
+	counter.Done()
 
 	return slices.Clip(rv), nil
 }
 
+// Resample re-reads sockets of containers, found by last Containers call, discovery is not
+// repeated. Unix sockets are matched by descriptors, collected on discovery, so they are not re-read,
+// in nsenter mode descriptors are walked again, as sockets owners may change between samples.
+func (d *Docker) Resample(
+	ctx context.Context,
+	proto graph.NetProto,
+	deep bool,
+	containers []*graph.Container,
+	progress func(int, int),
+) (rv []*graph.Container, err error) {
+	proto &^= graph.UNIX
+
+	var ids []string
+
+	for _, c := range containers {
+		if _, ok := d.sampled[c.ID]; ok && c.Host == d.opt.Host {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	inodes := d.resampleInodes(ctx, ids)
+
+	var (
+		results = make([]*graph.Container, len(ids))
+		counter = &progressCounter{report: progress, total: len(ids)}
+	)
+
+	_ = parallel(d.opt.Workers, len(ids), func(i int) error {
+		defer counter.Step()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		cid := ids[i]
+		tgt := d.sampled[cid]
+		con := &graph.Container{ID: cid}
+
+		cctx, cancel := d.opt.containerContext(ctx)
+		defer cancel()
+
+		_, cerr := d.connections(cctx, cid, proto, inodes, func(pid int, conn *graph.Connection) {
+			if (!deep && conn.IsLocal()) || !d.opt.matchState(conn) || !tgt.Keep(pid, conn) {
+				return
+			}
+
+			con.AddConnection(conn)
+		})
+
+		switch {
+		case cerr == nil:
+		case isTimeout(cerr):
+			log.Printf("container: %s %s timed out: %v", tgt.Name, cid, cerr)
+
+			con.TimedOut = true
+		case errors.Is(cerr, context.Canceled):
+			// scan is interrupted, graph is built from already scanned containers
+			return nil
+		default:
+			log.Printf("container: %s %s error: %v", tgt.Name, cid, cerr)
+
+			return nil
+		}
+
+		con.SortConnections()
+		results[i] = con
+
+		return nil
+	})
+
+	for _, con := range results {
+		if con != nil {
+			rv = append(rv, con)
+		}
+	}
+
+	counter.Done()
+
+	return rv, nil
+}
+
+// resampleInodes collects descriptors inodes of sampled containers in nsenter mode, failed
+// containers are left without owners.
+func (d *Docker) resampleInodes(ctx context.Context, ids []string) (inodes *InodesMap) {
+	if d.opt.Mode != LinuxNsenter || d.opt.Inodes == nil {
+		return nil
+	}
+
+	targets := make([]container.Summary, len(ids))
+
+	for i, cid := range ids {
+		targets[i] = container.Summary{
+			ID:    cid,
+			Names: []string{d.sampled[cid].Name},
+			State: stateRunning,
+		}
+	}
+
+	inodes, _ = d.collectInodes(ctx, targets, func(idx int, err error) (stop bool) {
+		if !errors.Is(err, context.Canceled) {
+			log.Printf("container: %s %s inodes: %v", targets[idx].Names[0], targets[idx].ID, err)
+		}
+
+		return false
+	})
+
+	return inodes
+}
+
 func (d *Docker) Close() (err error) {
 	if err = d.cli.Close(); err != nil {
 		return fmt.Errorf("close: %w", err)
@@ -172,14 +392,17 @@ func (d *Docker) collectInodes(
 ) {
 	inodes = &InodesMap{}
 
-	for i := 0; i < len(containers); i++ {
+	err = parallel(d.opt.Workers, len(containers), func(i int) error {
 		c := &containers[i]
 
 		if c.State != stateRunning {
-			continue
+			return nil
 		}
 
-		err = d.processesContainer(ctx, c.ID, func(pid int, name string) (err error) {
+		cctx, cancel := d.opt.containerContext(ctx)
+		defer cancel()
+
+		perr := d.processesContainer(cctx, c.ID, func(pid int, name string) (err error) {
 			inodes.AddProcess(c.ID, pid, name)
 
 			if err = d.opt.Inodes(pid, func(inode uint64) {
@@ -190,9 +413,14 @@ func (d *Docker) collectInodes(
 
 			return nil
 		})
-		if err != nil && errcb(i, err) {
-			return nil, fmt.Errorf("inodes %s: %w", c.Names[0], err)
+		if perr != nil && errcb(i, perr) {
+			return fmt.Errorf("inodes %s: %w", c.Names[0], perr)
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return inodes, nil
@@ -205,25 +433,50 @@ func (d *Docker) extractInfo(
 	deep bool,
 	skeys set.Unordered[string],
 	inodes *InodesMap,
+	nets map[string]*graph.Network,
+	pod *podMember,
 ) (rv *graph.Container, err error) {
 	rv = &graph.Container{
 		ID:        c.ID,
 		Image:     c.Image,
 		Name:      strings.TrimLeft(c.Names[0], "/"),
+		Host:      d.opt.Host,
 		Labels:    maps.Clone(c.Labels),
 		Endpoints: extractEndpoints(c.NetworkSettings.Networks),
 	}
 
 	info, err := d.cli.ContainerInspect(ctx, c.ID)
 	if err != nil {
-		return nil, fmt.Errorf("inspect: %w", err)
+		return rv, fmt.Errorf("inspect: %w", err)
 	}
 
 	rv.Volumes = extractVolumesInfo(info.Mounts)
 	rv.Info = extractContainerInfo(&info, skeys)
 
-	if err := d.connections(ctx, c.ID, proto, func(pid int, conn *graph.Connection) {
-		if !deep && conn.IsLocal() {
+	if info.NetworkSettings != nil {
+		rv.Networks = extractNetworkEndpoints(info.NetworkSettings.Networks, nets)
+	}
+
+	keep := func(int, *graph.Connection) bool { return true }
+
+	if pod != nil {
+		if rv.Labels == nil {
+			rv.Labels = make(map[string]string)
+		}
+
+		rv.Labels[graph.PodmanPodLabel] = pod.Name
+
+		if keep, err = d.podFilter(ctx, c.ID, pod); err != nil {
+			return rv, fmt.Errorf("pod: %w", err)
+		}
+	}
+
+	d.mu.Lock()
+	d.sampled[c.ID] = &sampleTarget{Name: rv.Name, Keep: keep}
+	d.mu.Unlock()
+
+	if rv.Strategy, err = d.connections(ctx, c.ID, proto, inodes, func(pid int, conn *graph.Connection) {
+		if (!deep && conn.IsLocal()) || !d.opt.matchState(conn) || !keep(pid, conn) {
 			return
 		}
 
@@ -241,7 +494,9 @@ func (d *Docker) extractInfo(
 
 		rv.AddConnection(conn)
 	}); err != nil {
-		return nil, fmt.Errorf("connections: %w", err)
+		rv.SortConnections()
+
+		return rv, fmt.Errorf("connections: %w", err)
 	}
 
 	rv.SortConnections()
@@ -253,40 +508,122 @@ func (d *Docker) connections(
 	ctx context.Context,
 	cid string,
 	proto graph.NetProto,
+	inodes *InodesMap,
 	cb func(int, *graph.Connection),
-) (err error) {
+) (strategy string, err error) {
 	switch d.opt.Mode {
 	case InContainer:
-		err = d.connectionsContainer(ctx, cid, proto, func(r io.Reader) (err error) {
-			if err = graph.ParseNetstat(r, func(c *graph.Connection) {
-				cb(1, c)
-			}); err != nil {
-				return fmt.Errorf("parse: %w", err)
+		strategy, err = d.connectionsInContainer(ctx, cid, proto, cb)
+	case LinuxNsenter:
+		strategy, err = StrategyNsenter, d.connectionsNsenter(ctx, cid, proto, inodes, cb)
+	case Sidecar:
+		strategy, err = d.connectionsSidecar(ctx, cid, proto, cb)
+	}
+
+	if err != nil {
+		return strategy, fmt.Errorf("%s: %w", d.opt.Mode, err)
+	}
+
+	return strategy, nil
+}
+
+// connectionsNsenter reads socket tables once per network namespace, sockets are
+// attributed to their owners by matching descriptors inodes, collected for all containers.
+func (d *Docker) connectionsNsenter(
+	ctx context.Context,
+	cid string,
+	proto graph.NetProto,
+	inodes *InodesMap,
+	cb func(int, *graph.Connection),
+) (err error) {
+	var (
+		seen   = make(set.Unordered[uint64])
+		owners map[uint64]int
+		names  = make(map[int]string)
+		pids   []int
+	)
+
+	if inodes != nil {
+		owners = inodes.Owners(cid)
+	}
+
+	if err = d.processesContainer(ctx, cid, func(pid int, name string) error {
+		names[pid] = filepath.Base(name)
+
+		if d.opt.Netns != nil {
+			if ns, nerr := d.opt.Netns(pid); nerr == nil && !seen.Add(ns) {
+				return nil
 			}
+		}
 
-			return nil
-		})
-	case LinuxNsenter:
-		err = d.processesContainer(ctx, cid, func(pid int, _ string) (err error) {
-			if err := d.opt.Nsenter(pid, proto, cb); err != nil {
-				return err
+		pids = append(pids, pid)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, pid := range pids {
+		if err = d.opt.Nsenter(pid, proto, func(spid int, conn *graph.Connection) {
+			owner, owned := owners[conn.Inode]
+			if owned && conn.Inode != 0 {
+				spid = owner
+			}
+
+			// all sockets are named by container top, so process has single name
+			conn.Process = names[spid]
+
+			if !owned && d.opt.Pods != nil && inodes != nil {
+				// socket from shared pod namespace, owned by another member
+				conn.Process = graph.ProcessUnknown
+			}
+
+			cb(spid, conn)
+		}); err != nil {
+			return fmt.Errorf("pid: %d: %w", pid, err)
+		}
+	}
+
+	return nil
+}
+
+// connectionsInContainer walks through exec strategies chain, until first one,
+// which binary is present inside container.
+func (d *Docker) connectionsInContainer(
+	ctx context.Context,
+	cid string,
+	proto graph.NetProto,
+	cb func(int, *graph.Connection),
+) (strategy string, err error) {
+	for _, s := range d.chain {
+		err = d.connectionsContainer(ctx, cid, s.Cmd(proto), func(r io.Reader) (err error) {
+			if err = s.Parse(r, d.opt.States, func(c *graph.Connection) {
+				// strategies may report more protocols, than requested
+				if proto.Has(c.Proto) {
+					cb(1, c)
+				}
+			}); err != nil {
+				return fmt.Errorf("parse: %w", err)
 			}
 
 			return nil
 		})
-	}
 
-	if err != nil {
-		return fmt.Errorf("%s: %w", d.opt.Mode, err)
+		switch {
+		case err == nil:
+			return s.Name, nil
+		case !errors.Is(err, ErrCmdNotFound):
+			return s.Name, fmt.Errorf("%s: %w", s.Name, err)
+		}
 	}
 
-	return nil
+	return "", fmt.Errorf("strategies: %w", err)
 }
 
 func (d *Docker) connectionsContainer(
 	ctx context.Context,
 	containerID string,
-	proto graph.NetProto,
+	cmd []string,
 	parse func(io.Reader) error,
 ) (
 	err error,
@@ -295,7 +632,7 @@ func (d *Docker) connectionsContainer(
 		Tty:          true,
 		AttachStdout: true,
 		Privileged:   true,
-		Cmd:          graph.NetstatCMD(proto),
+		Cmd:          cmd,
 	})
 	if err != nil {
 		return fmt.Errorf("exec-create: %w", err)
@@ -310,10 +647,28 @@ func (d *Docker) connectionsContainer(
 
 	defer resp.Close()
 
+	// unblock reading from hung exec, once context is done
+	stop := context.AfterFunc(ctx, resp.Close)
+	defer stop()
+
 	if err = parse(resp.Reader); err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			err = cerr
+		}
+
 		return fmt.Errorf("parse: %w", err)
 	}
 
+	info, err := d.cli.ContainerExecInspect(ctx, exe.ID)
+	if err != nil {
+		return fmt.Errorf("exec-inspect: %w", err)
+	}
+
+	switch info.ExitCode {
+	case exitNotFound, exitNotExecutable:
+		return fmt.Errorf("%w: %s", ErrCmdNotFound, cmd[0])
+	}
+
 	return nil
 }
 
@@ -350,28 +705,44 @@ func (d *Docker) processesContainer(
 	return nil
 }
 
+func isSidecar(c *container.Summary) (yes bool) {
+	_, yes = c.Labels[SidecarLabel]
+
+	return yes
+}
+
+// isTimeout reports whether err is a deadline expiration, containers, cancelled (i.e. by signal)
+// are not timed out, they are skipped.
+func isTimeout(err error) (yes bool) {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
 func extractContainerInfo(
 	c *container.InspectResponse,
 	s set.Unordered[string],
 ) (rv *graph.ContainerInfo) {
-	rv = &graph.ContainerInfo{
+	return &graph.ContainerInfo{
 		Cmd: c.Config.Cmd,
+		Env: filterEnv(c.Config.Env, s),
 	}
+}
 
+func filterEnv(
+	env []string,
+	s set.Unordered[string],
+) (rv []string) {
 	if s.Len() == 0 {
-		rv.Env = c.Config.Env
-
-		return rv
+		return env
 	}
 
 	const nparts = 2
 
-	for _, env := range c.Config.Env {
-		if key := strings.SplitN(env, "=", nparts)[0]; s.Has(key) {
+	for _, e := range env {
+		if key := strings.SplitN(e, "=", nparts)[0]; s.Has(key) {
 			continue
 		}
 
-		rv.Env = append(rv.Env, env)
+		rv = append(rv, e)
 	}
 
 	return rv
@@ -387,7 +758,196 @@ func extractEndpoints(
 			continue
 		}
 
-		rv[n.IPAddress] = name
+		for _, ip := range []string{n.IPAddress, n.GlobalIPv6Address} {
+			if ip == "" {
+				continue
+			}
+
+			rv[graph.NormalizeAddr(ip)] = name
+		}
+	}
+
+	return rv
+}
+
+// inspectNetworks gathers inventory for networks of running containers, networks
+// that cannot be inspected are known only by names.
+func (d *Docker) inspectNetworks(
+	ctx context.Context,
+	containers []container.Summary,
+) (rv map[string]*graph.Network) {
+	rv = make(map[string]*graph.Network)
+
+	for i := range containers {
+		c := &containers[i]
+
+		if c.State != stateRunning || c.NetworkSettings == nil {
+			continue
+		}
+
+		for name, n := range c.NetworkSettings.Networks {
+			if n.NetworkID == "" || n.EndpointID == "" {
+				continue
+			}
+
+			if _, ok := rv[n.NetworkID]; ok {
+				continue
+			}
+
+			nw := &graph.Network{ID: n.NetworkID, Name: name}
+			rv[n.NetworkID] = nw
+
+			info, err := d.cli.NetworkInspect(ctx, n.NetworkID, network.InspectOptions{})
+			if err != nil {
+				log.Printf("network: %s inspect error: %v", name, err)
+
+				continue
+			}
+
+			nw.Driver = info.Driver
+			nw.Internal = info.Internal
+
+			for _, cfg := range info.IPAM.Config {
+				if cfg.Subnet != "" {
+					nw.Subnets = append(nw.Subnets, cfg.Subnet)
+				}
+
+				if cfg.Gateway != "" {
+					nw.Gateways = append(nw.Gateways, cfg.Gateway)
+				}
+			}
+		}
+	}
+
+	return rv
+}
+
+// serviceVIPs maps swarm service ids to their virtual ips, services are listed only
+// if there are swarm tasks among containers.
+func (d *Docker) serviceVIPs(
+	ctx context.Context,
+	containers []container.Summary,
+) (rv map[string][]string) {
+	if !slices.ContainsFunc(containers, func(c container.Summary) bool {
+		return c.Labels[graph.SwarmServiceIDLabel] != ""
+	}) {
+		return nil
+	}
+
+	services, err := d.cli.ServiceList(ctx, swarm.ServiceListOptions{})
+	if err != nil {
+		log.Printf("swarm: services list error: %v", err)
+
+		return nil
+	}
+
+	rv = make(map[string][]string, len(services))
+
+	for _, svc := range services {
+		for _, vip := range svc.Endpoint.VirtualIPs {
+			addr := vip.Addr
+
+			if ip, _, err := net.ParseCIDR(addr); err == nil {
+				addr = ip.String()
+			}
+
+			if addr != "" {
+				rv[svc.ID] = append(rv[svc.ID], graph.NormalizeAddr(addr))
+			}
+		}
+	}
+
+	return rv
+}
+
+func extractNetworkEndpoints(
+	eps map[string]*network.EndpointSettings,
+	nets map[string]*graph.Network,
+) (rv []*graph.NetworkEndpoint) {
+	for name, ep := range eps {
+		if ep.EndpointID == "" {
+			continue
+		}
+
+		nw, ok := nets[ep.NetworkID]
+		if !ok {
+			nw = &graph.Network{ID: ep.NetworkID, Name: name}
+		}
+
+		rv = append(rv, &graph.NetworkEndpoint{
+			Network: nw,
+			IP:      graph.NormalizeAddr(cmp.Or(ep.IPAddress, ep.GlobalIPv6Address)),
+			Aliases: slices.Compact(slices.Sorted(slices.Values(ep.Aliases))),
+		})
+	}
+
+	slices.SortFunc(rv, func(a, b *graph.NetworkEndpoint) int {
+		return cmp.Compare(a.Network.Name, b.Network.Name)
+	})
+
+	return rv
+}
+
+// extractGateways returns bridge gateways, as published ports are reachable from
+// containers through them.
+func extractGateways(
+	containers []container.Summary,
+) (rv []string) {
+	seen := make(set.Unordered[string])
+
+	for i := range containers {
+		c := &containers[i]
+
+		if c.NetworkSettings == nil {
+			continue
+		}
+
+		for _, n := range c.NetworkSettings.Networks {
+			if n.Gateway == "" || !seen.Add(n.Gateway) {
+				continue
+			}
+
+			rv = append(rv, n.Gateway)
+		}
+	}
+
+	slices.Sort(rv)
+
+	return rv
+}
+
+// extractPublished maps port bindings to host addresses, wildcard bindings are
+// expanded to every known host address and bridge gateway, gateways are reachable
+// from same host only.
+func extractPublished(
+	ports []container.Port,
+	hostIPs, gateways []string,
+) (rv []*graph.PublishedPort) {
+	for _, p := range ports {
+		if p.PublicPort == 0 {
+			continue
+		}
+
+		proto, ok := graph.ParseNetProto(p.Type)
+		if !ok {
+			continue
+		}
+
+		ips := []string{p.IP}
+
+		if ip := net.ParseIP(p.IP); ip == nil || ip.IsUnspecified() {
+			ips = slices.Concat(hostIPs, gateways)
+		}
+
+		for _, ip := range ips {
+			rv = append(rv, &graph.PublishedPort{
+				IP:     ip,
+				Port:   int(p.PublicPort),
+				Target: int(p.PrivatePort),
+				Proto:  proto,
+				Local:  slices.Contains(gateways, ip),
+			})
+		}
 	}
 
 	return rv
@@ -6,11 +6,14 @@ import (
 	"context"
 	"errors"
 	"net"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
 
 	"github.com/s0rg/decompose/internal/client"
 	"github.com/s0rg/decompose/internal/graph"
@@ -1068,6 +1071,101 @@ func TestDockerClientNsEnterOk(t *testing.T) {
 	}
 }
 
+func TestDockerClientNsEnterStates(t *testing.T) {
+	t.Parallel()
+
+	cm := &clientMock{}
+
+	cm.OnList = func() (rv []container.Summary) {
+		return []container.Summary{
+			{
+				ID:    "1",
+				Names: []string{"test"},
+				Image: "test-image",
+				State: "running",
+				NetworkSettings: &container.NetworkSettingsSummary{
+					Networks: map[string]*network.EndpointSettings{
+						"test-net": {
+							EndpointID: "1",
+							IPAddress:  "1.1.1.1",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	cm.OnInspect = func() (rv container.InspectResponse) {
+		rv.ContainerJSONBase = &container.ContainerJSONBase{}
+		rv.State = &container.State{Pid: 1}
+		rv.Config = &container.Config{}
+
+		return rv
+	}
+
+	cm.OnContainerTop = func() (rv container.TopResponse) {
+		rv.Titles = []string{"PID,CMD"}
+		rv.Processes = [][]string{
+			{"1", "test"},
+		}
+
+		return rv
+	}
+
+	testEnter := func(_ int, _ graph.NetProto, fn func(
+		_ int, _ *graph.Connection,
+	)) error {
+		for port, state := range []string{"", "TIME_WAIT", "SYN_SENT"} {
+			fn(1, &graph.Connection{
+				Process: "test",
+				SrcIP:   net.ParseIP("1.1.1.1"),
+				DstIP:   net.ParseIP("2.2.2.2"),
+				SrcPort: 40000 + port,
+				DstPort: 80 + port,
+				State:   state,
+				Proto:   graph.TCP,
+			})
+		}
+
+		return nil
+	}
+
+	states, _ := graph.ParseTCPStates("time_wait")
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.LinuxNsenter),
+		client.WithNsenterFn(testEnter),
+		client.WithStates(states),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 || rv[0].ConnectionsCount() != 2 {
+		t.Fatal("unexpected connections")
+	}
+
+	rv[0].IterOutbounds(func(c *graph.Connection) {
+		if c.State == "SYN_SENT" {
+			t.Fatal("state:", c.State)
+		}
+	})
+}
+
 func TestDockerClientNsEnterLocal(t *testing.T) {
 	t.Parallel()
 
@@ -1283,3 +1381,1366 @@ func TestDockerClientUnixSockets(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDockerClientContainersWorkers(t *testing.T) {
+	t.Parallel()
+
+	const total = 20
+
+	cm := &clientMock{}
+
+	cm.OnList = func() (rv []container.Summary) {
+		rv = make([]container.Summary, total)
+
+		for i := range rv {
+			id := strconv.Itoa(i)
+
+			rv[i] = container.Summary{
+				ID:    id,
+				Names: []string{"test-" + id},
+				Image: "test-image",
+				State: "running",
+				NetworkSettings: &container.NetworkSettingsSummary{
+					Networks: map[string]*network.EndpointSettings{
+						"test-net": {
+							EndpointID: id,
+							IPAddress:  "1.1.1." + id,
+						},
+					},
+				},
+			}
+		}
+
+		rv[total/2].State = "exited"
+
+		return rv
+	}
+
+	cm.OnInspect = func() (rv container.InspectResponse) {
+		rv.ContainerJSONBase = &container.ContainerJSONBase{}
+		rv.State = &container.State{Pid: 1}
+		rv.Config = &container.Config{}
+
+		return rv
+	}
+
+	cm.OnContainerTop = func() (rv container.TopResponse) {
+		rv.Titles = []string{"PID,CMD"}
+		rv.Processes = [][]string{
+			{"1", "test"},
+		}
+
+		return rv
+	}
+
+	testEnter := func(_ int, _ graph.NetProto, fn func(int, *graph.Connection)) error {
+		fn(1, &graph.Connection{
+			Process: "test",
+			SrcPort: 1,
+			Proto:   graph.TCP,
+			Listen:  true,
+		})
+
+		return nil
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.LinuxNsenter),
+		client.WithNsenterFn(testEnter),
+		client.WithWorkers(4),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	var last, calls int
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		func(cur, all int) {
+			if cur < last || all != total {
+				t.Fail()
+			}
+
+			last = cur
+			calls++
+		},
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != total-1 {
+		t.Fatal("len:", len(rv))
+	}
+
+	if last != total || calls != total {
+		t.Log("progress:", last, calls)
+		t.Fail()
+	}
+
+	for i := 1; i < len(rv); i++ {
+		a, _ := strconv.Atoi(rv[i-1].ID)
+		b, _ := strconv.Atoi(rv[i].ID)
+
+		if a >= b {
+			t.Fatal("order:", a, b)
+		}
+
+		if rv[i].ConnectionsCount() != 1 {
+			t.Fail()
+		}
+	}
+}
+
+func TestDockerClientContainersTimeout(t *testing.T) {
+	t.Parallel()
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"test"},
+					Image: "test-image",
+					State: "running",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: map[string]*network.EndpointSettings{
+							"test-net": {
+								EndpointID: "1",
+								IPAddress:  "1.1.1.1",
+							},
+						},
+					},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			conn, _ := net.Pipe() // nobody writes here - exec hangs
+
+			rv.Conn = conn
+			rv.Reader = bufio.NewReader(conn)
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 || !rv[0].TimedOut {
+		t.Fail()
+	}
+}
+
+func TestDockerClientContainersCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var execs int
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{ID: "1", Names: []string{"a"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+				{ID: "2", Names: []string{"b"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+				{ID: "3", Names: []string{"c"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			if execs++; execs == 1 {
+				rv.Conn = &connMock{}
+				rv.Reader = bufio.NewReader(bytes.NewBufferString(""))
+
+				return
+			}
+
+			conn, _ := net.Pipe() // nobody writes here - exec hangs, until cancelled
+
+			rv.Conn = conn
+			rv.Reader = bufio.NewReader(conn)
+
+			cancel()
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(ctx, graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("err:", err)
+	}
+
+	// first container is scanned, second is interrupted, third is skipped
+	if len(rv) != 1 || rv[0].ID != "1" || rv[0].TimedOut || execs != 2 {
+		t.Fatal("result:", len(rv), execs)
+	}
+}
+
+func TestDockerClientNsEnterNetns(t *testing.T) {
+	t.Parallel()
+
+	cm := &clientMock{}
+
+	cm.OnList = func() (rv []container.Summary) {
+		return []container.Summary{
+			{
+				ID:    "1",
+				Names: []string{"test"},
+				Image: "test-image",
+				State: "running",
+				NetworkSettings: &container.NetworkSettingsSummary{
+					Networks: map[string]*network.EndpointSettings{
+						"test-net": {
+							EndpointID: "1",
+							IPAddress:  "1.1.1.1",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	cm.OnInspect = func() (rv container.InspectResponse) {
+		rv.ContainerJSONBase = &container.ContainerJSONBase{}
+		rv.Config = &container.Config{}
+
+		return rv
+	}
+
+	cm.OnContainerTop = func() (rv container.TopResponse) {
+		rv.Titles = []string{"PID,CMD"}
+		rv.Processes = [][]string{
+			{"1", "/bin/init"},
+			{"2", "/usr/bin/worker -n 1"},
+			{"3", "/usr/bin/worker -n 2"},
+			{"4", "/usr/bin/sidecar"},
+		}
+
+		return rv
+	}
+
+	testNetns := func(pid int) (uint64, error) {
+		if pid == 4 {
+			return 200, nil
+		}
+
+		return 100, nil
+	}
+
+	var walked int
+
+	testInodes := func(pid int, cb func(uint64)) error {
+		walked++
+
+		cb(uint64(pid) * 10)
+
+		return nil
+	}
+
+	var entered []int
+
+	testEnter := func(pid int, _ graph.NetProto, fn func(int, *graph.Connection)) error {
+		entered = append(entered, pid)
+
+		if pid != 1 {
+			return nil
+		}
+
+		// procfs names differ from container top ones
+		fn(pid, &graph.Connection{Process: "systemd", Inode: 10, SrcPort: 1, Proto: graph.TCP, Listen: true})
+		fn(pid, &graph.Connection{Process: "systemd", Inode: 30, SrcPort: 3, Proto: graph.TCP, Listen: true})
+		fn(pid, &graph.Connection{Process: "systemd", Inode: 99, SrcPort: 9, Proto: graph.TCP, Listen: true})
+
+		return nil
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.LinuxNsenter),
+		client.WithNsenterFn(testEnter),
+		client.WithInodesFn(testInodes),
+		client.WithNetnsFn(testNetns),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(entered) != 2 || entered[0] != 1 || entered[1] != 4 {
+		t.Fatal("entered:", entered)
+	}
+
+	// descriptors are walked once, while collecting inodes
+	if walked != 4 {
+		t.Fatal("walked:", walked)
+	}
+
+	owners := make(map[int]string)
+
+	rv[0].IterListeners(func(c *graph.Connection) {
+		owners[c.SrcPort] = c.Process
+	})
+
+	if owners[1] != "init" || owners[3] != "worker" || owners[9] != "init" {
+		t.Log(owners)
+		t.Fail()
+	}
+}
+
+func TestDockerClientNsEnterResample(t *testing.T) {
+	t.Parallel()
+
+	cm := &clientMock{}
+
+	cm.OnList = func() (rv []container.Summary) {
+		return []container.Summary{
+			{
+				ID:              "1",
+				Names:           []string{"test"},
+				State:           "running",
+				NetworkSettings: &container.NetworkSettingsSummary{},
+			},
+		}
+	}
+
+	cm.OnInspect = func() (rv container.InspectResponse) {
+		rv.ContainerJSONBase = &container.ContainerJSONBase{}
+		rv.Config = &container.Config{}
+		rv.NetworkSettings = &container.NetworkSettings{}
+
+		return rv
+	}
+
+	cm.OnContainerTop = func() (rv container.TopResponse) {
+		rv.Titles = []string{"PID,CMD"}
+		rv.Processes = [][]string{
+			{"1", "/bin/init"},
+			{"2", "/usr/bin/worker"},
+		}
+
+		return rv
+	}
+
+	var walked int
+
+	testInodes := func(pid int, cb func(uint64)) error {
+		walked++
+
+		// worker opens its socket after discovery
+		if pid == 2 && walked > 2 {
+			cb(20)
+		}
+
+		cb(uint64(pid))
+
+		return nil
+	}
+
+	testEnter := func(pid int, _ graph.NetProto, fn func(int, *graph.Connection)) error {
+		fn(pid, &graph.Connection{Process: "init", Inode: 20, SrcPort: 2, Proto: graph.TCP, Listen: true})
+
+		return nil
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.LinuxNsenter),
+		client.WithNsenterFn(testEnter),
+		client.WithInodesFn(testInodes),
+		client.WithNetnsFn(func(int) (uint64, error) { return 1, nil }),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	known, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	rv, err := cli.Resample(context.Background(), graph.TCP, false, known, voidProgress)
+	if err != nil {
+		t.Fatal("resample:", err)
+	}
+
+	// descriptors are walked again for every sample
+	if walked != 4 {
+		t.Fatal("walked:", walked)
+	}
+
+	owners := make(map[int]string)
+
+	rv[0].IterListeners(func(c *graph.Connection) {
+		owners[c.SrcPort] = c.Process
+	})
+
+	if owners[2] != "worker" {
+		t.Fatal("owners:", owners)
+	}
+}
+
+func TestDockerClientContainersSsFallback(t *testing.T) {
+	t.Parallel()
+
+	var execs int
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"test"},
+					Image: "test-image",
+					State: "running",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: map[string]*network.EndpointSettings{
+							"test-net": {
+								EndpointID: "1",
+								IPAddress:  "1.1.1.1",
+							},
+						},
+					},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			execs++
+
+			rv.ID = strconv.Itoa(execs)
+
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			out := `OCI runtime exec failed: exec: "netstat": executable file not found in $PATH`
+
+			if execs > 1 {
+				out = `Netid State  Recv-Q Send-Q Local Address:Port Peer Address:Port Process
+tcp   LISTEN 0      4096         0.0.0.0:80        0.0.0.0:*     users:(("nginx",pid=1,fd=6))
+tcp   ESTAB  0      0          1.1.1.1:34567      1.1.1.2:5432  users:(("nginx",pid=7,fd=9))
+udp   UNCONN 0      0          1.1.1.1:123        0.0.0.0:*     users:(("ntpd",pid=8,fd=3))
+`
+			}
+
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(out))
+
+			return
+		},
+		OnExecInspect: func(id string) (rv container.ExecInspect) {
+			if id == "1" {
+				rv.ExitCode = 127
+			}
+
+			return rv
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if execs != 2 {
+		t.Fatal("execs:", execs)
+	}
+
+	if len(rv) != 1 || rv[0].ConnectionsCount() != 2 || rv[0].Strategy != client.StrategySS {
+		t.Fail()
+	}
+}
+
+func TestDockerClientExecChainUnknown(t *testing.T) {
+	t.Parallel()
+
+	_, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return &clientMock{}, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithExecChain("netstat", "unknown"),
+	)
+	if !errors.Is(err, client.ErrUnknownStrategy) {
+		t.Fail()
+	}
+}
+
+func TestDockerClientExecChain(t *testing.T) {
+	t.Parallel()
+
+	var execs int
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"test"},
+					Image: "test-image",
+					State: "running",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: map[string]*network.EndpointSettings{},
+					},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			execs++
+
+			rv.ID = strconv.Itoa(execs)
+
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			var out string
+
+			if execs == 2 {
+				out = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+`
+			}
+
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(out))
+
+			return
+		},
+		OnExecInspect: func(id string) (rv container.ExecInspect) {
+			if id == "1" {
+				rv.ExitCode = 126
+			}
+
+			return rv
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithExecChain(client.StrategyLsof, client.StrategyProcNet, client.StrategyNetstat),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if execs != 2 {
+		t.Fatal("execs:", execs)
+	}
+
+	if len(rv) != 1 || rv[0].ConnectionsCount() != 1 || rv[0].Strategy != client.StrategyProcNet {
+		t.Fail()
+	}
+
+	if n := rv[0].ToNode(); n.Container.Strategy != client.StrategyProcNet {
+		t.Fail()
+	}
+}
+
+func TestDockerClientSidecar(t *testing.T) {
+	t.Parallel()
+
+	var (
+		created *container.HostConfig
+		image   string
+		removed string
+	)
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"scratch-app"},
+					Image: "test-image",
+					State: "running",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: map[string]*network.EndpointSettings{},
+					},
+				},
+				{
+					ID:     "2",
+					Names:  []string{"stale-helper"},
+					Image:  "helper",
+					State:  "running",
+					Labels: map[string]string{client.SidecarLabel: "1"},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnCreate: func(cfg *container.Config, hcfg *container.HostConfig) (rv container.CreateResponse) {
+			created, image = hcfg, cfg.Image
+			rv.ID = "helper-1"
+
+			return rv
+		},
+		OnRemove: func(id string) {
+			removed = id
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(`Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp        0      0 0.0.0.0:80              0.0.0.0:*               LISTEN  1/app
+`))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.Sidecar),
+		client.WithSidecarImage("my/netshoot"),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	if cli.Mode() != "sidecar" {
+		t.Fail()
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 || rv[0].ConnectionsCount() != 1 || rv[0].Strategy != client.StrategyNetstat {
+		t.Fatal("result:", rv)
+	}
+
+	if image != "my/netshoot" || removed != "helper-1" {
+		t.Fail()
+	}
+
+	if created.NetworkMode != "container:1" || created.PidMode != "container:1" {
+		t.Fail()
+	}
+}
+
+func TestDockerClientSidecarPull(t *testing.T) {
+	t.Parallel()
+
+	var pulled []string
+
+	newMock := func(pullErr error) *clientMock {
+		return &clientMock{
+			NoImage: true,
+			OnList: func() (rv []container.Summary) {
+				return []container.Summary{
+					{
+						ID:    "1",
+						Names: []string{"scratch-app"},
+						Image: "test-image",
+						State: "running",
+						NetworkSettings: &container.NetworkSettingsSummary{
+							Networks: map[string]*network.EndpointSettings{},
+						},
+					},
+				}
+			},
+			OnInspect: func() (rv container.InspectResponse) {
+				rv.ContainerJSONBase = &container.ContainerJSONBase{}
+				rv.Config = &container.Config{}
+
+				return rv
+			},
+			OnPull: func(ref string) error {
+				pulled = append(pulled, ref)
+
+				// pull outlasts per-container timeout
+				time.Sleep(50 * time.Millisecond)
+
+				return pullErr
+			},
+			OnCreate: func(_ *container.Config, _ *container.HostConfig) (rv container.CreateResponse) {
+				rv.ID = "helper-1"
+
+				return rv
+			},
+			OnRemove:     func(string) {},
+			OnExecCreate: func() (rv container.ExecCreateResponse) { return rv },
+			OnExecAttach: func() (rv types.HijackedResponse) {
+				rv.Conn = &connMock{}
+				rv.Reader = bufio.NewReader(bytes.NewBufferString(`Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp        0      0 0.0.0.0:80              0.0.0.0:*               LISTEN  1/app
+`))
+
+				return
+			},
+		}
+	}
+
+	scan := func(cm *clientMock) ([]*graph.Container, error) {
+		cli, err := client.NewDocker(
+			client.WithClientCreator(func() (client.DockerClient, error) {
+				return cm, nil
+			}),
+			client.WithMode(client.Sidecar),
+			client.WithSidecarImage("my/netshoot"),
+			client.WithTimeout(20*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatal("client:", err)
+		}
+
+		return cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	}
+
+	rv, err := scan(newMock(nil))
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 || rv[0].ConnectionsCount() != 1 {
+		t.Fatal("result:", rv)
+	}
+
+	if len(pulled) != 1 || pulled[0] != "my/netshoot" {
+		t.Fatal("pulled:", pulled)
+	}
+
+	// present image is not pulled
+	cm := newMock(nil)
+	cm.NoImage = false
+
+	if _, err = scan(cm); err != nil || len(pulled) != 1 {
+		t.Fatal("pulled:", pulled, err)
+	}
+
+	if _, err = scan(newMock(errors.New("denied"))); err == nil {
+		t.Fatal("no error")
+	}
+}
+
+func TestDockerClientPublished(t *testing.T) {
+	t.Parallel()
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"web"},
+					Image: "test-image",
+					State: "running",
+					Ports: []container.Port{
+						{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+						{IP: "10.1.1.1", PrivatePort: 53, PublicPort: 5353, Type: "udp"},
+						{PrivatePort: 9000, Type: "tcp"},
+						{IP: "0.0.0.0", PrivatePort: 7000, PublicPort: 7000, Type: "sctp"},
+					},
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: map[string]*network.EndpointSettings{
+							"bridge": {
+								EndpointID: "1",
+								IPAddress:  "172.17.0.2",
+								Gateway:    "172.17.0.1",
+							},
+						},
+					},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(""))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithHost("host-a", "192.168.1.20", "10.1.1.1"),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP|graph.UDP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 || rv[0].Host != "host-a" {
+		t.Fatal("result:", rv)
+	}
+
+	pub := rv[0].Published
+
+	// wildcard binding expands to host addresses and bridge gateway
+	if len(pub) != 4 {
+		t.Fatal("published:", len(pub))
+	}
+
+	if pub[0].IP != "192.168.1.20" || pub[0].Port != 8080 || pub[0].Target != 80 || pub[0].Proto != graph.TCP {
+		t.Fatal("published[0]:", pub[0])
+	}
+
+	if pub[2].IP != "172.17.0.1" || pub[2].Port != 8080 || !pub[2].Local || pub[0].Local {
+		t.Fatal("published[2]:", pub[2])
+	}
+
+	if pub[3].IP != "10.1.1.1" || pub[3].Port != 5353 || pub[3].Proto != graph.UDP {
+		t.Fatal("published[3]:", pub[3])
+	}
+
+	exp := rv[0].ToNode().Container.Exposed
+
+	if len(exp) != 2 || exp[0].Label() != "tcp:8080->80" || exp[1].Label() != "udp:5353->53" {
+		t.Fatal("exposed:", exp)
+	}
+}
+
+func TestDockerClientNetworks(t *testing.T) {
+	t.Parallel()
+
+	endpoints := map[string]*network.EndpointSettings{
+		"front": {NetworkID: "n1", EndpointID: "e1", IPAddress: "172.20.0.2", Aliases: []string{"www", "web", "www"}},
+		"back":  {NetworkID: "n2", EndpointID: "e2", IPAddress: "172.30.0.2"},
+		"none":  {NetworkID: "n3"},
+	}
+
+	var inspected []string
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"proxy"},
+					State: "running",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: endpoints,
+					},
+				},
+				{
+					ID:    "2",
+					Names: []string{"stopped"},
+					State: "exited",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: map[string]*network.EndpointSettings{
+							"other": {NetworkID: "n4", EndpointID: "e4"},
+						},
+					},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+			rv.NetworkSettings = &container.NetworkSettings{
+				Networks: endpoints,
+			}
+
+			return rv
+		},
+		OnNetwork: func(id string) (rv network.Inspect) {
+			inspected = append(inspected, id)
+
+			if id == "n1" {
+				rv.Driver = "bridge"
+				rv.Internal = true
+				rv.IPAM.Config = []network.IPAMConfig{
+					{Subnet: "172.20.0.0/16", Gateway: "172.20.0.1"},
+				}
+			}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(""))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 || len(inspected) != 2 {
+		t.Fatal("result:", len(rv), inspected)
+	}
+
+	nets := rv[0].Networks
+
+	if len(nets) != 2 || nets[0].Network.Name != "back" || nets[1].Network.Name != "front" {
+		t.Fatal("networks:", nets)
+	}
+
+	front := nets[1]
+
+	if front.IP != "172.20.0.2" || len(front.Aliases) != 2 || front.Aliases[0] != "web" {
+		t.Fatal("front:", front)
+	}
+
+	if n := front.Network; n.Driver != "bridge" || !n.Internal ||
+		n.Subnets[0] != "172.20.0.0/16" || n.Gateways[0] != "172.20.0.1" {
+		t.Fatal("front network:", n)
+	}
+}
+
+func TestDockerClientIPv6(t *testing.T) {
+	t.Parallel()
+
+	endpoints := map[string]*network.EndpointSettings{
+		"v6only": {NetworkID: "n1", EndpointID: "e1", GlobalIPv6Address: "fd00:0:0::2"},
+		"dual":   {NetworkID: "n2", EndpointID: "e2", IPAddress: "172.20.0.2", GlobalIPv6Address: "fd01::2"},
+	}
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{
+					ID:    "1",
+					Names: []string{"app"},
+					State: "running",
+					NetworkSettings: &container.NetworkSettingsSummary{
+						Networks: endpoints,
+					},
+				},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+			rv.NetworkSettings = &container.NetworkSettings{
+				Networks: endpoints,
+			}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(""))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 1 {
+		t.Fatal("result:", len(rv))
+	}
+
+	eps := rv[0].Endpoints
+
+	if len(eps) != 3 || eps["fd00::2"] != "v6only" || eps["fd01::2"] != "dual" || eps["172.20.0.2"] != "dual" {
+		t.Fatal("endpoints:", eps)
+	}
+
+	if nets := rv[0].Networks; len(nets) != 2 || nets[1].IP != "fd00::2" {
+		t.Fatal("networks:", nets)
+	}
+}
+
+func TestDockerClientSwarm(t *testing.T) {
+	t.Parallel()
+
+	task := func(id, name string) container.Summary {
+		return container.Summary{
+			ID:              id,
+			Names:           []string{name},
+			State:           "running",
+			Labels:          map[string]string{graph.SwarmServiceIDLabel: "s1"},
+			NetworkSettings: &container.NetworkSettingsSummary{},
+		}
+	}
+
+	var listed int
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				task("1", "shop_web.1.aaa"),
+				task("2", "shop_web.2.bbb"),
+				{ID: "3", Names: []string{"plain"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+			rv.NetworkSettings = &container.NetworkSettings{}
+
+			return rv
+		},
+		OnServices: func() ([]swarm.Service, error) {
+			listed++
+
+			return []swarm.Service{
+				{ID: "s1", Endpoint: swarm.Endpoint{VirtualIPs: []swarm.EndpointVirtualIP{
+					{NetworkID: "ingress", Addr: "10.0.0.5/24"},
+					{NetworkID: "overlay", Addr: "10.0.1.5/24"},
+				}}},
+				{ID: "s2", Endpoint: swarm.Endpoint{VirtualIPs: []swarm.EndpointVirtualIP{
+					{NetworkID: "overlay", Addr: "10.0.1.9/24"},
+				}}},
+			}, nil
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(""))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 3 || listed != 1 {
+		t.Fatal("result:", len(rv), listed)
+	}
+
+	for _, c := range rv {
+		want := 2
+		if c.Name == "plain" {
+			want = 0
+		}
+
+		if len(c.VIPs) != want {
+			t.Fatal("vips:", c.Name, c.VIPs)
+		}
+	}
+
+	if vips := rv[0].VIPs; vips[0] != "10.0.0.5" || vips[1] != "10.0.1.5" {
+		t.Fatal("vips:", vips)
+	}
+}
+
+func TestDockerClientResample(t *testing.T) {
+	t.Parallel()
+
+	var listed, inspected, execs int
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			listed++
+
+			return []container.Summary{
+				{ID: "1", Names: []string{"a"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+				{ID: "2", Names: []string{"b"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			inspected++
+
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+			rv.NetworkSettings = &container.NetworkSettings{}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			execs++
+
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(`Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp        0      0 1.1.1.1:40000           2.2.2.2:80              ESTABLISHED 1/foo
+`))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	known, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	// containers from other hosts are skipped
+	known = append(known, &graph.Container{ID: "1", Host: "other"})
+
+	for range 2 {
+		rv, err := cli.Resample(context.Background(), graph.TCP, false, known, voidProgress)
+		if err != nil {
+			t.Fatal("resample:", err)
+		}
+
+		if len(rv) != 2 || rv[0].ID != "1" || rv[1].ConnectionsCount() != 1 {
+			t.Fatal("result:", rv)
+		}
+	}
+
+	if listed != 1 || inspected != 2 || execs != 6 {
+		t.Fatal("listed/inspected/execs:", listed, inspected, execs)
+	}
+}
+
+func TestDockerClientSwarmError(t *testing.T) {
+	t.Parallel()
+
+	var listed int
+
+	cm := &clientMock{
+		OnList: func() (rv []container.Summary) {
+			return []container.Summary{
+				{ID: "1", Names: []string{"plain"}, State: "running", NetworkSettings: &container.NetworkSettingsSummary{}},
+			}
+		},
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+			rv.NetworkSettings = &container.NetworkSettings{}
+
+			return rv
+		},
+		OnServices: func() ([]swarm.Service, error) {
+			listed++
+
+			return nil, errors.New("not a swarm manager")
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(""))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	// no swarm tasks - no services listing
+	if _, err = cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress); err != nil || listed != 0 {
+		t.Fatal("containers:", err, listed)
+	}
+
+	cm.OnList = func() (rv []container.Summary) {
+		return []container.Summary{
+			{
+				ID:              "1",
+				Names:           []string{"web.1.a"},
+				State:           "running",
+				Labels:          map[string]string{graph.SwarmServiceIDLabel: "s1"},
+				NetworkSettings: &container.NetworkSettingsSummary{},
+			},
+		}
+	}
+
+	rv, err := cli.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil || listed != 1 {
+		t.Fatal("containers:", err, listed)
+	}
+
+	if len(rv) != 1 || len(rv[0].VIPs) != 0 {
+		t.Fatal("result:", rv)
+	}
+}
@@ -1,13 +1,20 @@
 package client_test
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	dockerclient "github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 func voidProgress(_, _ int) {}
@@ -18,12 +25,43 @@ type clientMock struct {
 	OnInspect      func() container.InspectResponse
 	OnExecCreate   func() container.ExecCreateResponse
 	OnExecAttach   func() types.HijackedResponse
+	OnExecInspect  func(string) container.ExecInspect
+	OnCreate       func(*container.Config, *container.HostConfig) container.CreateResponse
+	OnRemove       func(string)
 	OnContainerTop func() container.TopResponse
+	OnTop          func(string) container.TopResponse
+	OnNetwork      func(string) network.Inspect
+	OnServices     func() ([]swarm.Service, error)
+	OnPull         func(string) error
+	NoImage        bool
+}
+
+func (cm *clientMock) ServiceList(
+	_ context.Context,
+	_ swarm.ServiceListOptions,
+) (rv []swarm.Service, err error) {
+	if cm.OnServices == nil {
+		return rv, nil
+	}
+
+	return cm.OnServices()
+}
+
+func (cm *clientMock) NetworkInspect(
+	_ context.Context,
+	id string,
+	_ network.InspectOptions,
+) (rv network.Inspect, err error) {
+	if cm.OnNetwork == nil {
+		return rv, nil
+	}
+
+	return cm.OnNetwork(id), nil
 }
 
 func (cm *clientMock) ContainerTop(
 	_ context.Context,
-	_ string,
+	id string,
 	_ []string,
 ) (rv container.TopResponse, err error) {
 	if cm.Err != nil {
@@ -32,6 +70,10 @@ func (cm *clientMock) ContainerTop(
 		return
 	}
 
+	if cm.OnTop != nil {
+		return cm.OnTop(id), nil
+	}
+
 	return cm.OnContainerTop(), nil
 }
 
@@ -89,6 +131,96 @@ func (cm *clientMock) ContainerExecAttach(
 	return cm.OnExecAttach(), nil
 }
 
+func (cm *clientMock) ContainerExecInspect(
+	_ context.Context,
+	id string,
+) (rv container.ExecInspect, err error) {
+	if cm.Err != nil {
+		err = cm.Err
+
+		return
+	}
+
+	if cm.OnExecInspect == nil {
+		return rv, nil
+	}
+
+	return cm.OnExecInspect(id), nil
+}
+
+func (cm *clientMock) ContainerCreate(
+	_ context.Context,
+	cfg *container.Config,
+	hcfg *container.HostConfig,
+	_ *network.NetworkingConfig,
+	_ *ocispec.Platform,
+	_ string,
+) (rv container.CreateResponse, err error) {
+	if cm.Err != nil {
+		err = cm.Err
+
+		return
+	}
+
+	if cm.NoImage {
+		return rv, cerrdefs.ErrNotFound
+	}
+
+	return cm.OnCreate(cfg, hcfg), nil
+}
+
+func (cm *clientMock) ImageInspect(
+	_ context.Context,
+	_ string,
+	_ ...dockerclient.ImageInspectOption,
+) (rv image.InspectResponse, err error) {
+	if cm.NoImage {
+		return rv, cerrdefs.ErrNotFound
+	}
+
+	return rv, nil
+}
+
+func (cm *clientMock) ImagePull(
+	ctx context.Context,
+	ref string,
+	_ image.PullOptions,
+) (rv io.ReadCloser, err error) {
+	if cm.OnPull != nil {
+		if err = cm.OnPull(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cm.NoImage = false
+
+	return io.NopCloser(bytes.NewBufferString(`{"status":"Downloaded"}`)), nil
+}
+
+func (cm *clientMock) ContainerStart(
+	_ context.Context,
+	_ string,
+	_ container.StartOptions,
+) (err error) {
+	return cm.Err
+}
+
+func (cm *clientMock) ContainerRemove(
+	_ context.Context,
+	id string,
+	_ container.RemoveOptions,
+) (err error) {
+	if cm.OnRemove != nil {
+		cm.OnRemove(id)
+	}
+
+	return cm.Err
+}
+
 func (cm *clientMock) Ping(_ context.Context) (rv types.Ping, err error) {
 	if cm.Err != nil {
 		err = cm.Err
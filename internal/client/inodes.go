@@ -1,14 +1,17 @@
 package client
 
 import (
+	"sync"
+
 	"github.com/s0rg/set"
 )
 
 type InodesMap struct {
-	m map[string]map[int]set.Unordered[uint64]
-	u map[string]map[int]set.Unordered[uint64]
-	n map[string]map[int]string
-	l map[string]map[int]string
+	m  map[string]map[int]set.Unordered[uint64]
+	u  map[string]map[int]set.Unordered[uint64]
+	n  map[string]map[int]string
+	l  map[string]map[int]string
+	mu sync.Mutex
 }
 
 type item struct {
@@ -17,6 +20,9 @@ type item struct {
 }
 
 func (m *InodesMap) AddProcess(containerID string, pid int, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.n == nil {
 		m.n = make(map[string]map[int]string)
 	}
@@ -31,6 +37,9 @@ func (m *InodesMap) AddProcess(containerID string, pid int, name string) {
 }
 
 func (m *InodesMap) AddInode(containerID string, pid int, inode uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.m == nil {
 		m.m = make(map[string]map[int]set.Unordered[uint64])
 	}
@@ -51,6 +60,9 @@ func (m *InodesMap) AddInode(containerID string, pid int, inode uint64) {
 }
 
 func (m *InodesMap) MarkListener(containerID string, pid int, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.l == nil {
 		m.l = make(map[string]map[int]string)
 	}
@@ -65,6 +77,9 @@ func (m *InodesMap) MarkListener(containerID string, pid int, path string) {
 }
 
 func (m *InodesMap) MarkUnknown(containerID string, pid int, inode uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.u == nil {
 		m.u = make(map[string]map[int]set.Unordered[uint64])
 	}
@@ -87,6 +102,9 @@ func (m *InodesMap) MarkUnknown(containerID string, pid int, inode uint64) {
 func (m *InodesMap) ResolveUnknown(
 	cb func(srcCID, dstCID, srcName, dstName, path string),
 ) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	index := make(map[uint64]*item)
 
 	for c, pids := range m.m {
@@ -134,6 +152,9 @@ func (m *InodesMap) ResolveUnknown(
 }
 
 func (m *InodesMap) Has(containerID string, pid int, inode uint64) (yes bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.m == nil {
 		return false
 	}
@@ -151,6 +172,27 @@ func (m *InodesMap) Has(containerID string, pid int, inode uint64) (yes bool) {
 	return inodes.Has(inode)
 }
 
+// Owners returns index of container sockets inodes to pids of processes, that hold them,
+// inode, shared by several processes (i.e. after fork), goes to the lowest pid.
+func (m *InodesMap) Owners(containerID string) (rv map[uint64]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rv = make(map[uint64]int)
+
+	for pid, inodes := range m.m[containerID] {
+		inodes.Iter(func(k uint64) bool {
+			if cur, ok := rv[k]; !ok || pid < cur {
+				rv[k] = pid
+			}
+
+			return true
+		})
+	}
+
+	return rv
+}
+
 func (m *InodesMap) findListener(containerID string, pid int) (path string, ok bool) {
 	pids, ok := m.l[containerID]
 	if !ok {
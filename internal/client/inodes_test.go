@@ -40,3 +40,25 @@ func TestInodes(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestInodesOwners(t *testing.T) {
+	t.Parallel()
+
+	m := &client.InodesMap{}
+
+	// socket is inherited by forked workers
+	for _, pid := range []int{7, 3, 5} {
+		m.AddProcess("1", pid, "app")
+		m.AddInode("1", pid, 101)
+	}
+
+	m.AddInode("1", 5, 102)
+
+	for range 10 {
+		owners := m.Owners("1")
+
+		if len(owners) != 2 || owners[101] != 3 || owners[102] != 5 {
+			t.Fatal("owners:", owners)
+		}
+	}
+}
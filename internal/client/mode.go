@@ -6,6 +6,7 @@ const (
 	None         mode = 0
 	InContainer  mode = 1
 	LinuxNsenter mode = 2
+	Sidecar      mode = 3
 )
 
 func (m mode) String() (rv string) {
@@ -14,6 +15,8 @@ func (m mode) String() (rv string) {
 		return "in-container"
 	case LinuxNsenter:
 		return "linux-nsenter"
+	case Sidecar:
+		return "sidecar"
 	case None:
 	}
 
@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+var ErrNoHosts = errors.New("no hosts")
+
+type HostClient interface {
+	graph.ContainerClient
+	Mode() string
+	Close() error
+}
+
+type hostEntry struct {
+	Cli  HostClient
+	Name string
+}
+
+// Multi scans several hosts concurrently, results are concatenated in hosts order.
+type Multi struct {
+	hosts []*hostEntry
+	skip  []string
+}
+
+func NewMulti() *Multi {
+	return &Multi{}
+}
+
+func (m *Multi) Add(name string, cli HostClient) {
+	m.hosts = append(m.hosts, &hostEntry{Name: name, Cli: cli})
+}
+
+func (m *Multi) Mode() string {
+	var modes []string
+
+	for _, h := range m.hosts {
+		if mode := h.Cli.Mode(); !slices.Contains(modes, mode) {
+			modes = append(modes, mode)
+		}
+	}
+
+	return strings.Join(modes, ", ")
+}
+
+func (m *Multi) Close() (err error) {
+	errs := make([]error, 0, len(m.hosts))
+
+	for _, h := range m.hosts {
+		if cerr := h.Cli.Close(); cerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.Name, cerr))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Multi) Containers(
+	ctx context.Context,
+	proto graph.NetProto,
+	deep bool,
+	skipkeys []string,
+	progress func(int, int),
+) (rv []*graph.Container, err error) {
+	m.skip = skipkeys
+
+	return m.each(progress, func(h *hostEntry, report func(int, int)) ([]*graph.Container, error) {
+		return h.Cli.Containers(ctx, proto, deep, skipkeys, report)
+	})
+}
+
+// Resample re-reads sockets of known containers on every host, hosts, that cannot resample,
+// are scanned again.
+func (m *Multi) Resample(
+	ctx context.Context,
+	proto graph.NetProto,
+	deep bool,
+	containers []*graph.Container,
+	progress func(int, int),
+) (rv []*graph.Container, err error) {
+	return m.each(progress, func(h *hostEntry, report func(int, int)) ([]*graph.Container, error) {
+		if rs, ok := h.Cli.(graph.Resampler); ok {
+			return rs.Resample(ctx, proto, deep, containers, report)
+		}
+
+		return h.Cli.Containers(ctx, proto, deep, m.skip, report)
+	})
+}
+
+// each runs scan for all hosts concurrently, it fails only if all hosts are failed.
+func (m *Multi) each(
+	progress func(int, int),
+	scan func(*hostEntry, func(int, int)) ([]*graph.Container, error),
+) (rv []*graph.Container, err error) {
+	if len(m.hosts) == 0 {
+		return nil, ErrNoHosts
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		current = make([]int, len(m.hosts))
+		totals  = make([]int, len(m.hosts))
+		results = make([][]*graph.Container, len(m.hosts))
+		errs    = make([]error, len(m.hosts))
+	)
+
+	report := func(idx, cur, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		current[idx], totals[idx] = cur, total
+
+		var sc, st int
+
+		for i := range current {
+			sc, st = sc+current[i], st+totals[i]
+		}
+
+		progress(sc, st)
+	}
+
+	for i, h := range m.hosts {
+		wg.Go(func() {
+			results[i], errs[i] = scan(h, func(cur, total int) {
+				report(i, cur, total)
+			})
+		})
+	}
+
+	wg.Wait()
+
+	var failed int
+
+	for i, h := range m.hosts {
+		if errs[i] != nil {
+			log.Printf("[-] host %s: %v", h.Name, errs[i])
+
+			failed++
+
+			continue
+		}
+
+		rv = append(rv, results[i]...)
+	}
+
+	if failed == len(m.hosts) {
+		return nil, fmt.Errorf("hosts: %w", errors.Join(errs...))
+	}
+
+	return rv, nil
+}
+
+// ResolveHost returns name and addresses for docker host, i.e. tcp://10.0.0.2:2376,
+// local sockets are named after current host and addressed by its interfaces.
+func ResolveHost(host string) (name string, ips []string, err error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse: %w", err)
+	}
+
+	if name = u.Hostname(); name == "" {
+		if name, err = os.Hostname(); err != nil {
+			return "", nil, fmt.Errorf("hostname: %w", err)
+		}
+
+		if ips, err = localIPs(); err != nil {
+			return "", nil, fmt.Errorf("interfaces: %w", err)
+		}
+
+		return name, ips, nil
+	}
+
+	if ip := net.ParseIP(name); ip != nil {
+		return name, []string{ip.String()}, nil
+	}
+
+	if ips, err = net.LookupHost(name); err != nil {
+		return "", nil, fmt.Errorf("lookup: %w", err)
+	}
+
+	return name, ips, nil
+}
+
+// LocalHost returns name of local docker host among given ones: the one, reached through
+// unix socket or loopback address.
+func LocalHost(hosts []string) (name string, ok bool) {
+	for _, host := range hosts {
+		u, err := url.Parse(host)
+		if err != nil {
+			continue
+		}
+
+		if h := u.Hostname(); h != "" && h != "localhost" {
+			if ip := net.ParseIP(h); ip == nil || !ip.IsLoopback() {
+				continue
+			}
+		}
+
+		if name, _, err = ResolveHost(host); err == nil {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func localIPs() (rv []string, err error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok || ipn.IP.IsLoopback() || ipn.IP.IsLinkLocalUnicast() {
+			continue
+		}
+
+		rv = append(rv, ipn.IP.String())
+	}
+
+	return rv, nil
+}
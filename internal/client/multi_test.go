@@ -0,0 +1,192 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/client"
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+type hostMock struct {
+	Err      error
+	CloseErr error
+	Data     []*graph.Container
+	Name     string
+}
+
+func (hm *hostMock) Containers(
+	_ context.Context,
+	_ graph.NetProto,
+	_ bool,
+	_ []string,
+	progress func(int, int),
+) ([]*graph.Container, error) {
+	if hm.Err != nil {
+		return nil, hm.Err
+	}
+
+	progress(len(hm.Data), len(hm.Data))
+
+	return hm.Data, nil
+}
+
+func (hm *hostMock) Mode() string {
+	return hm.Name
+}
+
+func (hm *hostMock) Close() error {
+	return hm.CloseErr
+}
+
+func TestMultiContainers(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test-err")
+
+	m := client.NewMulti()
+
+	if _, err := m.Containers(context.Background(), graph.TCP, false, nil, voidProgress); !errors.Is(err, client.ErrNoHosts) {
+		t.Fatal("empty:", err)
+	}
+
+	m.Add("a", &hostMock{Name: "in-container", Data: []*graph.Container{{Name: "a-1"}, {Name: "a-2"}}})
+	m.Add("b", &hostMock{Name: "in-container", Err: testErr})
+	m.Add("c", &hostMock{Name: "sidecar", Data: []*graph.Container{{Name: "c-1"}}, CloseErr: testErr})
+
+	if m.Mode() != "in-container, sidecar" {
+		t.Fatal("mode:", m.Mode())
+	}
+
+	var last, total int
+
+	rv, err := m.Containers(context.Background(), graph.TCP, false, nil, func(cur, all int) {
+		last, total = cur, all
+	})
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 3 || rv[0].Name != "a-1" || rv[2].Name != "c-1" {
+		t.Fatal("result:", rv)
+	}
+
+	if last != 3 || total != 3 {
+		t.Fatal("progress:", last, total)
+	}
+
+	if err = m.Close(); !errors.Is(err, testErr) {
+		t.Fatal("close:", err)
+	}
+}
+
+type resampleMock struct {
+	hostMock
+	Known []*graph.Container
+}
+
+func (rm *resampleMock) Resample(
+	_ context.Context,
+	_ graph.NetProto,
+	_ bool,
+	containers []*graph.Container,
+	progress func(int, int),
+) ([]*graph.Container, error) {
+	rm.Known = containers
+
+	progress(1, 1)
+
+	return []*graph.Container{{Name: "r-1"}}, nil
+}
+
+func TestMultiResample(t *testing.T) {
+	t.Parallel()
+
+	rm := &resampleMock{hostMock: hostMock{Data: []*graph.Container{{Name: "a-1"}}}}
+
+	m := client.NewMulti()
+	m.Add("a", rm)
+	m.Add("b", &hostMock{Data: []*graph.Container{{Name: "b-1"}}})
+
+	known, err := m.Containers(context.Background(), graph.TCP, false, nil, voidProgress)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	rv, err := m.Resample(context.Background(), graph.TCP, false, known, voidProgress)
+	if err != nil {
+		t.Fatal("resample:", err)
+	}
+
+	if len(rv) != 2 || rv[0].Name != "r-1" || rv[1].Name != "b-1" {
+		t.Fatal("result:", rv)
+	}
+
+	if len(rm.Known) != 2 {
+		t.Fatal("known:", rm.Known)
+	}
+}
+
+func TestMultiContainersAllFailed(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test-err")
+
+	m := client.NewMulti()
+	m.Add("a", &hostMock{Err: testErr})
+	m.Add("b", &hostMock{Err: testErr})
+
+	if _, err := m.Containers(context.Background(), graph.TCP, false, nil, voidProgress); !errors.Is(err, testErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveHost(t *testing.T) {
+	t.Parallel()
+
+	name, ips, err := client.ResolveHost("tcp://10.0.0.2:2376")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "10.0.0.2" || len(ips) != 1 || ips[0] != "10.0.0.2" {
+		t.Fatal("tcp:", name, ips)
+	}
+
+	host, _ := os.Hostname()
+
+	if name, ips, err = client.ResolveHost("unix:///var/run/docker.sock"); err != nil || name != host {
+		t.Fatal("unix:", name, ips, err)
+	}
+
+	for _, ip := range ips {
+		if net.ParseIP(ip).IsLoopback() {
+			t.Fatal("unix: loopback", ip)
+		}
+	}
+
+	if _, _, err = client.ResolveHost("://bad"); err == nil {
+		t.Fatal("bad: no error")
+	}
+}
+
+func TestLocalHost(t *testing.T) {
+	t.Parallel()
+
+	host, _ := os.Hostname()
+
+	if name, ok := client.LocalHost([]string{"tcp://10.0.0.2:2376", "unix:///var/run/docker.sock"}); !ok || name != host {
+		t.Fatal("unix:", name, ok)
+	}
+
+	if name, ok := client.LocalHost([]string{"tcp://10.0.0.2:2376", "tcp://127.0.0.1:2375"}); !ok || name != "127.0.0.1" {
+		t.Fatal("loopback:", name, ok)
+	}
+
+	if _, ok := client.LocalHost([]string{"tcp://10.0.0.2:2376", "://bad"}); ok {
+		t.Fatal("remote only: found")
+	}
+}
@@ -1,11 +1,31 @@
 package client
 
+import (
+	"context"
+	"time"
+
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
 type Option func(*options)
 
 type options struct {
 	Create  createClient
+	Dial    dialCRI
 	Nsenter nsenter
 	Inodes  inodes
+	Netns   netns
+	Pods    pods
+	Cgroup  cgroup
+	States  set.Unordered[string]
+	Sidecar string
+	Host    string
+	HostIPs []string
+	Chain   []string
+	Timeout time.Duration
+	Workers int
 	Mode    mode
 }
 
@@ -27,8 +47,85 @@ func WithNsenterFn(f nsenter) Option {
 	}
 }
 
+func WithNetnsFn(f netns) Option {
+	return func(o *options) {
+		o.Netns = f
+	}
+}
+
 func WithInodesFn(f inodes) Option {
 	return func(o *options) {
 		o.Inodes = f
 	}
 }
+
+func WithCgroupFn(f cgroup) Option {
+	return func(o *options) {
+		o.Cgroup = f
+	}
+}
+
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.Workers = n
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.Timeout = d
+	}
+}
+
+func WithExecChain(names ...string) Option {
+	return func(o *options) {
+		o.Chain = names
+	}
+}
+
+func WithSidecarImage(image string) Option {
+	return func(o *options) {
+		o.Sidecar = image
+	}
+}
+
+func WithPodsFn(f pods) Option {
+	return func(o *options) {
+		o.Pods = f
+	}
+}
+
+// WithHost sets docker host name and its addresses, that are used to resolve published ports.
+func WithHost(name string, ips ...string) Option {
+	return func(o *options) {
+		o.Host = name
+		o.HostIPs = ips
+	}
+}
+
+// WithStates sets transient tcp states to keep, connections in other ones are dropped on scan.
+func WithStates(states set.Unordered[string]) Option {
+	return func(o *options) {
+		o.States = states
+	}
+}
+
+func WithCRIDialer(f dialCRI) Option {
+	return func(o *options) {
+		o.Dial = f
+	}
+}
+
+func (o *options) matchState(c *graph.Connection) bool {
+	return c.State == "" || o.States.Has(c.State)
+}
+
+func (o *options) containerContext(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	if o.Timeout > 0 {
+		return context.WithTimeout(ctx, o.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
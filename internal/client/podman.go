@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	podmanHostENV   = "CONTAINER_HOST"
+	podmanRuntime   = "XDG_RUNTIME_DIR"
+	podmanRootSock  = "/run/podman/podman.sock"
+	podmanPodsURL   = "/v4.0.0/libpod/pods/json"
+	podmanDummyHost = "podman"
+)
+
+var (
+	ErrPodmanScheme = errors.New("unsupported podman host scheme")
+	ErrPodmanStatus = errors.New("unexpected podman api status")
+)
+
+type Pod struct {
+	ID      string
+	Name    string
+	InfraID string
+	Members []string
+}
+
+type podJSON struct {
+	ID         string `json:"Id"`
+	Name       string `json:"Name"`
+	InfraID    string `json:"InfraId"`
+	Containers []struct {
+		ID string `json:"Id"`
+	} `json:"Containers"`
+}
+
+// podMember describes container, that shares network namespace with others pod members,
+// primary one receives sockets with unknown owners.
+type podMember struct {
+	Name    string
+	Primary bool
+}
+
+// PodmanHost returns podman api socket address: CONTAINER_HOST if set, or
+// rootless / rootful socket for given effective user id.
+func PodmanHost(euid int) string {
+	if host := os.Getenv(podmanHostENV); host != "" {
+		return host
+	}
+
+	if euid == 0 {
+		return "unix://" + podmanRootSock
+	}
+
+	dir := os.Getenv(podmanRuntime)
+	if dir == "" {
+		dir = filepath.Join("/run/user", strconv.Itoa(euid))
+	}
+
+	return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+}
+
+// PodmanPods creates pods lister, that talks to libpod api at given host.
+func PodmanPods(host string) (
+	rv func(context.Context) ([]*Pod, error),
+	err error,
+) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	var (
+		base = url.URL{Scheme: "http", Host: u.Host, Path: podmanPodsURL}
+		cli  = &http.Client{}
+	)
+
+	switch u.Scheme {
+	case "unix":
+		var dialer net.Dialer
+
+		base.Host = podmanDummyHost
+		cli.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", u.Path)
+			},
+		}
+	case "tcp", "http":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPodmanScheme, u.Scheme)
+	}
+
+	endpoint := base.String()
+
+	return func(ctx context.Context) (rv []*Pod, err error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("request: %w", err)
+		}
+
+		resp, err := cli.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("do: %w", err)
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%w: %s", ErrPodmanStatus, resp.Status)
+		}
+
+		var raw []*podJSON
+
+		if err = json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("decode: %w", err)
+		}
+
+		rv = make([]*Pod, len(raw))
+
+		for i, p := range raw {
+			rv[i] = &Pod{
+				ID:      p.ID,
+				Name:    p.Name,
+				InfraID: p.InfraID,
+				Members: make([]string, len(p.Containers)),
+			}
+
+			for j, c := range p.Containers {
+				rv[i].Members[j] = c.ID
+			}
+		}
+
+		return rv, nil
+	}, nil
+}
+
+// podMembers maps running pod members to their pods, infra containers are reported
+// separately, as they only hold namespaces.
+func (d *Docker) podMembers(
+	ctx context.Context,
+	containers []container.Summary,
+) (
+	members map[string]*podMember,
+	infra set.Unordered[string],
+	err error,
+) {
+	members, infra = make(map[string]*podMember), make(set.Unordered[string])
+
+	if d.opt.Pods == nil {
+		return members, infra, nil
+	}
+
+	list, err := d.opt.Pods(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pods: %w", err)
+	}
+
+	owner := make(map[string]*Pod)
+
+	for _, p := range list {
+		infra.Add(p.InfraID)
+
+		for _, id := range p.Members {
+			if id != p.InfraID {
+				owner[id] = p
+			}
+		}
+	}
+
+	seen := make(set.Unordered[string])
+
+	for i := range containers {
+		c := &containers[i]
+
+		p, ok := owner[c.ID]
+		if !ok || c.State != stateRunning {
+			continue
+		}
+
+		members[c.ID] = &podMember{
+			Name:    p.Name,
+			Primary: seen.Add(p.ID),
+		}
+	}
+
+	return members, infra, nil
+}
+
+// podFilter drops sockets from shared pod namespace, that belongs to others pod members. In nsenter
+// mode sockets are matched by owners pids, otherwise by process names, as exec strategies report them:
+// comm (ss, lsof) or executable (netstat), i.e. `python3` and `python3.11` for `/usr/bin/python3.11`.
+func (d *Docker) podFilter(
+	ctx context.Context,
+	cid string,
+	pm *podMember,
+) (
+	keep func(int, *graph.Connection) bool,
+	err error,
+) {
+	const minPsFields = 3
+
+	ps, err := d.cli.ContainerTop(ctx, cid, []string{"-o pid,comm,args"})
+	if err != nil {
+		return nil, fmt.Errorf("top: %w", err)
+	}
+
+	var (
+		pids  = make(set.Unordered[int])
+		names = make(set.Unordered[string])
+	)
+
+	for _, p := range ps.Processes {
+		if len(p) < minPsFields {
+			continue
+		}
+
+		pid, perr := strconv.Atoi(p[0])
+		if perr != nil {
+			continue
+		}
+
+		pids.Add(pid)
+		names.Add(p[1])
+
+		if args := strings.Fields(p[2]); len(args) > 0 {
+			names.Add(filepath.Base(args[0]))
+		}
+	}
+
+	return func(pid int, conn *graph.Connection) bool {
+		switch {
+		case conn.Process == graph.ProcessUnknown:
+			return pm.Primary
+		case d.opt.Mode == LinuxNsenter:
+			return pids.Has(pid)
+		default:
+			return names.Has(conn.Process)
+		}
+	}, nil
+}
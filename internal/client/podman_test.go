@@ -0,0 +1,340 @@
+package client_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+
+	"github.com/s0rg/decompose/internal/client"
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const testPodsJSON = `[{
+	"Id": "pod-1",
+	"Name": "web",
+	"InfraId": "infra",
+	"Containers": [{"Id": "infra"}, {"Id": "1"}, {"Id": "2"}]
+}]`
+
+func testPodsServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/libpod/pods/json") {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestPodmanHost(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	if h := client.PodmanHost(0); h != "unix:///run/podman/podman.sock" {
+		t.Fatal("root:", h)
+	}
+
+	if h := client.PodmanHost(1000); h != "unix:///run/user/1000/podman/podman.sock" {
+		t.Fatal("rootless:", h)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	if h := client.PodmanHost(1001); h != "unix:///run/user/1001/podman/podman.sock" {
+		t.Fatal("rootless no-xdg:", h)
+	}
+
+	t.Setenv("CONTAINER_HOST", "tcp://podman:8080")
+
+	if h := client.PodmanHost(1000); h != "tcp://podman:8080" {
+		t.Fatal("env:", h)
+	}
+}
+
+func TestPodmanPods(t *testing.T) {
+	t.Parallel()
+
+	srv := testPodsServer(t, http.StatusOK, testPodsJSON)
+
+	pods, err := client.PodmanPods(srv.URL)
+	if err != nil {
+		t.Fatal("create:", err)
+	}
+
+	rv, err := pods(context.Background())
+	if err != nil {
+		t.Fatal("pods:", err)
+	}
+
+	if len(rv) != 1 {
+		t.Fatal("len:", len(rv))
+	}
+
+	p := rv[0]
+
+	if p.ID != "pod-1" || p.Name != "web" || p.InfraID != "infra" || len(p.Members) != 3 || p.Members[2] != "2" {
+		t.Fail()
+	}
+}
+
+func TestPodmanPodsErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := client.PodmanPods("ssh://host"); !errors.Is(err, client.ErrPodmanScheme) {
+		t.Fatal("scheme:", err)
+	}
+
+	srv := testPodsServer(t, http.StatusInternalServerError, "")
+
+	pods, err := client.PodmanPods(srv.URL)
+	if err != nil {
+		t.Fatal("create:", err)
+	}
+
+	if _, err = pods(context.Background()); !errors.Is(err, client.ErrPodmanStatus) {
+		t.Fatal("status:", err)
+	}
+
+	srv = testPodsServer(t, http.StatusOK, "{")
+
+	if pods, err = client.PodmanPods(srv.URL); err != nil {
+		t.Fatal("create:", err)
+	}
+
+	if _, err = pods(context.Background()); err == nil {
+		t.Fatal("decode: no error")
+	}
+}
+
+func testPodMembers() []container.Summary {
+	member := func(id, name string) container.Summary {
+		return container.Summary{
+			ID:    id,
+			Names: []string{name},
+			Image: "test-image",
+			State: "running",
+			NetworkSettings: &container.NetworkSettingsSummary{
+				Networks: map[string]*network.EndpointSettings{},
+			},
+		}
+	}
+
+	return []container.Summary{
+		member("infra", "web-infra"),
+		member("1", "web-front"),
+		member("2", "web-back"),
+		member("3", "alone"),
+	}
+}
+
+func TestPodmanContainers(t *testing.T) {
+	t.Parallel()
+
+	srv := testPodsServer(t, http.StatusOK, testPodsJSON)
+
+	pods, err := client.PodmanPods(srv.URL)
+	if err != nil {
+		t.Fatal("pods:", err)
+	}
+
+	cm := &clientMock{
+		OnList: testPodMembers,
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnTop: func(id string) (rv container.TopResponse) {
+			// comm and executable names differ for interpreters
+			procs := map[string][]string{
+				"1":     {"1", "python3", "/usr/bin/python3.11 -m http.server 80"},
+				"2":     {"2", "back", "/usr/bin/back -v"},
+				"3":     {"3", "alone", "alone"},
+				"infra": {"4", "catatonit", "catatonit -P"},
+			}
+
+			rv.Processes = [][]string{procs[id]}
+
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			// pod members share network and pid namespaces, thus see each others sockets
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(`Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp        0      0 0.0.0.0:80              0.0.0.0:*               LISTEN  1/python3
+tcp        0      0 0.0.0.0:8080            0.0.0.0:*               LISTEN  2/back
+`))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithPodsFn(pods),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	if len(rv) != 3 {
+		t.Fatal("len:", len(rv))
+	}
+
+	want := map[string]struct {
+		Pod   string
+		Count int
+	}{
+		"web-front": {Pod: "web", Count: 1},
+		"web-back":  {Pod: "web", Count: 1},
+		"alone":     {Pod: "", Count: 2},
+	}
+
+	for _, c := range rv {
+		w, ok := want[c.Name]
+		if !ok {
+			t.Fatal("unexpected:", c.Name)
+		}
+
+		if c.Labels[graph.PodmanPodLabel] != w.Pod || c.ConnectionsCount() != w.Count {
+			t.Fatal(c.Name, c.Labels, c.ConnectionsCount())
+		}
+	}
+}
+
+func TestPodmanContainersUnknown(t *testing.T) {
+	t.Parallel()
+
+	cm := &clientMock{
+		OnList: testPodMembers,
+		OnInspect: func() (rv container.InspectResponse) {
+			rv.ContainerJSONBase = &container.ContainerJSONBase{}
+			rv.Config = &container.Config{}
+
+			return rv
+		},
+		OnTop: func(string) (rv container.TopResponse) {
+			return rv
+		},
+		OnExecCreate: func() (rv container.ExecCreateResponse) {
+			return rv
+		},
+		OnExecAttach: func() (rv types.HijackedResponse) {
+			rv.Conn = &connMock{}
+			rv.Reader = bufio.NewReader(bytes.NewBufferString(
+				`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+`))
+
+			return
+		},
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithExecChain(client.StrategyProcNet),
+		client.WithPodsFn(func(context.Context) ([]*client.Pod, error) {
+			return []*client.Pod{
+				{ID: "pod-1", Name: "web", InfraID: "infra", Members: []string{"infra", "1", "2"}},
+			}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	rv, err := cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	)
+	if err != nil {
+		t.Fatal("containers:", err)
+	}
+
+	// sockets with unknown owners goes to primary pod member only
+	counts := make(map[string]int)
+
+	for _, c := range rv {
+		counts[c.Name] = c.ConnectionsCount()
+	}
+
+	if counts["web-front"] != 1 || counts["web-back"] != 0 || counts["alone"] != 1 {
+		t.Fatal(counts)
+	}
+}
+
+func TestPodmanContainersPodsError(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test-err")
+
+	cm := &clientMock{
+		OnList: testPodMembers,
+	}
+
+	cli, err := client.NewDocker(
+		client.WithClientCreator(func() (client.DockerClient, error) {
+			return cm, nil
+		}),
+		client.WithMode(client.InContainer),
+		client.WithPodsFn(func(context.Context) ([]*client.Pod, error) {
+			return nil, testErr
+		}),
+	)
+	if err != nil {
+		t.Fatal("client:", err)
+	}
+
+	if _, err = cli.Containers(
+		context.Background(),
+		graph.TCP,
+		false,
+		nil,
+		voidProgress,
+	); !errors.Is(err, testErr) {
+		t.Fatal(err)
+	}
+}
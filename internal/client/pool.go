@@ -0,0 +1,67 @@
+package client
+
+import "sync"
+
+type progressCounter struct {
+	report func(int, int)
+	mu     sync.Mutex
+	cur    int
+	total  int
+}
+
+func (p *progressCounter) Step() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cur++
+
+	if p.cur < p.total {
+		p.report(p.cur, p.total)
+	}
+}
+
+func (p *progressCounter) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.report(p.total, p.total)
+}
+
+// parallel runs fn for every index in [0, count) on at most workers goroutines,
+// first error stops scheduling of remaining indexes and is returned.
+func parallel(workers, count int, fn func(int) error) (err error) {
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		jobs = make(chan int)
+		stop = make(chan struct{})
+	)
+
+	for range max(1, min(workers, count)) {
+		wg.Go(func() {
+			for idx := range jobs {
+				if ferr := fn(idx); ferr != nil {
+					once.Do(func() {
+						err = ferr
+
+						close(stop)
+					})
+				}
+			}
+		})
+	}
+
+feed:
+	for i := range count {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break feed
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return err
+}
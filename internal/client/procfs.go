@@ -0,0 +1,232 @@
+package client
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/procfs"
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	procENV     = "IN_DOCKER_PROC_ROOT"
+	procDefault = "/proc"
+	nsNet       = "net"
+
+	// from net/tcp_states.h.
+	tcpEstablished = uint64(1)
+	tcpListen      = uint64(10)
+
+	fibLocal         = "/32 host LOCAL"
+	fibPrefix        = "|--"
+	inet6Fields      = 6
+	inet6ScopeGlobal = "00"
+)
+
+// ProcRoot returns procfs mount point, honouring IN_DOCKER_PROC_ROOT.
+func ProcRoot() string {
+	if root := os.Getenv(procENV); root != "" {
+		return filepath.Join(root, procDefault)
+	}
+
+	return procDefault
+}
+
+// checkState returns name for transient tcp states, or empty one for listening and established sockets.
+func checkState(state uint64) (name string, listener, valid bool) {
+	switch state {
+	case tcpListen:
+		return "", true, true
+	case tcpEstablished:
+		return "", false, true
+	}
+
+	name, valid = graph.TCPState(state)
+
+	return name, false, valid
+}
+
+func scanTCP(
+	pfs procfs.FS,
+	name string,
+	onconn func(*graph.Connection),
+) {
+	tcp4, err := pfs.NetTCP()
+	if err != nil {
+		log.Printf("[-] procfs/tcp: %v", err)
+	} else {
+		for _, s := range tcp4 {
+			state, listener, ok := checkState(s.St)
+			if !ok {
+				continue
+			}
+
+			onconn(&graph.Connection{
+				Process: name,
+				Inode:   s.Inode,
+				SrcIP:   s.LocalAddr,
+				DstIP:   s.RemAddr,
+				SrcPort: int(s.LocalPort),
+				DstPort: int(s.RemPort),
+				State:   state,
+				Proto:   graph.TCP,
+				Listen:  listener,
+			})
+		}
+	}
+
+	tcp6, err := pfs.NetTCP6()
+	if err != nil {
+		log.Printf("[-] procfs/tcp6: %v", err)
+
+		return
+	}
+
+	for _, s := range tcp6 {
+		state, listener, ok := checkState(s.St)
+		if !ok {
+			continue
+		}
+
+		onconn(&graph.Connection{
+			Process: name,
+			Inode:   s.Inode,
+			SrcIP:   s.LocalAddr,
+			DstIP:   s.RemAddr,
+			SrcPort: int(s.LocalPort),
+			DstPort: int(s.RemPort),
+			State:   state,
+			Proto:   graph.TCP,
+			Listen:  listener,
+		})
+	}
+}
+
+func scanUDP(
+	pfs procfs.FS,
+	name string,
+	onconn func(*graph.Connection),
+) {
+	udp4, err := pfs.NetUDP()
+	if err != nil {
+		log.Printf("[-] procfs/udp: %v", err)
+	} else {
+		for _, s := range udp4 {
+			onconn(&graph.Connection{
+				Process: name,
+				Inode:   s.Inode,
+				SrcIP:   s.LocalAddr,
+				DstIP:   s.RemAddr,
+				SrcPort: int(s.LocalPort),
+				DstPort: int(s.RemPort),
+				Proto:   graph.UDP,
+			})
+		}
+	}
+
+	udp6, err := pfs.NetUDP6()
+	if err != nil {
+		log.Printf("[-] procfs/udp6: %v", err)
+
+		return
+	}
+
+	for _, s := range udp6 {
+		onconn(&graph.Connection{
+			Process: name,
+			Inode:   s.Inode,
+			SrcIP:   s.LocalAddr,
+			DstIP:   s.RemAddr,
+			SrcPort: int(s.LocalPort),
+			DstPort: int(s.RemPort),
+			Proto:   graph.UDP,
+		})
+	}
+}
+
+func scanUNIX(
+	pfs procfs.FS,
+	name string,
+	onconn func(*graph.Connection),
+) {
+	unix, err := pfs.NetUNIX()
+	if err != nil {
+		log.Printf("[-] procfs/unix: %v", err)
+
+		return
+	}
+
+	for _, s := range unix.Rows {
+		onconn(&graph.Connection{
+			Process: name,
+			Inode:   s.Inode,
+			Path:    s.Path,
+			Listen:  s.Flags != 0,
+			Proto:   graph.UNIX,
+		})
+	}
+}
+
+// parseFibTrie reads local (host) ipv4 addresses from /proc/net/fib_trie.
+func parseFibTrie(r io.Reader, cb func(net.IP)) error {
+	var (
+		sc   = bufio.NewScanner(r)
+		seen = make(set.Unordered[string])
+		last string
+	)
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		switch {
+		case strings.HasPrefix(line, fibPrefix):
+			last = strings.TrimSpace(line[len(fibPrefix):])
+		case line == fibLocal && last != "":
+			if ip := net.ParseIP(last); ip != nil && !ip.IsLoopback() && seen.Add(last) {
+				cb(ip)
+			}
+
+			last = ""
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+// parseIfInet6 reads global ipv6 addresses from /proc/net/if_inet6.
+func parseIfInet6(r io.Reader, cb func(net.IP)) error {
+	sc := bufio.NewScanner(r)
+
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != inet6Fields || fields[3] != inet6ScopeGlobal {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != net.IPv6len {
+			continue
+		}
+
+		cb(net.IP(raw))
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
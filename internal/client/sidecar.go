@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	SidecarLabel        = "decompose.sidecar"
+	DefaultSidecarImage = "nicolaka/netshoot"
+
+	sidecarSleep  = "600"
+	sidecarRemove = 10 * time.Second
+)
+
+// connectionsSidecar starts helper container, that shares network and pid
+// namespaces with target one, and runs exec strategies chain inside it.
+func (d *Docker) connectionsSidecar(
+	ctx context.Context,
+	cid string,
+	proto graph.NetProto,
+	cb func(int, *graph.Connection),
+) (strategy string, err error) {
+	resp, err := d.createSidecar(ctx, cid)
+	if err != nil {
+		return "", fmt.Errorf("sidecar create: %w", err)
+	}
+
+	defer func() {
+		// helper must be removed, even if scan is already cancelled
+		rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sidecarRemove)
+		defer cancel()
+
+		if rerr := d.cli.ContainerRemove(rctx, resp.ID, container.RemoveOptions{
+			Force: true,
+		}); rerr != nil {
+			log.Printf("[-] sidecar %s for %s remove: %v", resp.ID, cid, rerr)
+		}
+	}()
+
+	if err = d.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("sidecar start: %w", err)
+	}
+
+	return d.connectionsInContainer(ctx, resp.ID, proto, cb)
+}
+
+// createSidecar creates helper container for target one, helper image must be
+// already present on host (see pullSidecar).
+func (d *Docker) createSidecar(
+	ctx context.Context,
+	cid string,
+) (rv container.CreateResponse, err error) {
+	target := "container:" + cid
+
+	return d.cli.ContainerCreate(ctx, &container.Config{
+		Image:  d.opt.Sidecar,
+		Cmd:    []string{"sleep", sidecarSleep},
+		Labels: map[string]string{SidecarLabel: cid},
+	}, &container.HostConfig{
+		NetworkMode: container.NetworkMode(target),
+		PidMode:     container.PidMode(target),
+	}, nil, nil, "")
+}
+
+// pullSidecar pulls helper image, if it is missing on host. It runs once per scan, before
+// containers are processed, so pull is not limited by per-container timeout.
+func (d *Docker) pullSidecar(ctx context.Context) (err error) {
+	if _, err = d.cli.ImageInspect(ctx, d.opt.Sidecar); err == nil {
+		return nil
+	}
+
+	if !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("image %s (set by -sidecar): %w", d.opt.Sidecar, err)
+	}
+
+	log.Println("Pulling sidecar image:", d.opt.Sidecar)
+
+	if err = d.pullImage(ctx, d.opt.Sidecar); err != nil {
+		return fmt.Errorf("image %s (set by -sidecar): %w", d.opt.Sidecar, err)
+	}
+
+	return nil
+}
+
+func (d *Docker) pullImage(ctx context.Context, ref string) (err error) {
+	rc, err := d.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("pull: %w", err)
+	}
+
+	defer rc.Close()
+
+	// pull is complete, once its progress stream ends
+	if _, err = io.Copy(io.Discard, rc); err != nil {
+		return fmt.Errorf("progress: %w", err)
+	}
+
+	return nil
+}
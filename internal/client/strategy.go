@@ -0,0 +1,74 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+const (
+	StrategyNetstat = "netstat"
+	StrategySS      = "ss"
+	StrategyProcNet = "procnet"
+	StrategyLsof    = "lsof"
+	StrategyNsenter = "nsenter"
+)
+
+var (
+	ErrUnknownStrategy = errors.New("unknown strategy")
+
+	DefaultStrategies = []string{
+		StrategyNetstat,
+		StrategySS,
+		StrategyProcNet,
+		StrategyLsof,
+	}
+)
+
+type execStrategy struct {
+	Cmd   func(graph.NetProto) []string
+	Parse func(io.Reader, set.Unordered[string], func(*graph.Connection)) error
+	Name  string
+}
+
+func strategyFor(name string) (rv *execStrategy, ok bool) {
+	rv = &execStrategy{Name: name}
+
+	switch name {
+	case StrategyNetstat:
+		rv.Cmd, rv.Parse = graph.NetstatCMD, graph.ParseNetstat
+	case StrategySS:
+		rv.Cmd, rv.Parse = graph.SsCMD, graph.ParseSS
+	case StrategyProcNet:
+		rv.Cmd, rv.Parse = graph.ProcNetCMD, graph.ParseProcNet
+	case StrategyLsof:
+		rv.Cmd, rv.Parse = graph.LsofCMD, graph.ParseLsof
+	default:
+		return nil, false
+	}
+
+	return rv, true
+}
+
+func makeChain(names []string) (rv []*execStrategy, err error) {
+	if len(names) == 0 {
+		names = DefaultStrategies
+	}
+
+	rv = make([]*execStrategy, len(names))
+
+	for i, name := range names {
+		s, ok := strategyFor(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+		}
+
+		rv[i] = s
+	}
+
+	return rv, nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/s0rg/decompose/internal/node"
 )
@@ -24,6 +26,12 @@ type ContainerClient interface {
 	Containers(context.Context, NetProto, bool, []string, func(int, int)) ([]*Container, error)
 }
 
+// Resampler is implemented by clients, that can re-read sockets of already discovered
+// containers, it is used for every sample, besides first one.
+type Resampler interface {
+	Resample(context.Context, NetProto, bool, []*Container, func(int, int)) ([]*Container, error)
+}
+
 type Builder interface {
 	AddNode(*node.Node) error
 	AddEdge(*node.Edge)
@@ -39,37 +47,51 @@ type NamedBuilderWriter interface {
 	NamedWriter
 }
 
+// NetworkBuilder is implemented by builders, that can draw networks.
+type NetworkBuilder interface {
+	AddNetwork(*node.Network)
+}
+
+// HeaderBuilder is implemented by builders, that can record stream provenance.
+type HeaderBuilder interface {
+	SetHeader(*node.Header)
+}
+
 type Enricher interface {
 	Enrich(*node.Node)
 }
 
 func Build(
+	ctx context.Context,
 	cfg *Config,
 	cli ContainerClient,
 ) error {
 	log.Println("Gathering containers info, please be patient...")
 
-	containers, err := cli.Containers(
-		context.Background(),
-		cfg.Proto,
-		cfg.Deep,
-		cfg.SkipEnv,
-		func(cur, total int) {
-			switch {
-			case cur == 0:
-				return
-			case cur < total && cur%minReport > 0:
-				return
-			}
+	if hdr := cfg.Header; hdr != nil {
+		hdr.Scanned = time.Now()
+		hdr.Proto = cfg.Proto.String()
+		hdr.Deep = cfg.Deep
+	}
 
-			log.Printf("Processing %d / %d [%.02f%%]", cur, total, percentOf(cur, total))
-		})
+	containers, err := sample(ctx, cfg, cli)
 	if err != nil {
 		return fmt.Errorf("containers: %w", err)
 	}
 
+	switch err = ctx.Err(); {
+	case errors.Is(err, context.Canceled):
+		log.Println("[-] Scan interrupted, graph is partial")
+	case err != nil:
+		log.Println("[-] Scan deadline exceeded, graph is partial")
+	}
+
 	log.Printf("Found %d alive containers", len(containers))
 
+	if names := timedOut(containers); len(names) > 0 {
+		log.Printf("[-] Timed out %d container(s): %s", len(names), strings.Join(names, ", "))
+	}
+
 	if len(containers) < minItems {
 		return fmt.Errorf("%w: containers", ErrNotEnough)
 	}
@@ -93,9 +115,111 @@ func Build(
 
 	log.Printf("Found %d edges", state.BuildEdges())
 
+	if cfg.Flows != nil {
+		flows, err := state.BuildFlows()
+		if err != nil {
+			log.Println("[-] conntrack:", err)
+		}
+
+		log.Printf("Found %d conntrack edges", flows)
+	}
+
+	if nets := state.BuildNetworks(); nets > 0 {
+		log.Printf("Found %d networks", nets)
+	}
+
 	return nil
 }
 
+// sample scans containers cfg.Samples times, connections from all samples are merged.
+// Containers are discovered by first sample, if client is able to resample them.
+func sample(
+	ctx context.Context,
+	cfg *Config,
+	cli ContainerClient,
+) (rv []*Container, err error) {
+	index := make(map[string]*Container)
+
+	for i := range max(cfg.Samples, 1) {
+		if i > 0 {
+			log.Printf("Sample %d / %d in %s", i+1, cfg.Samples, cfg.Interval)
+
+			select {
+			case <-ctx.Done():
+				return rv, nil
+			case <-time.After(cfg.Interval):
+			}
+		}
+
+		at := time.Now()
+
+		cur, err := scan(ctx, cfg, cli, rv, i > 0)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+
+			log.Printf("[-] Sample %d: %v", i+1, err)
+
+			continue
+		}
+
+		for _, c := range cur {
+			c.Stamp(at)
+
+			if acc, ok := index[c.ID]; ok {
+				acc.Merge(c)
+
+				continue
+			}
+
+			index[c.ID] = c
+			rv = append(rv, c)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return rv, nil
+}
+
+func scan(
+	ctx context.Context,
+	cfg *Config,
+	cli ContainerClient,
+	known []*Container,
+	again bool,
+) ([]*Container, error) {
+	if rs, ok := cli.(Resampler); ok && again {
+		return rs.Resample(ctx, cfg.Proto, cfg.Deep, known, reportProgress)
+	}
+
+	return cli.Containers(ctx, cfg.Proto, cfg.Deep, cfg.SkipEnv, reportProgress)
+}
+
+func reportProgress(cur, total int) {
+	switch {
+	case cur == 0:
+		return
+	case cur < total && cur%minReport > 0:
+		return
+	}
+
+	log.Printf("Processing %d / %d [%.02f%%]", cur, total, percentOf(cur, total))
+}
+
+func timedOut(containers []*Container) (rv []string) {
+	for _, c := range containers {
+		if c.TimedOut {
+			rv = append(rv, c.Name)
+		}
+	}
+
+	return rv
+}
+
 func percentOf(a, b int) float64 {
 	const hundred = 100.0
 
@@ -1,19 +1,35 @@
 package graph
 
 import (
+	"cmp"
 	"fmt"
 	"log"
+	"net"
+	"slices"
 	"strconv"
 
 	"github.com/s0rg/decompose/internal/node"
 	"github.com/s0rg/set"
 )
 
+const volumeTmpfs = "tmpfs"
+
+type publishedTarget struct {
+	Container *Container
+	Port      int
+}
+
+// builderState resolves addresses within scanned host first, as hosts reuse same private
+// (i.e. default bridge) addresses, see hostKey.
 type builderState struct {
 	Config     *Config
-	KnownIP    map[string]*Container
+	KnownIP    map[string]*Container       // container addresses, by host
+	KnownPort  map[string]*publishedTarget // published ports, by host
 	Nodes      map[string]*node.Node
 	Remotes    set.Unordered[string]
+	HostIPs    set.Unordered[string] // published ports addresses, by host
+	HostOf     map[string]string     // host name for its external address, to resolve cross-host edges
+	Edges      set.Unordered[string] // built network edges, to skip duplicate flows
 	Containers []*Container
 }
 
@@ -25,13 +41,49 @@ func newBuilderState(
 		Config:     cfg,
 		Containers: cntrs,
 		KnownIP:    make(map[string]*Container, len(cntrs)),
+		KnownPort:  make(map[string]*publishedTarget),
 		Nodes:      make(map[string]*node.Node, len(cntrs)),
 		Remotes:    make(set.Unordered[string]),
+		HostIPs:    make(set.Unordered[string]),
+		HostOf:     make(map[string]string),
+		Edges:      make(set.Unordered[string]),
 	}
 
+	vips := make(map[string]*Container)
+
 	for _, it := range cntrs {
 		for ip := range it.Endpoints {
-			bs.KnownIP[ip] = it
+			bs.KnownIP[hostKey(it.Host, NormalizeAddr(ip))] = it
+		}
+
+		// service vip resolves to its task with lowest name
+		for _, vip := range it.VIPs {
+			vip = hostKey(it.Host, NormalizeAddr(vip))
+
+			if cur, ok := vips[vip]; !ok || it.Name < cur.Name {
+				vips[vip] = it
+			}
+		}
+
+		for _, p := range it.Published {
+			ip := NormalizeAddr(p.IP)
+
+			bs.KnownPort[hostKey(it.Host, publishedKey(ip, p.Proto, p.Port))] = &publishedTarget{
+				Container: it,
+				Port:      p.Target,
+			}
+
+			bs.HostIPs.Add(hostKey(it.Host, ip))
+
+			if !p.Local {
+				bs.HostOf[ip] = it.Host
+			}
+		}
+	}
+
+	for vip, it := range vips {
+		if _, ok := bs.KnownIP[vip]; !ok { // endpoints take precedence
+			bs.KnownIP[vip] = it
 		}
 	}
 
@@ -92,9 +144,32 @@ func (bs *builderState) BuildEdges() (total int) {
 		}
 
 		con.IterOutbounds(func(c *Connection) {
-			if edge, ok := bs.findEdge(con.ID, con.Name, c); ok {
+			if !bs.Config.MatchState(c.State) {
+				return
+			}
+
+			if edge, ok := bs.findEdge(con, c); ok {
 				edge.SrcID = src.ID
 
+				bs.Edges.Add(edgeKey(edge))
+				bs.Config.Builder.AddEdge(edge)
+
+				total++
+			}
+		})
+
+		if !bs.Config.Inbound || bs.Config.OnlyLocal {
+			continue
+		}
+
+		con.IterInbounds(func(c *Connection) {
+			if !bs.Config.MatchState(c.State) {
+				return
+			}
+
+			if edge, ok := bs.inboundEdge(con.Host, c); ok {
+				edge.DstID = src.ID
+
 				bs.Config.Builder.AddEdge(edge)
 
 				total++
@@ -102,6 +177,136 @@ func (bs *builderState) BuildEdges() (total int) {
 		})
 	}
 
+	if bs.Config.Shares {
+		total += bs.buildVolumeEdges()
+	}
+
+	return total
+}
+
+type volumeMount struct {
+	Node *node.Node
+	Dst  string
+}
+
+type volumeShare struct {
+	Src    string
+	Mounts []*volumeMount
+}
+
+// buildVolumeEdges connects every pair of nodes, that mount same volume or bind path on same host.
+func (bs *builderState) buildVolumeEdges() (total int) {
+	var (
+		index  = make(map[string]*volumeShare)
+		shares []*volumeShare
+	)
+
+	for _, con := range bs.Containers {
+		n, ok := bs.Nodes[con.ID]
+		if !ok {
+			continue
+		}
+
+		for _, v := range con.Volumes {
+			if v.Src == "" || v.Type == volumeTmpfs {
+				continue
+			}
+
+			key := con.Host + ":" + v.Src
+
+			vs, ok := index[key]
+			if !ok {
+				vs = &volumeShare{Src: v.Src}
+				index[key] = vs
+				shares = append(shares, vs)
+			}
+
+			vs.Mounts = append(vs.Mounts, &volumeMount{Node: n, Dst: v.Dst})
+		}
+	}
+
+	slices.SortStableFunc(shares, func(a, b *volumeShare) int {
+		return cmp.Compare(a.Src, b.Src)
+	})
+
+	for _, vs := range shares {
+		slices.SortStableFunc(vs.Mounts, func(a, b *volumeMount) int {
+			return cmp.Compare(a.Node.Name, b.Node.Name)
+		})
+
+		for i, a := range vs.Mounts {
+			for _, b := range vs.Mounts[i+1:] {
+				if a.Node.ID == b.Node.ID {
+					continue
+				}
+
+				bs.Config.Builder.AddEdge(&node.Edge{
+					SrcID:   a.Node.ID,
+					SrcName: a.Dst,
+					DstID:   b.Node.ID,
+					DstName: b.Dst,
+					Kind:    node.EdgeSharesVolume,
+					Port: &node.Port{
+						Kind:  node.KindVolume,
+						Value: vs.Src,
+					},
+				})
+
+				total++
+			}
+		}
+	}
+
+	return total
+}
+
+// BuildNetworks groups scanned nodes by their networks, networks from different hosts are distinct.
+func (bs *builderState) BuildNetworks() (total int) {
+	nb, ok := bs.Config.Builder.(NetworkBuilder)
+	if !ok {
+		return 0
+	}
+
+	var (
+		index = make(map[string]*node.Network)
+		order []string
+	)
+
+	for _, con := range bs.Containers {
+		n, ok := bs.Nodes[con.ID]
+		if !ok {
+			continue
+		}
+
+		for _, ep := range con.Networks {
+			key := con.Host + "/" + cmp.Or(ep.Network.ID, ep.Network.Name)
+
+			nn, ok := index[key]
+			if !ok {
+				nn = ep.Network.toNode(con.Host)
+				index[key] = nn
+				order = append(order, key)
+			}
+
+			nn.Members = append(nn.Members, &node.NetworkMember{
+				ID:      n.ID,
+				Name:    n.Name,
+				IP:      ep.IP,
+				Aliases: ep.Aliases,
+			})
+		}
+	}
+
+	slices.SortFunc(order, func(a, b string) int {
+		return cmp.Compare(index[a].Label(), index[b].Label())
+	})
+
+	for _, key := range order {
+		nb.AddNetwork(index[key])
+
+		total++
+	}
+
 	return total
 }
 
@@ -109,13 +314,13 @@ func (bs *builderState) matchContainer(cn *Container) (yes bool) {
 	yes = bs.Config.MatchName(cn.Name)
 
 	cn.IterOutbounds(func(c *Connection) {
-		if c.Proto == UNIX || c.DstIP.IsLoopback() {
+		if c.Proto == UNIX || c.DstIP.IsLoopback() || !bs.Config.MatchState(c.State) {
 			return
 		}
 
 		rip := c.DstIP.String()
 
-		if lc, ok := bs.KnownIP[rip]; ok { // destination known
+		if lc, ok := bs.knownDestination(cn.Host, c); ok { // destination known
 			if !yes && bs.Config.MatchName(lc.Name) {
 				yes = true
 			}
@@ -142,20 +347,85 @@ func (bs *builderState) matchContainer(cn *Container) (yes bool) {
 		})
 	})
 
+	if bs.Config.Inbound && yes && !bs.Config.OnlyLocal {
+		cn.IterInbounds(func(c *Connection) {
+			if !bs.isRemotePeer(cn.Host, c) || !bs.Config.MatchState(c.State) {
+				return
+			}
+
+			rip := c.DstIP.String()
+
+			if _, ok := bs.Nodes[rip]; !ok {
+				bs.Nodes[rip] = node.External(rip)
+				bs.Remotes.Add(rip)
+			}
+		})
+	}
+
 	return yes
 }
 
-func (bs *builderState) findEdge(cid, cname string, conn *Connection) (rv *node.Edge, ok bool) {
+// isRemotePeer reports whether inbound connection came from outside: peers, that are
+// scanned containers, already have outbound edges, and host addresses are just proxies
+// for published ports.
+func (bs *builderState) isRemotePeer(host string, c *Connection) (yes bool) {
+	if c.Proto == UNIX || c.DstIP.IsLoopback() || c.DstIP.IsUnspecified() {
+		return false
+	}
+
+	rip := c.DstIP.String()
+
+	if _, ok := bs.KnownIP[hostKey(host, rip)]; ok {
+		return false
+	}
+
+	if _, ok := bs.HostOf[rip]; ok {
+		return false
+	}
+
+	return !bs.HostIPs.Has(hostKey(host, rip))
+}
+
+func (bs *builderState) inboundEdge(host string, conn *Connection) (rv *node.Edge, ok bool) {
+	if !bs.isRemotePeer(host, conn) {
+		return nil, false
+	}
+
+	rsrc, ok := bs.Nodes[conn.DstIP.String()]
+	if !ok {
+		return nil, false
+	}
+
+	return &node.Edge{
+		SrcID:    rsrc.ID,
+		SrcName:  ProcessRemote,
+		DstName:  conn.Process,
+		State:    conn.State,
+		Samples:  bs.samples(conn),
+		Timespan: conn.Timespan,
+		Port: &node.Port{
+			Kind:   conn.Proto.String(),
+			Value:  strconv.Itoa(conn.SrcPort),
+			Number: conn.SrcPort,
+		},
+	}, true
+}
+
+func (bs *builderState) findEdge(src *Container, conn *Connection) (rv *node.Edge, ok bool) {
 	var (
 		port = &node.Port{
 			Kind: conn.Proto.String(),
 		}
+		cid = src.ID
 		key string
 	)
 
 	rv = &node.Edge{
-		SrcName: conn.Process,
-		Port:    port,
+		SrcName:  conn.Process,
+		State:    conn.State,
+		Samples:  bs.samples(conn),
+		Timespan: conn.Timespan,
+		Port:     port,
 	}
 
 	switch conn.Proto {
@@ -169,8 +439,13 @@ func (bs *builderState) findEdge(cid, cname string, conn *Connection) (rv *node.
 
 		if conn.DstIP.IsLoopback() {
 			rv.DstID = cid
-		} else if ldst, found := bs.KnownIP[key]; found {
+		} else if ldst, found := bs.KnownIP[hostKey(src.Host, key)]; found {
 			rv.DstID = ldst.ID
+		} else if pt, found := bs.knownPort(src.Host, key, conn.Proto, conn.DstPort); found {
+			// published port on scanned host, edge goes to container port
+			rv.DstID = pt.Container.ID
+			port.Value = strconv.Itoa(pt.Port)
+			port.Number = pt.Port
 		}
 	}
 
@@ -194,7 +469,7 @@ func (bs *builderState) findEdge(cid, cname string, conn *Connection) (rv *node.
 		return rv, true
 	}
 
-	if !bs.Config.MatchName(cname) || bs.Config.OnlyLocal {
+	if !bs.Config.MatchName(src.Name) || bs.Config.OnlyLocal {
 		return nil, false
 	}
 
@@ -207,3 +482,138 @@ func (bs *builderState) findEdge(cid, cname string, conn *Connection) (rv *node.
 
 	return nil, false
 }
+
+// samples returns number of samples, connection was seen in, for multi-sample scans only.
+func (bs *builderState) samples(c *Connection) int {
+	if bs.Config.Samples <= 1 {
+		return 0
+	}
+
+	return c.samples()
+}
+
+func (bs *builderState) knownDestination(host string, c *Connection) (rv *Container, ok bool) {
+	key := c.DstIP.String()
+
+	if rv, ok = bs.KnownIP[hostKey(host, key)]; ok {
+		return rv, true
+	}
+
+	if pt, found := bs.knownPort(host, key, c.Proto, c.DstPort); found {
+		return pt.Container, true
+	}
+
+	return nil, false
+}
+
+// knownPort resolves published port, reachable from given host: its own bindings (including
+// bridge gateways) first, then external addresses of other scanned hosts.
+func (bs *builderState) knownPort(host, ip string, proto NetProto, port int) (rv *publishedTarget, ok bool) {
+	key := publishedKey(ip, proto, port)
+
+	if rv, ok = bs.KnownPort[hostKey(host, key)]; ok {
+		return rv, true
+	}
+
+	owner, ok := bs.HostOf[ip]
+	if !ok || owner == host {
+		return nil, false
+	}
+
+	rv, ok = bs.KnownPort[hostKey(owner, key)]
+
+	return rv, ok
+}
+
+// BuildFlows adds edges for tracked flows between scanned containers, that have no edge yet:
+// closed connections and connections, that pass through nat.
+func (bs *builderState) BuildFlows() (total int, err error) {
+	err = bs.Config.Flows.Flows(func(f *Flow) {
+		if edge, ok := bs.flowEdge(f); ok {
+			bs.Config.Builder.AddEdge(edge)
+
+			total++
+		}
+	})
+	if err != nil {
+		return total, fmt.Errorf("flows: %w", err)
+	}
+
+	return total, nil
+}
+
+func (bs *builderState) flowEdge(f *Flow) (rv *node.Edge, ok bool) {
+	if !bs.Config.Proto.Has(f.Proto) {
+		return nil, false
+	}
+
+	src, ok := bs.KnownIP[hostKey(f.Host, f.OrigSrc.String())]
+	if !ok {
+		return nil, false
+	}
+
+	dst, port, ok := bs.flowTarget(f)
+	if !ok || (bs.Config.NoLoops && src.ID == dst.ID) {
+		return nil, false
+	}
+
+	nsrc, ok := bs.Nodes[src.ID]
+	if !ok {
+		return nil, false
+	}
+
+	ndst, ok := bs.Nodes[dst.ID]
+	if !ok {
+		return nil, false
+	}
+
+	// flows are read once, after last scan of source
+	rv = &node.Edge{
+		Timespan: node.Stamp(nsrc.LastSeen),
+		SrcID:    nsrc.ID,
+		SrcName:  ProcessUnknown,
+		DstID:    ndst.ID,
+		Kind:     node.EdgeConntrack,
+		Port: &node.Port{
+			Kind:   f.Proto.String(),
+			Value:  strconv.Itoa(port),
+			Number: port,
+		},
+	}
+
+	if !bs.Edges.Add(edgeKey(rv)) {
+		return nil, false
+	}
+
+	if rv.DstName, ok = ndst.Ports.Get(rv.Port); !ok {
+		rv.DstName = ProcessUnknown
+	}
+
+	return rv, true
+}
+
+// flowTarget resolves flow responder: reply source holds real (after dnat) destination.
+func (bs *builderState) flowTarget(f *Flow) (rv *Container, port int, ok bool) {
+	if rv, ok = bs.KnownIP[hostKey(f.Host, f.ReplySrc.String())]; ok {
+		return rv, f.ReplySport, true
+	}
+
+	if pt, found := bs.knownPort(f.Host, f.OrigDst.String(), f.Proto, f.OrigDport); found {
+		return pt.Container, pt.Port, true
+	}
+
+	return nil, 0, false
+}
+
+func edgeKey(e *node.Edge) string {
+	return e.SrcID + "|" + e.DstID + "|" + e.Port.Label()
+}
+
+// hostKey scopes address (or published port key) to scanned host.
+func hostKey(host, addr string) string {
+	return host + "/" + addr
+}
+
+func publishedKey(ip string, proto NetProto, port int) string {
+	return net.JoinHostPort(ip, strconv.Itoa(port)) + "/" + proto.String()
+}
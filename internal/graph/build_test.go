@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/s0rg/set"
 
@@ -41,8 +46,50 @@ func (tc *testClient) Containers(
 	return tc.Data, nil
 }
 
+// testSamplesClient returns next sample on every call, samples past the end are errors.
+type testSamplesClient struct {
+	Samples [][]*graph.Container
+	calls   int
+}
+
+func (tc *testSamplesClient) Containers(
+	_ context.Context,
+	_ graph.NetProto,
+	_ bool,
+	_ []string,
+	_ func(int, int),
+) ([]*graph.Container, error) {
+	defer func() { tc.calls++ }()
+
+	if tc.calls >= len(tc.Samples) {
+		return nil, errors.New("no more samples")
+	}
+
+	return tc.Samples[tc.calls], nil
+}
+
+// testResampleClient discovers containers once, next samples are served by Resample.
+type testResampleClient struct {
+	testSamplesClient
+	known     int
+	resampled int
+}
+
+func (tc *testResampleClient) Resample(
+	ctx context.Context,
+	proto graph.NetProto,
+	deep bool,
+	known []*graph.Container,
+	progress func(int, int),
+) ([]*graph.Container, error) {
+	tc.known, tc.resampled = len(known), tc.resampled+1
+
+	return tc.Containers(ctx, proto, deep, nil, progress)
+}
+
 type testBuilder struct {
 	Err   error
+	Last  *node.Edge
 	Nodes int
 	Edges int
 }
@@ -57,7 +104,8 @@ func (tb *testBuilder) AddNode(_ *node.Node) error {
 	return nil
 }
 
-func (tb *testBuilder) AddEdge(_ *node.Edge) {
+func (tb *testBuilder) AddEdge(e *node.Edge) {
+	tb.Last = e
 	tb.Edges++
 }
 
@@ -65,6 +113,15 @@ func (tb *testBuilder) Reset() {
 	tb.Nodes, tb.Edges = 0, 0
 }
 
+type testNetBuilder struct {
+	testBuilder
+	Networks []*node.Network
+}
+
+func (tb *testNetBuilder) AddNetwork(n *node.Network) {
+	tb.Networks = append(tb.Networks, n)
+}
+
 type testEnricher struct{}
 
 func (de *testEnricher) Enrich(_ *node.Node) {}
@@ -78,7 +135,7 @@ func TestBuildError(t *testing.T) {
 		Proto: graph.ALL,
 	}
 
-	err := graph.Build(cfg, cli)
+	err := graph.Build(context.Background(), cfg, cli)
 	if err == nil {
 		t.Fatal("err is nil")
 	}
@@ -99,7 +156,7 @@ func TestBuildOneConainer(t *testing.T) {
 		Proto: graph.ALL,
 	}
 
-	if err := graph.Build(cfg, cli); err == nil {
+	if err := graph.Build(context.Background(), cfg, cli); err == nil {
 		t.Fail()
 	}
 }
@@ -171,13 +228,852 @@ func TestBuildSimple(t *testing.T) {
 		Proto:   graph.ALL,
 	}
 
-	if err := graph.Build(cfg, cli); err != nil {
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Nodes != 4 || bld.Edges != 7 {
+		t.Fail()
+	}
+}
+
+func TestBuildPartial(t *testing.T) {
+	t.Parallel()
+
+	cli := testClientWithEnv().(*testClient)
+	cli.Data[1].TimedOut = true
+
+	bld := &testBuilder{}
+	ext := &testEnricher{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    ext,
+		Proto:   graph.ALL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := graph.Build(ctx, cfg, cli); err != nil {
 		t.Fatalf("err = %v", err)
 	}
 
 	if bld.Nodes != 4 || bld.Edges != 7 {
 		t.Fail()
 	}
+
+	if n := cli.Data[1].ToNode(); !n.Container.TimedOut || !n.ToJSON().Container.TimedOut {
+		t.Fail()
+	}
+}
+
+// testSignalClient gets interrupted in the middle of scan, only first containers are scanned.
+type testSignalClient struct {
+	Data []*graph.Container
+}
+
+func (tc *testSignalClient) Containers(
+	ctx context.Context,
+	_ graph.NetProto,
+	_ bool,
+	_ []string,
+	_ func(int, int),
+) ([]*graph.Container, error) {
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = self.Signal(os.Interrupt); err != nil {
+		return nil, err
+	}
+
+	<-ctx.Done()
+
+	return tc.Data[:2], nil
+}
+
+func TestBuildSignal(t *testing.T) {
+	t.Parallel()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cli := &testSignalClient{Data: testClientWithEnv().(*testClient).Data}
+	bld := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	if err := graph.Build(ctx, cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Nodes == 0 || bld.Edges == 0 {
+		t.Fatal("nodes/edges:", bld.Nodes, bld.Edges)
+	}
+}
+
+func TestBuildPublished(t *testing.T) {
+	t.Parallel()
+
+	front := makeContainer("front", "172.20.0.2")
+	front.Host = "host-a"
+	front.AddConnection(&graph.Connection{
+		Process: "app",
+		SrcIP:   net.ParseIP("172.20.0.2"),
+		DstIP:   net.ParseIP("192.168.1.20"),
+		SrcPort: 50000,
+		DstPort: 8080,
+		Proto:   graph.TCP,
+	})
+
+	back := makeContainer("back", "172.30.0.2")
+	back.Host = "host-b"
+	back.Published = []*graph.PublishedPort{
+		{IP: "192.168.1.20", Port: 8080, Target: 80, Proto: graph.TCP},
+	}
+	back.AddConnection(&graph.Connection{
+		Process: "nginx",
+		SrcIP:   net.ParseIP("172.30.0.2"),
+		SrcPort: 80,
+		Proto:   graph.TCP,
+		Listen:  true,
+	})
+
+	bld := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	if err := graph.Build(context.Background(), cfg, &testClient{Data: []*graph.Container{front, back}}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Nodes != 2 || bld.Edges != 1 {
+		t.Fatal("nodes/edges:", bld.Nodes, bld.Edges)
+	}
+
+	if e := bld.Last; e.DstID != "back-id" || e.DstName != "nginx" || e.Port.Value != "80" {
+		t.Fatal("edge:", e.DstID, e.DstName, e.Port.Value)
+	}
+
+	if n := back.ToNode(); n.Container.Host != "host-b" || n.ToJSON().Container.Host != "host-b" {
+		t.Fail()
+	}
+}
+
+func TestBuildHostsOverlap(t *testing.T) {
+	t.Parallel()
+
+	makeHost := func(host string, names ...string) (rv []*graph.Container) {
+		for i, name := range names {
+			c := makeContainer(name, "172.17.0."+strconv.Itoa(i+2))
+			c.ID = host + "-" + c.ID
+			c.Host = host
+
+			rv = append(rv, c)
+		}
+
+		return rv
+	}
+
+	hostA := makeHost("host-a", "app", "db")
+	hostB := makeHost("host-b", "web", "db")
+
+	app := hostA[0]
+	app.AddMany([]*graph.Connection{
+		{Process: "app", SrcIP: net.ParseIP("172.17.0.2"), DstIP: net.ParseIP("172.17.0.3"), SrcPort: 40000, DstPort: 5432, Proto: graph.TCP},
+		{Process: "app", SrcIP: net.ParseIP("172.17.0.2"), DstIP: net.ParseIP("192.168.1.20"), SrcPort: 40001, DstPort: 8080, Proto: graph.TCP},
+	})
+
+	app.AddConnection(&graph.Connection{
+		Process: "app", SrcIP: net.ParseIP("172.17.0.2"), DstIP: net.ParseIP("172.17.0.1"), SrcPort: 40002, DstPort: 8080, Proto: graph.TCP,
+	})
+
+	web := hostB[0]
+	web.Published = []*graph.PublishedPort{
+		{IP: "192.168.1.20", Port: 8080, Target: 80, Proto: graph.TCP},
+		{IP: "172.17.0.1", Port: 8080, Target: 80, Proto: graph.TCP, Local: true},
+	}
+
+	for _, db := range []*graph.Container{hostA[1], hostB[1]} {
+		db.AddConnection(&graph.Connection{Process: "postgres", SrcPort: 5432, Proto: graph.TCP, Listen: true})
+	}
+
+	web.AddConnection(&graph.Connection{Process: "nginx", SrcPort: 80, Proto: graph.TCP, Listen: true})
+
+	bld := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	// host-b containers come last, as they would win for unscoped addresses
+	cli := &testClient{Data: slices.Concat(hostA, hostB)}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	// host-b gateway is not reachable from host-a: edge goes to external node
+	if len(bld.Nodes) != 5 || len(bld.Edges) != 3 {
+		t.Fatal("nodes/edges:", len(bld.Nodes), len(bld.Edges))
+	}
+
+	dst := make(map[string]string)
+
+	for _, e := range bld.Edges {
+		dst[e.Port.Value] = e.DstID
+	}
+
+	if dst["8080"] != "172.17.0.1" {
+		t.Fatal("gateway edge:", dst["8080"])
+	}
+
+	if dst["5432"] != "host-a-db-id" || dst["80"] != "host-b-web-id" {
+		t.Fatal("edges:", dst)
+	}
+}
+
+func TestBuildIPv6(t *testing.T) {
+	t.Parallel()
+
+	app := makeContainer("app", "fd00::2")
+	app.AddMany([]*graph.Connection{
+		{Process: "app", SrcIP: net.ParseIP("fd00::2"), DstIP: net.ParseIP("fd00::3"), SrcPort: 40000, DstPort: 5432, Proto: graph.TCP},
+		{Process: "app", SrcIP: net.ParseIP("::ffff:172.20.0.2"), DstIP: net.ParseIP("::ffff:172.20.0.4"), SrcPort: 40001, DstPort: 6379, Proto: graph.TCP},
+	})
+
+	db := makeContainer("db", "fd00:0:0::3") // v6-only, non-canonical form
+	db.AddConnection(&graph.Connection{Process: "postgres", SrcIP: net.IPv6unspecified, SrcPort: 5432, Proto: graph.TCP, Listen: true})
+
+	cache := makeContainer("cache", "172.20.0.4")
+	cache.AddConnection(&graph.Connection{Process: "redis", SrcIP: net.IPv6unspecified, SrcPort: 6379, Proto: graph.TCP, Listen: true})
+
+	bld := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	if err := graph.Build(context.Background(), cfg, &testClient{Data: []*graph.Container{app, db, cache}}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	// both peers are resolved, no external nodes
+	if bld.Nodes != 3 || bld.Edges != 2 {
+		t.Fatal("nodes/edges:", bld.Nodes, bld.Edges)
+	}
+}
+
+func TestBuildSwarm(t *testing.T) {
+	t.Parallel()
+
+	makeTask := func(slot, ip string) *graph.Container {
+		c := makeContainer("shop_web."+slot+".task"+slot, ip)
+		c.VIPs = []string{"10.0.1.5"}
+		c.Labels = map[string]string{
+			graph.SwarmServiceIDLabel:   "s1",
+			graph.SwarmServiceNameLabel: "shop_web",
+			graph.SwarmTaskNameLabel:    "shop_web." + slot + ".task" + slot,
+			graph.SwarmNamespaceLabel:   "shop",
+		}
+		c.AddConnection(&graph.Connection{Process: "nginx", SrcIP: net.ParseIP(ip), SrcPort: 80, Proto: graph.TCP, Listen: true})
+
+		return c
+	}
+
+	front := makeContainer("front", "10.0.1.2")
+	front.AddConnection(&graph.Connection{
+		Process: "app",
+		SrcIP:   net.ParseIP("10.0.1.2"),
+		DstIP:   net.ParseIP("10.0.1.5"),
+		SrcPort: 40000,
+		DstPort: 80,
+		Proto:   graph.TCP,
+	})
+
+	tb := &testComposeBuilder{}
+	cg := graph.NewComposeGrouper(tb, false)
+	cfg := &graph.Config{
+		Builder: cg,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	cli := &testClient{Data: []*graph.Container{front, makeTask("2", "10.0.1.11"), makeTask("1", "10.0.1.10")}}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if err := cg.Write(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// vip is resolved to service, no external nodes
+	if len(tb.Nodes) != 2 || len(tb.Edges) != 1 {
+		t.Fatal("nodes/edges:", len(tb.Nodes), len(tb.Edges))
+	}
+
+	svc := tb.Nodes[1]
+
+	if svc.Name != "shop_web" || svc.Cluster != "shop" || svc.Container.Replicas != 2 {
+		t.Fatal("service:", svc.Name, svc.Cluster, svc.Container.Replicas)
+	}
+
+	if e := tb.Edges[0]; e.DstID != svc.ID || e.DstName != "nginx" {
+		t.Fatal("edge:", e.DstID, e.DstName)
+	}
+}
+
+type testFlows struct {
+	Err  error
+	Data []*graph.Flow
+}
+
+func (tf *testFlows) Flows(fn func(*graph.Flow)) error {
+	for _, f := range tf.Data {
+		fn(f)
+	}
+
+	return tf.Err
+}
+
+func TestBuildConntrack(t *testing.T) {
+	t.Parallel()
+
+	flow := func(proto graph.NetProto, src, dst string, dport int, rsrc string, rsport int) *graph.Flow {
+		return &graph.Flow{
+			Proto:      proto,
+			OrigSrc:    net.ParseIP(src),
+			OrigDst:    net.ParseIP(dst),
+			OrigSport:  40000,
+			OrigDport:  dport,
+			ReplySrc:   net.ParseIP(rsrc),
+			ReplyDst:   net.ParseIP(src),
+			ReplySport: rsport,
+			ReplyDport: 40000,
+		}
+	}
+
+	front := makeContainer("front", "172.20.0.2")
+	front.AddConnection(&graph.Connection{
+		Process: "app", SrcIP: net.ParseIP("172.20.0.2"), DstIP: net.ParseIP("172.20.0.4"),
+		SrcPort: 40010, DstPort: 6379, Proto: graph.TCP,
+	})
+
+	back := makeContainer("back", "172.30.0.2")
+	back.Published = []*graph.PublishedPort{
+		{IP: "192.168.1.20", Port: 8080, Target: 80, Proto: graph.TCP},
+	}
+	back.AddConnection(&graph.Connection{Process: "nginx", SrcPort: 80, Proto: graph.TCP, Listen: true})
+
+	cache := makeContainer("cache", "172.20.0.4")
+	cache.AddConnection(&graph.Connection{Process: "redis", SrcPort: 6379, Proto: graph.TCP, Listen: true})
+
+	flows := &testFlows{
+		Err: errors.New("partial"),
+		Data: []*graph.Flow{
+			flow(graph.TCP, "172.20.0.2", "192.168.1.20", 8080, "192.168.1.20", 8080), // docker-proxy
+			flow(graph.TCP, "172.20.0.2", "192.168.1.20", 8080, "172.30.0.2", 80),     // dnat, same edge
+			flow(graph.TCP, "172.20.0.2", "172.20.0.4", 6379, "172.20.0.4", 6379),     // socket edge exists
+			flow(graph.TCP, "10.0.0.1", "172.20.0.4", 6379, "172.20.0.4", 6379),       // unknown source
+			flow(graph.TCP, "172.20.0.2", "1.1.1.1", 443, "1.1.1.1", 443),             // unknown target
+			flow(graph.UDP, "172.20.0.2", "172.20.0.4", 53, "172.20.0.4", 53),         // filtered protocol
+		},
+	}
+
+	bld := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Flows:   flows,
+		Proto:   graph.TCP,
+	}
+
+	if err := graph.Build(context.Background(), cfg, &testClient{Data: []*graph.Container{front, back, cache}}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(bld.Edges) != 2 {
+		t.Fatal("edges:", len(bld.Edges))
+	}
+
+	if e := bld.Edges[0]; e.Kind != "" || e.DstID != "cache-id" {
+		t.Fatal("socket edge:", e)
+	}
+
+	if e := bld.Edges[1]; e.Kind != node.EdgeConntrack || e.DstID != "back-id" || e.DstName != "nginx" ||
+		e.SrcName != graph.ProcessUnknown || e.Port.Value != "80" {
+		t.Fatal("flow edge:", e)
+	}
+}
+
+func TestBuildConntrackHosts(t *testing.T) {
+	t.Parallel()
+
+	scanned := func(host, name, ip string) *graph.Container {
+		c := makeContainer(name, ip)
+		c.ID = host + "-" + c.ID
+		c.Host = host
+
+		return c
+	}
+
+	app := scanned("host-a", "app", "172.17.0.2")
+	app.AddConnection(&graph.Connection{Process: "app", SrcPort: 8000, Proto: graph.TCP, Listen: true})
+
+	// same addresses on other host, it comes last, as it would win for unscoped addresses
+	dbA := scanned("host-a", "db", "172.17.0.3")
+	dbB := scanned("host-b", "db", "172.17.0.3")
+	appB := scanned("host-b", "app", "172.17.0.2")
+
+	for _, db := range []*graph.Container{dbA, dbB} {
+		db.AddConnection(&graph.Connection{Process: "postgres", SrcPort: 5432, Proto: graph.TCP, Listen: true})
+	}
+
+	flows := &testFlows{
+		Data: []*graph.Flow{
+			{
+				Host:       "host-a",
+				Proto:      graph.TCP,
+				OrigSrc:    net.ParseIP("172.17.0.2"),
+				OrigDst:    net.ParseIP("172.17.0.3"),
+				OrigSport:  40000,
+				OrigDport:  5432,
+				ReplySrc:   net.ParseIP("172.17.0.3"),
+				ReplyDst:   net.ParseIP("172.17.0.2"),
+				ReplySport: 5432,
+				ReplyDport: 40000,
+			},
+		},
+	}
+
+	bld := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Flows:   flows,
+		Proto:   graph.TCP,
+	}
+
+	if err := graph.Build(context.Background(), cfg, &testClient{Data: []*graph.Container{app, dbA, dbB, appB}}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(bld.Edges) != 1 {
+		t.Fatal("edges:", len(bld.Edges))
+	}
+
+	if e := bld.Edges[0]; e.SrcID != "host-a-app-id" || e.DstID != "host-a-db-id" {
+		t.Fatal("flow edge:", e.SrcID, e.DstID)
+	}
+}
+
+func TestBuildInbound(t *testing.T) {
+	t.Parallel()
+
+	web := makeContainer("web", "172.20.0.2")
+	web.Published = []*graph.PublishedPort{
+		{IP: "172.20.0.1", Port: 8080, Target: 80, Proto: graph.TCP},
+	}
+
+	for _, peer := range []string{
+		"10.1.1.1",   // external client
+		"10.1.1.1",   // same client, other connection
+		"172.20.0.1", // docker-proxy on bridge gateway
+		"172.20.0.3", // known container
+	} {
+		web.AddConnection(&graph.Connection{
+			Process: "nginx",
+			SrcIP:   net.ParseIP("172.20.0.2"),
+			DstIP:   net.ParseIP(peer),
+			SrcPort: 80,
+			DstPort: 40000,
+			Proto:   graph.TCP,
+		})
+	}
+
+	app := makeContainer("app", "172.20.0.3")
+
+	cli := &testClient{Data: []*graph.Container{web, app}}
+	bld := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Nodes != 2 || bld.Edges != 0 {
+		t.Fatal("default nodes/edges:", bld.Nodes, bld.Edges)
+	}
+
+	bld.Reset()
+	cfg.Inbound = true
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Nodes != 3 || bld.Edges != 1 {
+		t.Fatal("inbound nodes/edges:", bld.Nodes, bld.Edges)
+	}
+
+	if e := bld.Last; e.SrcID != "10.1.1.1" || e.SrcName != graph.ProcessRemote ||
+		e.DstID != "web-id" || e.DstName != "nginx" || e.Port.Value != "80" {
+		t.Fatal("edge:", e)
+	}
+
+	bld.Reset()
+	cfg.OnlyLocal = true
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Nodes != 2 || bld.Edges != 0 {
+		t.Fatal("local nodes/edges:", bld.Nodes, bld.Edges)
+	}
+}
+
+func TestBuildStates(t *testing.T) {
+	t.Parallel()
+
+	app := makeContainer("app", "172.20.0.2")
+
+	for _, c := range []*graph.Connection{
+		{DstIP: net.ParseIP("172.20.0.3"), DstPort: 5432, State: node.StateSynSent},
+		{DstIP: net.ParseIP("172.20.0.3"), DstPort: 6379, State: "TIME_WAIT"},
+		{DstIP: net.ParseIP("1.1.1.1"), DstPort: 443, State: "TIME_WAIT"},
+	} {
+		c.Process, c.Proto = "app", graph.TCP
+		c.SrcIP, c.SrcPort = net.ParseIP("172.20.0.2"), 40000+c.DstPort
+
+		app.AddConnection(c)
+	}
+
+	db := makeContainer("db", "172.20.0.3")
+	db.AddConnection(&graph.Connection{Process: "postgres", SrcPort: 5432, Proto: graph.TCP, Listen: true})
+
+	cli := &testClient{Data: []*graph.Container{app, db}}
+	bld := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(bld.Nodes) != 2 || len(bld.Edges) != 0 {
+		t.Fatal("default nodes/edges:", len(bld.Nodes), len(bld.Edges))
+	}
+
+	bld = &testComposeBuilder{}
+	cfg.Builder = bld
+	cfg.States, _ = graph.ParseTCPStates("syn_sent")
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(bld.Nodes) != 2 || len(bld.Edges) != 1 {
+		t.Fatal("states nodes/edges:", len(bld.Nodes), len(bld.Edges))
+	}
+
+	if e := bld.Edges[0]; !e.IsAttempt() || e.DstID != "db-id" || e.DstName != "postgres" {
+		t.Fatal("edge:", e)
+	}
+}
+
+func TestBuildSamples(t *testing.T) {
+	t.Parallel()
+
+	scan := func(ports ...int) []*graph.Container {
+		app := makeContainer("app", "172.20.0.2")
+
+		for _, p := range ports {
+			app.AddConnection(&graph.Connection{
+				Process: "app",
+				SrcIP:   net.ParseIP("172.20.0.2"),
+				DstIP:   net.ParseIP("172.20.0.3"),
+				SrcPort: 40000 + p,
+				DstPort: p,
+				Proto:   graph.TCP,
+			})
+		}
+
+		db := makeContainer("db", "172.20.0.3")
+		db.AddConnection(&graph.Connection{Process: "postgres", SrcPort: 5432, Proto: graph.TCP, Listen: true})
+
+		return []*graph.Container{app, db}
+	}
+
+	cli := &testSamplesClient{Samples: [][]*graph.Container{
+		scan(5432),
+		scan(5432, 6432),
+		scan(5432),
+	}}
+
+	bld := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder:  bld,
+		Meta:     &testEnricher{},
+		Proto:    graph.ALL,
+		Samples:  4, // last one fails
+		Interval: time.Millisecond,
+	}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if cli.calls != 4 || len(bld.Nodes) != 2 || len(bld.Edges) != 2 {
+		t.Fatal("calls/nodes/edges:", cli.calls, len(bld.Nodes), len(bld.Edges))
+	}
+
+	for _, e := range bld.Edges {
+		want := 3
+		if e.Port.Value == "6432" {
+			want = 1
+		}
+
+		if e.Samples != want {
+			t.Fatal("samples:", e.Port.Value, e.Samples)
+		}
+
+		if e.FirstSeen.IsZero() || (want > 1) != e.LastSeen.After(e.FirstSeen) {
+			t.Fatal("timespan:", e.Port.Value, e.FirstSeen, e.LastSeen)
+		}
+	}
+
+	for _, n := range bld.Nodes {
+		if n.FirstSeen.IsZero() || !n.LastSeen.After(n.FirstSeen) {
+			t.Fatal("node timespan:", n.Name, n.FirstSeen, n.LastSeen)
+		}
+	}
+
+	if err := graph.Build(context.Background(), cfg, &testSamplesClient{}); err == nil {
+		t.Fatal("no error for failed first sample")
+	}
+}
+
+func TestBuildResample(t *testing.T) {
+	t.Parallel()
+
+	app := makeContainer("app", "172.20.0.2")
+	db := makeContainer("db", "172.20.0.3")
+	db.AddConnection(&graph.Connection{Process: "postgres", SrcPort: 5432, Proto: graph.TCP, Listen: true})
+	db.AddConnection(&graph.Connection{Process: "postgres", SrcPort: 6432, Proto: graph.TCP, Listen: true})
+
+	// resampled containers carry only connections
+	conns := func(port int) []*graph.Container {
+		rv := &graph.Container{ID: app.ID}
+		rv.AddConnection(&graph.Connection{
+			Process: "app",
+			SrcIP:   net.ParseIP("172.20.0.2"),
+			DstIP:   net.ParseIP("172.20.0.3"),
+			SrcPort: 40000 + port,
+			DstPort: port,
+			Proto:   graph.TCP,
+		})
+
+		return []*graph.Container{rv}
+	}
+
+	cli := &testResampleClient{testSamplesClient: testSamplesClient{Samples: [][]*graph.Container{
+		{app, db},
+		conns(5432),
+		conns(6432),
+	}}}
+
+	bld := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder:  bld,
+		Meta:     &testEnricher{},
+		Proto:    graph.ALL,
+		Samples:  3,
+		Interval: time.Millisecond,
+	}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if cli.resampled != 2 || cli.known != 2 {
+		t.Fatal("resampled/known:", cli.resampled, cli.known)
+	}
+
+	if len(bld.Nodes) != 2 || len(bld.Edges) != 2 {
+		t.Fatal("nodes/edges:", len(bld.Nodes), len(bld.Edges))
+	}
+}
+
+func TestBuildSamplesCancel(t *testing.T) {
+	t.Parallel()
+
+	cli := &testSamplesClient{Samples: [][]*graph.Container{
+		{makeContainer("a", "172.20.0.2"), makeContainer("b", "172.20.0.3")},
+		{makeContainer("a", "172.20.0.2"), makeContainer("b", "172.20.0.3")},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := &graph.Config{
+		Builder:  &testComposeBuilder{},
+		Meta:     &testEnricher{},
+		Proto:    graph.ALL,
+		Samples:  2,
+		Interval: time.Hour,
+	}
+
+	if err := graph.Build(ctx, cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if cli.calls != 1 {
+		t.Fatal("calls:", cli.calls)
+	}
+}
+
+func TestBuildNetworks(t *testing.T) {
+	t.Parallel()
+
+	front := &graph.Network{ID: "n1", Name: "front", Driver: "bridge", Subnets: []string{"172.20.0.0/16"}}
+	back := &graph.Network{ID: "n2", Name: "back", Internal: true}
+
+	proxy := makeContainer("proxy", "172.20.0.2")
+	proxy.Networks = []*graph.NetworkEndpoint{
+		{Network: back, IP: "172.30.0.2"},
+		{Network: front, IP: "172.20.0.2", Aliases: []string{"www"}},
+	}
+
+	app := makeContainer("app", "172.30.0.3")
+	app.Networks = []*graph.NetworkEndpoint{
+		{Network: back, IP: "172.30.0.3"},
+	}
+
+	other := makeContainer("other", "172.20.0.2")
+	other.Host = "host-b"
+	other.Networks = []*graph.NetworkEndpoint{
+		{Network: front, IP: "172.20.0.2"},
+	}
+
+	bld := &testNetBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	cli := &testClient{Data: []*graph.Container{proxy, app, other}}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	labels := make([]string, len(bld.Networks))
+
+	for i, n := range bld.Networks {
+		labels[i] = n.Label()
+	}
+
+	if !slices.Equal(labels, []string{"back", "front", "host-b/front"}) {
+		t.Fatal("networks:", labels)
+	}
+
+	if n := bld.Networks[0]; !n.Internal || len(n.Members) != 2 || n.Members[1].ID != "app-id" {
+		t.Fatal("back:", n)
+	}
+
+	if n := bld.Networks[1]; n.Driver != "bridge" || len(n.Members) != 1 || n.Members[0].Aliases[0] != "www" {
+		t.Fatal("front:", n)
+	}
+
+	if mh := node.MultiHomed(bld.Networks); mh.Len() != 1 || !mh.Has("proxy-id") {
+		t.Fatal("multi-homed:", mh)
+	}
+}
+
+func TestBuildShares(t *testing.T) {
+	t.Parallel()
+
+	a := makeContainer("a", "172.20.0.2")
+	a.Volumes = []*graph.VolumeInfo{
+		{Type: "bind", Src: "/var/run/docker.sock", Dst: "/var/run/docker.sock"},
+		{Type: "volume", Src: "/var/lib/docker/volumes/up/_data", Dst: "/uploads"},
+		{Type: "tmpfs", Dst: "/tmp"},
+	}
+
+	b := makeContainer("b", "172.20.0.3")
+	b.Volumes = []*graph.VolumeInfo{
+		{Type: "bind", Src: "/var/run/docker.sock", Dst: "/docker.sock"},
+		{Type: "tmpfs", Dst: "/tmp"},
+	}
+
+	c := makeContainer("c", "172.20.0.4")
+	c.Volumes = []*graph.VolumeInfo{
+		{Type: "bind", Src: "/var/run/docker.sock", Dst: "/var/run/docker.sock"},
+		{Type: "volume", Src: "/var/lib/docker/volumes/up/_data", Dst: "/data"},
+	}
+
+	other := makeContainer("other", "172.20.0.5")
+	other.Host = "host-b"
+	other.Volumes = []*graph.VolumeInfo{
+		{Type: "bind", Src: "/var/run/docker.sock", Dst: "/var/run/docker.sock"},
+	}
+
+	bld := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	cli := &testClient{Data: []*graph.Container{c, b, a, other}}
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if bld.Edges != 0 {
+		t.Fatal("default edges:", bld.Edges)
+	}
+
+	bld.Reset()
+	cfg.Shares = true
+
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	// uploads: a-c, docker.sock: a-b, a-c, b-c
+	if bld.Edges != 4 {
+		t.Fatal("edges:", bld.Edges)
+	}
+
+	e := bld.Last
+
+	if !e.IsShare() || e.SrcID != "b-id" || e.DstID != "c-id" ||
+		e.SrcName != "/docker.sock" || e.DstName != "/var/run/docker.sock" ||
+		e.Port.Label() != "volume:/var/run/docker.sock" {
+		t.Fatal("edge:", e, e.Port)
+	}
 }
 
 func TestBuildFollow(t *testing.T) {
@@ -196,7 +1092,7 @@ func TestBuildFollow(t *testing.T) {
 		Proto:   graph.ALL,
 	}
 
-	if err := graph.Build(cfg, cli); err != nil {
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
 		t.Fatalf("err = %v", err)
 	}
 
@@ -218,7 +1114,7 @@ func TestBuildLocal(t *testing.T) {
 		OnlyLocal: true,
 	}
 
-	if err := graph.Build(cfg, cli); err != nil {
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
 		t.Fatalf("err = %v", err)
 	}
 
@@ -240,7 +1136,7 @@ func TestBuildNoNodes(t *testing.T) {
 		Proto:     graph.ALL,
 	}
 
-	if err := graph.Build(cfg, cli); err == nil {
+	if err := graph.Build(context.Background(), cfg, cli); err == nil {
 		t.Fail()
 	}
 }
@@ -258,7 +1154,7 @@ func TestBuildNodeError(t *testing.T) {
 		Proto:   graph.ALL,
 	}
 
-	err := graph.Build(cfg, cli)
+	err := graph.Build(context.Background(), cfg, cli)
 	if err == nil {
 		t.Fatal("err is nil")
 	}
@@ -285,7 +1181,7 @@ func TestBuildNoConnections(t *testing.T) {
 		Proto:   graph.ALL,
 	}
 
-	if err := graph.Build(cfg, cli); err != nil {
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
 		t.Fatalf("err = %v", err)
 	}
 }
@@ -323,7 +1219,7 @@ func TestBuildLoops(t *testing.T) {
 		OnlyLocal: true,
 	}
 
-	if err := graph.Build(cfg, cli); err != nil {
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
 		t.Fatalf("err = %v", err)
 	}
 
@@ -335,7 +1231,7 @@ func TestBuildLoops(t *testing.T) {
 
 	cfg.NoLoops = true
 
-	if err := graph.Build(cfg, cli); err != nil {
+	if err := graph.Build(context.Background(), cfg, cli); err != nil {
 		t.Fatalf("err = %v", err)
 	}
 
@@ -0,0 +1,286 @@
+package graph
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+const (
+	ComposeProjectLabel = "com.docker.compose.project"
+	ComposeServiceLabel = "com.docker.compose.service"
+	ComposeNumberLabel  = "com.docker.compose.container-number"
+
+	SwarmServiceIDLabel   = "com.docker.swarm.service.id"
+	SwarmServiceNameLabel = "com.docker.swarm.service.name"
+	SwarmTaskIDLabel      = "com.docker.swarm.task.id"
+	SwarmTaskNameLabel    = "com.docker.swarm.task.name"
+	SwarmNamespaceLabel   = "com.docker.stack.namespace"
+
+	composeName     = "compose"
+	composeIDPrefix = "compose:"
+)
+
+// composeService is a compose service or swarm service, node belongs to.
+type composeService struct {
+	Project string
+	Name    string
+	Replica int
+}
+
+// ComposeGrouper merges replicas of compose services (and tasks of swarm services) into
+// single service nodes, every compose project (or swarm stack) becomes a cluster,
+// traffic between replicas becomes service self-edge, unless loops are removed.
+type ComposeGrouper struct {
+	b       NamedBuilderWriter
+	index   map[string]string       // nodeID -> serviceID
+	groups  map[string][]*node.Node // serviceID -> replicas
+	order   []string                // node and service ids, in order of appearance
+	nodes   map[string]*node.Node   // non-compose nodes
+	edges   []*node.Edge
+	nets    []*node.Network
+	noLoops bool
+}
+
+func NewComposeGrouper(b NamedBuilderWriter, noLoops bool) *ComposeGrouper {
+	return &ComposeGrouper{
+		b:       b,
+		index:   make(map[string]string),
+		groups:  make(map[string][]*node.Node),
+		nodes:   make(map[string]*node.Node),
+		noLoops: noLoops,
+	}
+}
+
+func (g *ComposeGrouper) Name() string {
+	return g.b.Name() + " " + composeName
+}
+
+func (g *ComposeGrouper) AddNode(n *node.Node) error {
+	svc, ok := serviceOf(n)
+	if !ok {
+		g.nodes[n.ID] = n
+		g.order = append(g.order, n.ID)
+
+		return nil
+	}
+
+	id := composeIDPrefix + svc.Name
+
+	if host := n.Container.Host; host != "" {
+		id = composeIDPrefix + host + "/" + svc.Name
+	}
+
+	if _, ok = g.groups[id]; !ok {
+		g.order = append(g.order, id)
+	}
+
+	g.groups[id] = append(g.groups[id], n)
+	g.index[n.ID] = id
+
+	return nil
+}
+
+func (g *ComposeGrouper) AddEdge(e *node.Edge) {
+	g.edges = append(g.edges, e)
+}
+
+func (g *ComposeGrouper) AddNetwork(n *node.Network) {
+	g.nets = append(g.nets, n)
+}
+
+func (g *ComposeGrouper) Write(w io.Writer) (err error) {
+	for _, id := range g.order {
+		n, ok := g.nodes[id]
+		if !ok {
+			n = mergeReplicas(id, g.groups[id])
+		}
+
+		if err = g.b.AddNode(n); err != nil {
+			return fmt.Errorf("compose add-node [%s]: %w", g.b.Name(), err)
+		}
+	}
+
+	seen := make(map[string]*node.Edge)
+	edges := make([]*node.Edge, 0, len(g.edges))
+
+	for _, e := range g.edges {
+		src, dst := g.resolve(e.SrcID), g.resolve(e.DstID)
+
+		if src == dst && g.noLoops {
+			continue
+		}
+
+		key := strings.Join([]string{src, dst, e.SrcName, e.DstName, e.Kind, e.State, e.Port.Label()}, "|")
+		if cur, ok := seen[key]; ok {
+			cur.Merge(e)
+
+			continue
+		}
+
+		edge := *e
+		edge.SrcID, edge.DstID = src, dst
+
+		seen[key] = &edge
+		edges = append(edges, &edge)
+	}
+
+	for _, e := range edges {
+		g.b.AddEdge(e)
+	}
+
+	if nb, ok := g.b.(NetworkBuilder); ok {
+		for _, n := range g.nets {
+			nb.AddNetwork(g.remapMembers(n))
+		}
+	}
+
+	if err = g.b.Write(w); err != nil {
+		return fmt.Errorf("compose write [%s]: %w", g.b.Name(), err)
+	}
+
+	return nil
+}
+
+func (g *ComposeGrouper) resolve(id string) string {
+	if sid, ok := g.index[id]; ok {
+		return sid
+	}
+
+	return id
+}
+
+func (g *ComposeGrouper) remapMembers(n *node.Network) *node.Network {
+	seen := make(set.Unordered[string])
+	members := make([]*node.NetworkMember, 0, len(n.Members))
+
+	for _, m := range n.Members {
+		sid, ok := g.index[m.ID]
+		if !ok {
+			members = append(members, m)
+
+			continue
+		}
+
+		if !seen.Add(sid) {
+			continue
+		}
+
+		members = append(members, &node.NetworkMember{
+			ID:      sid,
+			Name:    g.serviceName(sid),
+			IP:      m.IP,
+			Aliases: m.Aliases,
+		})
+	}
+
+	rv := *n
+	rv.Members = members
+
+	return &rv
+}
+
+func (g *ComposeGrouper) serviceName(id string) string {
+	svc, _ := serviceOf(g.groups[id][0])
+
+	return svc.Name
+}
+
+// serviceOf extracts service from swarm task or compose container labels.
+func serviceOf(n *node.Node) (rv composeService, ok bool) {
+	if n.IsExternal() {
+		return rv, false
+	}
+
+	labels := n.Container.Labels
+
+	if name := labels[SwarmServiceNameLabel]; name != "" {
+		// task name is "<service>.<slot>.<task-id>", global services have node id instead of slot
+		slot, _, _ := strings.Cut(strings.TrimPrefix(labels[SwarmTaskNameLabel], name+"."), ".")
+
+		rv.Project, rv.Name = labels[SwarmNamespaceLabel], name
+		rv.Replica, _ = strconv.Atoi(slot)
+
+		return rv, true
+	}
+
+	project, service := labels[ComposeProjectLabel], labels[ComposeServiceLabel]
+	if project == "" || service == "" {
+		return rv, false
+	}
+
+	rv.Project, rv.Name = project, project+"-"+service
+	rv.Replica, _ = strconv.Atoi(labels[ComposeNumberLabel])
+
+	return rv, true
+}
+
+func replicaNumber(n *node.Node) int {
+	svc, _ := serviceOf(n)
+
+	return svc.Replica
+}
+
+func mergeReplicas(id string, replicas []*node.Node) (rv *node.Node) {
+	slices.SortStableFunc(replicas, func(a, b *node.Node) int {
+		return cmp.Or(
+			cmp.Compare(replicaNumber(a), replicaNumber(b)),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	head := replicas[0]
+	svc, _ := serviceOf(head)
+
+	rv = &node.Node{
+		ID:        id,
+		Name:      svc.Name,
+		Image:     head.Image,
+		Cluster:   svc.Project,
+		Meta:      head.Meta,
+		Volumes:   head.Volumes,
+		Container: head.Container,
+		Ports:     &node.Ports{},
+	}
+
+	rv.Container.Labels = maps.Clone(head.Container.Labels)
+
+	for _, key := range []string{ComposeNumberLabel, SwarmTaskIDLabel, SwarmTaskNameLabel} {
+		delete(rv.Container.Labels, key)
+	}
+
+	rv.Container.Replicas = len(replicas)
+	rv.Container.Exposed = nil
+
+	networks := make(set.Unordered[string])
+
+	for _, r := range replicas {
+		rv.Ports.Join(r.Ports)
+		rv.Container.Exposed = append(rv.Container.Exposed, r.Container.Exposed...)
+		rv.Container.TimedOut = rv.Container.TimedOut || r.Container.TimedOut
+		rv.Extend(r.Timespan)
+
+		set.Load(networks, r.Networks...)
+	}
+
+	rv.Ports.Compact()
+	rv.Networks = slices.Sorted(networks.Iter)
+
+	slices.SortFunc(rv.Container.Exposed, func(a, b *node.Exposed) int {
+		return cmp.Compare(a.Label(), b.Label())
+	})
+
+	rv.Container.Exposed = slices.CompactFunc(rv.Container.Exposed, func(a, b *node.Exposed) bool {
+		return a.Label() == b.Label()
+	})
+
+	return rv
+}
@@ -0,0 +1,228 @@
+package graph_test
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s0rg/decompose/internal/graph"
+	"github.com/s0rg/decompose/internal/node"
+)
+
+type testComposeBuilder struct {
+	Err      error
+	Nodes    []*node.Node
+	Edges    []*node.Edge
+	Networks []*node.Network
+}
+
+func (b *testComposeBuilder) AddNode(n *node.Node) error {
+	b.Nodes = append(b.Nodes, n)
+
+	return b.Err
+}
+
+func (b *testComposeBuilder) AddEdge(e *node.Edge) {
+	b.Edges = append(b.Edges, e)
+}
+
+func (b *testComposeBuilder) AddNetwork(n *node.Network) {
+	b.Networks = append(b.Networks, n)
+}
+
+func (b *testComposeBuilder) Name() string {
+	return "test-compose"
+}
+
+func (b *testComposeBuilder) Write(_ io.Writer) error {
+	return nil
+}
+
+func makeReplica(project, service, num string, ports ...int) *node.Node {
+	name := project + "-" + service + "-" + num
+
+	rv := &node.Node{
+		ID:       name + "-id",
+		Name:     name,
+		Image:    service + "-image",
+		Networks: []string{project + "_default"},
+		Ports:    &node.Ports{},
+		Container: node.Container{
+			Labels: map[string]string{
+				graph.ComposeProjectLabel: project,
+				graph.ComposeServiceLabel: service,
+				graph.ComposeNumberLabel:  num,
+			},
+		},
+	}
+
+	for _, p := range ports {
+		rv.Ports.Add(service, &node.Port{Kind: "tcp", Value: strconv.Itoa(p), Number: p})
+	}
+
+	return rv
+}
+
+func TestComposeGrouper(t *testing.T) {
+	t.Parallel()
+
+	tb := &testComposeBuilder{}
+	cg := graph.NewComposeGrouper(tb, false)
+
+	if !strings.Contains(cg.Name(), tb.Name()) {
+		t.Fail()
+	}
+
+	web2 := makeReplica("shop", "web", "2", 8080, 9090)
+	web1 := makeReplica("shop", "web", "1", 8080)
+	db := makeReplica("shop", "db", "1", 5432)
+	ext := node.External("1.1.1.1")
+	lone := &node.Node{ID: "lone-id", Name: "lone", Ports: &node.Ports{}}
+
+	for _, n := range []*node.Node{web2, ext, web1, db, lone} {
+		if err := cg.AddNode(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	port := &node.Port{Kind: "tcp", Value: "5432", Number: 5432}
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := first.Add(time.Hour)
+
+	cg.AddEdge(&node.Edge{SrcID: web1.ID, DstID: db.ID, Port: port, Timespan: node.Stamp(last), Samples: 1, Seen: 2, Total: 4})
+	cg.AddEdge(&node.Edge{SrcID: web2.ID, DstID: db.ID, Port: port, Timespan: node.Stamp(first), Samples: 3, Seen: 1, Total: 4})
+	cg.AddEdge(&node.Edge{SrcID: web1.ID, DstID: web2.ID, Port: port})
+	cg.AddEdge(&node.Edge{SrcID: db.ID, DstID: ext.ID, Port: port})
+
+	cg.AddNetwork(&node.Network{
+		Name: "shop_default",
+		Members: []*node.NetworkMember{
+			{ID: web1.ID, Name: web1.Name, IP: "172.20.0.2"},
+			{ID: web2.ID, Name: web2.Name, IP: "172.20.0.3"},
+			{ID: db.ID, Name: db.Name, IP: "172.20.0.4"},
+		},
+	})
+
+	if err := cg.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Nodes) != 4 {
+		t.Fatal("nodes:", len(tb.Nodes))
+	}
+
+	web := tb.Nodes[0]
+
+	if web.Name != "shop-web" || web.Cluster != "shop" || web.Container.Replicas != 2 || web.IsExternal() {
+		t.Fatal("web:", web.Name, web.Cluster, web.Container.Replicas)
+	}
+
+	if _, ok := web.Container.Labels[graph.ComposeNumberLabel]; ok {
+		t.Fatal("web: number label")
+	}
+
+	if web.Ports.Len() != 2 || !web.Ports.Has("tcp:8080", "tcp:9090") {
+		t.Fatal("web ports:", web.Ports.Len())
+	}
+
+	if web1.Container.Labels[graph.ComposeNumberLabel] != "1" {
+		t.Fatal("replica labels modified")
+	}
+
+	if tb.Nodes[1] != ext || tb.Nodes[3] != lone || tb.Nodes[2].Name != "shop-db" {
+		t.Fatal("order:", tb.Nodes[1].Name, tb.Nodes[2].Name, tb.Nodes[3].Name)
+	}
+
+	if len(tb.Edges) != 3 {
+		t.Fatal("edges:", len(tb.Edges))
+	}
+
+	e := tb.Edges[0]
+
+	if e.SrcID != web.ID || e.DstID != tb.Nodes[2].ID {
+		t.Fatal("edge:", e.SrcID, e.DstID)
+	}
+
+	// replicas edges counters are merged
+	if e.Samples != 3 || e.Seen != 2 || e.Total != 4 || !e.FirstSeen.Equal(first) || !e.LastSeen.Equal(last) {
+		t.Fatal("counters:", e.Samples, e.Seen, e.Total, e.Timespan)
+	}
+
+	// replica-to-replica traffic
+	if e := tb.Edges[1]; e.SrcID != web.ID || e.DstID != web.ID {
+		t.Fatal("self-edge:", e.SrcID, e.DstID)
+	}
+
+	if len(tb.Networks) != 1 {
+		t.Fatal("networks:", len(tb.Networks))
+	}
+
+	members := tb.Networks[0].Members
+
+	if len(members) != 2 || members[0].ID != web.ID || members[0].Name != "shop-web" || members[0].IP != "172.20.0.2" {
+		t.Fatal("members:", members)
+	}
+}
+
+func TestComposeGrouperHosts(t *testing.T) {
+	t.Parallel()
+
+	tb := &testComposeBuilder{}
+	cg := graph.NewComposeGrouper(tb, false)
+
+	a, b := makeReplica("shop", "web", "1"), makeReplica("shop", "web", "1")
+	a.ID, a.Container.Host = "a", "host-a"
+	b.ID, b.Container.Host = "b", "host-b"
+
+	_ = cg.AddNode(a)
+	_ = cg.AddNode(b)
+
+	if err := cg.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Nodes) != 2 || tb.Nodes[0].ID == tb.Nodes[1].ID {
+		t.Fatal("nodes:", len(tb.Nodes))
+	}
+}
+
+func TestComposeGrouperNoLoops(t *testing.T) {
+	t.Parallel()
+
+	tb := &testComposeBuilder{}
+	cg := graph.NewComposeGrouper(tb, true)
+
+	web1 := makeReplica("shop", "web", "1", 8080)
+	web2 := makeReplica("shop", "web", "2", 8080)
+
+	_ = cg.AddNode(web1)
+	_ = cg.AddNode(web2)
+
+	cg.AddEdge(&node.Edge{SrcID: web1.ID, DstID: web2.ID, Port: &node.Port{Kind: "tcp", Value: "8080", Number: 8080}})
+
+	if err := cg.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Nodes) != 1 || len(tb.Edges) != 0 {
+		t.Fatal("nodes/edges:", len(tb.Nodes), len(tb.Edges))
+	}
+}
+
+func TestComposeGrouperError(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test-err")
+	tb := &testComposeBuilder{Err: testErr}
+	cg := graph.NewComposeGrouper(tb, false)
+
+	_ = cg.AddNode(makeReplica("shop", "web", "1"))
+
+	if err := cg.Write(io.Discard); !errors.Is(err, testErr) {
+		t.Fatal(err)
+	}
+}
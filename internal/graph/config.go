@@ -1,22 +1,48 @@
 package graph
 
-import "github.com/s0rg/set"
+import (
+	"time"
+
+	"github.com/s0rg/decompose/internal/node"
+	"github.com/s0rg/set"
+)
 
 type Config struct {
-	Builder   Builder
-	Meta      Enricher
-	Follow    set.Unordered[string]
-	SkipEnv   []string
-	Proto     NetProto
-	OnlyLocal bool
-	NoLoops   bool
-	Deep      bool
+	Builder       Builder
+	Meta          Enricher
+	Header        *node.Header // provenance of scan, if output records it
+	Flows         FlowSource
+	Follow        set.Unordered[string]
+	States        set.Unordered[string]
+	SkipEnv       []string
+	Samples       int           // number of scans to merge, edges are annotated with samples count if above one
+	Interval      time.Duration // pause between samples
+	Since         time.Time     // nodes and edges, last seen before, are dropped on load
+	Until         time.Time     // nodes and edges, first seen after, are dropped on load
+	MinConfidence float64       // edges, seen in lesser share of loaded snapshots, are dropped
+	Proto         NetProto
+	OnlyLocal     bool
+	NoLoops       bool
+	Deep          bool
+	Inbound       bool
+	Shares        bool
 }
 
 func (c *Config) MatchName(v string) (yes bool) {
 	return c.Follow.Len() == 0 || c.Follow.Has(v)
 }
 
+// MatchState reports whether connection in given tcp state should be kept, established
+// connections (with empty state) always match.
+func (c *Config) MatchState(v string) (yes bool) {
+	return v == "" || c.States.Has(v)
+}
+
+// MatchTime reports whether timespan overlaps [Since, Until] window.
+func (c *Config) MatchTime(t node.Timespan) (yes bool) {
+	return t.Within(c.Since, c.Until)
+}
+
 func (c *Config) MatchProto(v string) (yes bool) {
 	return c.Proto == ALL || v == c.Proto.String()
 }
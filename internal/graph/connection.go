@@ -5,21 +5,58 @@ import (
 	"io"
 	"net"
 	"strconv"
+
+	"github.com/s0rg/decompose/internal/node"
 )
 
 type Connection struct {
+	node.Timespan
 	Process string
 	DstID   string
 	Path    string
+	State   string // transient tcp state, empty for established and listening sockets
 	SrcIP   net.IP
 	DstIP   net.IP
 	Inode   uint64
 	SrcPort int
 	DstPort int
+	Samples int // number of scan samples, connection was seen in, zero means one
 	Proto   NetProto
 	Listen  bool
 }
 
+// NormalizeIP returns IPv4-mapped IPv6 addresses (::ffff:a.b.c.d) in their IPv4 form,
+// so dual-stack sockets match IPv4 endpoints.
+func NormalizeIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+
+	return ip
+}
+
+// NormalizeAddr returns canonical textual form of address, or address itself if it cannot be parsed.
+func NormalizeAddr(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+
+	return NormalizeIP(ip).String()
+}
+
+func (c *Connection) normalize() {
+	if c.Proto == UNIX {
+		return
+	}
+
+	c.SrcIP, c.DstIP = NormalizeIP(c.SrcIP), NormalizeIP(c.DstIP)
+}
+
+func (c *Connection) samples() int {
+	return max(c.Samples, 1)
+}
+
 func (c *Connection) IsListener() bool {
 	return c.Listen
 }
@@ -59,3 +96,15 @@ func (c *Connection) UniqID() (id uint64, ok bool) {
 
 	return h.Sum64(), true
 }
+
+// peerID identifies inbound connection by remote peer and local port.
+func (c *Connection) peerID() (id uint64, ok bool) {
+	if c.Proto == UNIX || c.IsListener() || !c.IsInbound() {
+		return
+	}
+
+	h := fnv.New64a()
+	_, _ = io.WriteString(h, c.Process+c.DstIP.String()+c.Proto.String()+strconv.Itoa(c.SrcPort))
+
+	return h.Sum64(), true
+}
@@ -1,6 +1,7 @@
 package graph_test
 
 import (
+	"net"
 	"testing"
 
 	"github.com/s0rg/decompose/internal/graph"
@@ -68,3 +69,42 @@ func TestConnectionUNIX(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestNormalizeAddr(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		In, Want string
+	}{
+		{In: "172.20.0.2", Want: "172.20.0.2"},
+		{In: "::ffff:172.20.0.2", Want: "172.20.0.2"},
+		{In: "fd00:0:0::2", Want: "fd00::2"},
+		{In: "FD00::2", Want: "fd00::2"},
+		{In: "not-an-ip", Want: "not-an-ip"},
+		{In: "", Want: ""},
+	} {
+		if got := graph.NormalizeAddr(tc.In); got != tc.Want {
+			t.Errorf("%q: got %q want %q", tc.In, got, tc.Want)
+		}
+	}
+}
+
+func TestConnectionNormalize(t *testing.T) {
+	t.Parallel()
+
+	con := graph.Container{}
+
+	con.AddConnection(&graph.Connection{
+		Proto:   graph.TCP,
+		SrcIP:   net.ParseIP("::ffff:172.20.0.2"),
+		DstIP:   net.ParseIP("::ffff:172.20.0.3"),
+		SrcPort: 40000,
+		DstPort: 80,
+	})
+
+	con.IterOutbounds(func(c *graph.Connection) {
+		if len(c.SrcIP) != net.IPv4len || len(c.DstIP) != net.IPv4len {
+			t.Fail()
+		}
+	})
+}
@@ -9,9 +9,11 @@ import (
 
 type ConnGroup struct {
 	listenSeen set.Unordered[uint64]
-	connSeen   set.Unordered[uint64]
+	connSeen   map[uint64]*Connection
+	inSeen     map[uint64]*Connection
 	listen     []*Connection
 	connected  []*Connection
+	inbound    []*Connection
 }
 
 func (cg *ConnGroup) Len() (rv int) {
@@ -42,14 +44,90 @@ func (cg *ConnGroup) AddOutbound(c *Connection) {
 	}
 
 	if cg.connSeen == nil {
-		cg.connSeen = make(set.Unordered[uint64])
+		cg.connSeen = make(map[uint64]*Connection)
 	}
 
-	if !cg.connSeen.Add(cid) {
+	if addUniq(cg.connSeen, cid, c) {
+		cg.connected = append(cg.connected, c)
+	}
+}
+
+// AddInbound stores connection from remote peer, inbounds are not counted in Len.
+func (cg *ConnGroup) AddInbound(c *Connection) {
+	cid, ok := c.peerID()
+	if !ok {
 		return
 	}
 
-	cg.connected = append(cg.connected, c)
+	if cg.inSeen == nil {
+		cg.inSeen = make(map[uint64]*Connection)
+	}
+
+	if addUniq(cg.inSeen, cid, c) {
+		cg.inbound = append(cg.inbound, c)
+	}
+}
+
+// Merge adds connections from another scan sample, known connections have their samples counted.
+func (cg *ConnGroup) Merge(o *ConnGroup) {
+	for _, c := range o.listen {
+		cg.AddListener(c)
+	}
+
+	for _, c := range o.connected {
+		if cid, ok := c.UniqID(); ok && mergeSample(cg.connSeen, cid, c) {
+			continue
+		}
+
+		cg.AddOutbound(c)
+	}
+
+	for _, c := range o.inbound {
+		if cid, ok := c.peerID(); ok && mergeSample(cg.inSeen, cid, c) {
+			continue
+		}
+
+		cg.AddInbound(c)
+	}
+}
+
+// mergeSample reports whether connection is already known, and adds its samples to known one.
+func mergeSample(seen map[uint64]*Connection, cid uint64, c *Connection) (known bool) {
+	cur, ok := seen[cid]
+	if !ok {
+		return false
+	}
+
+	samples := cur.samples() + c.samples()
+	span := cur.Timespan
+
+	span.Extend(c.Timespan)
+
+	if cur.State != "" && c.State == "" {
+		*cur = *c
+	}
+
+	cur.Samples = samples
+	cur.Timespan = span
+
+	return true
+}
+
+// addUniq reports whether connection is new, for known ones established connection
+// takes place of transient (i.e. TIME_WAIT) one.
+func addUniq(seen map[uint64]*Connection, cid uint64, c *Connection) (added bool) {
+	cur, ok := seen[cid]
+	if !ok {
+		seen[cid] = c
+
+		return true
+	}
+
+	if cur.State != "" && c.State == "" {
+		*cur = *c
+	}
+
+	return false
 }
 
 func (cg *ConnGroup) IterOutbounds(it func(*Connection)) {
@@ -64,9 +142,16 @@ func (cg *ConnGroup) IterListeners(it func(*Connection)) {
 	}
 }
 
+func (cg *ConnGroup) IterInbounds(it func(*Connection)) {
+	for _, con := range cg.inbound {
+		it(con)
+	}
+}
+
 func (cg *ConnGroup) Sort() {
 	slices.SortFunc(cg.listen, compare)
 	slices.SortFunc(cg.connected, compare)
+	slices.SortFunc(cg.inbound, compare)
 }
 
 func compare(a, b *Connection) int {
@@ -70,6 +70,35 @@ func TestConnGroupOutbounds(t *testing.T) {
 	})
 }
 
+func TestConnGroupStates(t *testing.T) {
+	t.Parallel()
+
+	cg := &graph.ConnGroup{}
+
+	cg.AddOutbound(&graph.Connection{Proto: graph.TCP, SrcPort: 40002, DstPort: 5, State: "TIME_WAIT"})
+	cg.AddOutbound(&graph.Connection{Proto: graph.TCP, SrcPort: 40003, DstPort: 5})
+	cg.AddOutbound(&graph.Connection{Proto: graph.TCP, SrcPort: 40004, DstPort: 5, State: "SYN_SENT"})
+
+	cg.AddInbound(&graph.Connection{Proto: graph.TCP, SrcPort: 5, DstPort: 40000, State: "SYN_RECV"})
+	cg.AddInbound(&graph.Connection{Proto: graph.TCP, SrcPort: 5, DstPort: 40001})
+
+	if cg.Len() != 1 {
+		t.Fatal("len:", cg.Len())
+	}
+
+	cg.IterOutbounds(func(c *graph.Connection) {
+		if c.State != "" || c.SrcPort != 40003 {
+			t.Fatal("outbound:", c.State, c.SrcPort)
+		}
+	})
+
+	cg.IterInbounds(func(c *graph.Connection) {
+		if c.State != "" {
+			t.Fatal("inbound:", c.State)
+		}
+	})
+}
+
 func TestConnGroupSort(t *testing.T) {
 	t.Parallel()
 
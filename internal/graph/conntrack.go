@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	ctSrc   = "src"
+	ctDst   = "dst"
+	ctSport = "sport"
+	ctDport = "dport"
+)
+
+// Flow is a connection tracked by netfilter: original direction (as initiator sees it) and
+// reply direction (as responder sees it, after nat).
+type Flow struct {
+	Host       string // scanned host, which conntrack table flow is read from
+	OrigSrc    net.IP
+	OrigDst    net.IP
+	ReplySrc   net.IP
+	ReplyDst   net.IP
+	OrigSport  int
+	OrigDport  int
+	ReplySport int
+	ReplyDport int
+	Proto      NetProto
+}
+
+// FlowSource yields tracked flows, i.e. from host conntrack table.
+type FlowSource interface {
+	Flows(func(*Flow)) error
+}
+
+// ParseConntrack parses /proc/net/nf_conntrack or `conntrack -L` output, only tcp and
+// udp flows are reported.
+func ParseConntrack(r io.Reader, cb func(*Flow)) (err error) {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+
+	for s.Scan() {
+		if f, ok := parseFlow(s.Text()); ok {
+			cb(f)
+		}
+	}
+
+	if err = s.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+func parseFlow(line string) (f *Flow, ok bool) {
+	f = &Flow{}
+
+	// first tuple is original direction, second - reply
+	var ips, ports [4]string
+
+	nip, nport := 0, 0
+
+	for _, field := range strings.Fields(line) {
+		key, val, found := strings.Cut(field, "=")
+		if !found {
+			if f.Proto == NONE {
+				switch field {
+				case sTCP:
+					f.Proto = TCP
+				case sUDP:
+					f.Proto = UDP
+				}
+			}
+
+			continue
+		}
+
+		switch key {
+		case ctSrc, ctDst:
+			if nip < len(ips) {
+				ips[nip] = val
+				nip++
+			}
+		case ctSport, ctDport:
+			if nport < len(ports) {
+				ports[nport] = val
+				nport++
+			}
+		}
+	}
+
+	if f.Proto == NONE || nip < len(ips) || nport < len(ports) {
+		return nil, false
+	}
+
+	addrs := []*net.IP{&f.OrigSrc, &f.OrigDst, &f.ReplySrc, &f.ReplyDst}
+
+	for i, v := range ips {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, false
+		}
+
+		*addrs[i] = NormalizeIP(ip)
+	}
+
+	nums := []*int{&f.OrigSport, &f.OrigDport, &f.ReplySport, &f.ReplyDport}
+
+	for i, v := range ports {
+		uval, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, false
+		}
+
+		*nums[i] = int(uval)
+	}
+
+	return f, true
+}
@@ -0,0 +1,60 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+func TestParseConntrack(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`ipv4     2 tcp      6 117 TIME_WAIT src=172.20.0.2 dst=172.20.0.3 sport=40000 dport=5432 src=172.20.0.3 dst=172.20.0.2 sport=5432 dport=40000 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 tcp      6 431999 ESTABLISHED src=172.20.0.2 dst=192.168.1.20 sport=40001 dport=8080 src=172.30.0.2 dst=172.20.0.2 sport=80 dport=40001 [ASSURED] mark=0 use=1
+udp      17 28 src=172.20.0.2 dst=8.8.8.8 sport=5353 dport=53 [UNREPLIED] src=8.8.8.8 dst=192.168.1.2 sport=53 dport=5353 mark=0 use=1
+ipv6     10 tcp      6 300 ESTABLISHED src=fd00::2 dst=fd00::3 sport=40002 dport=80 src=fd00::3 dst=fd00::2 sport=80 dport=40002 [ASSURED] mark=0 use=1
+ipv4     2 icmp     1 29 src=172.20.0.2 dst=8.8.8.8 type=8 code=0 id=1 src=8.8.8.8 dst=172.20.0.2 type=0 code=0 id=1 mark=0 use=1
+ipv4     2 tcp      6 10 CLOSE src=bad dst=172.20.0.3 sport=1 dport=2 src=172.20.0.3 dst=172.20.0.2 sport=2 dport=1
+ipv4     2 tcp      6 10 CLOSE src=172.20.0.2 dst=172.20.0.3 sport=x dport=2 src=172.20.0.3 dst=172.20.0.2 sport=2 dport=1
+ipv4     2 tcp      6 10 CLOSE src=172.20.0.2 dst=172.20.0.3 sport=1 dport=2
+conntrack v1.4.6 (conntrack-tools): 4 flow entries have been shown.
+`)
+
+	var flows []*graph.Flow
+
+	if err := graph.ParseConntrack(b, func(f *graph.Flow) {
+		flows = append(flows, f)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 4 {
+		t.Fatal("total:", len(flows))
+	}
+
+	if f := flows[1]; f.Proto != graph.TCP || f.OrigDst.String() != "192.168.1.20" || f.OrigDport != 8080 ||
+		f.ReplySrc.String() != "172.30.0.2" || f.ReplySport != 80 {
+		t.Fatal("nat flow:", f)
+	}
+
+	if f := flows[2]; f.Proto != graph.UDP || f.ReplyDst.String() != "192.168.1.2" {
+		t.Fatal("udp flow:", f)
+	}
+
+	if f := flows[3]; f.OrigSrc.String() != "fd00::2" || f.ReplySrc.String() != "fd00::3" {
+		t.Fatal("ipv6 flow:", f)
+	}
+}
+
+func TestParseConntrackError(t *testing.T) {
+	t.Parallel()
+
+	myErr := errors.New("test-err")
+
+	err := graph.ParseConntrack(&failReader{Err: myErr}, func(*graph.Flow) {})
+	if !errors.Is(err, myErr) {
+		t.Fatal(err)
+	}
+}
@@ -1,8 +1,10 @@
 package graph
 
 import (
+	"cmp"
 	"slices"
 	"strconv"
+	"time"
 
 	"github.com/s0rg/decompose/internal/node"
 )
@@ -19,7 +21,34 @@ type (
 		Dst  string
 	}
 
+	// PublishedPort is a host address, that forwards to container port.
+	PublishedPort struct {
+		IP     string
+		Port   int
+		Target int
+		Proto  NetProto
+		Local  bool // address is reachable from same host only, i.e. bridge gateway
+	}
+
+	// Network is a container runtime network, shared between its endpoints.
+	Network struct {
+		ID       string
+		Name     string
+		Driver   string
+		Subnets  []string
+		Gateways []string
+		Internal bool
+	}
+
+	// NetworkEndpoint is a container attachment to network.
+	NetworkEndpoint struct {
+		Network *Network
+		IP      string
+		Aliases []string
+	}
+
 	Container struct {
+		node.Timespan
 		Endpoints map[string]string
 		Labels    map[string]string
 		conns     map[string]*ConnGroup
@@ -27,8 +56,14 @@ type (
 		ID        string
 		Name      string
 		Image     string
+		Strategy  string
+		Host      string
 		Info      *ContainerInfo
 		Volumes   []*VolumeInfo
+		Published []*PublishedPort
+		Networks  []*NetworkEndpoint
+		VIPs      []string // virtual ips of swarm service, container is a task of
+		TimedOut  bool
 	}
 )
 
@@ -45,6 +80,8 @@ func (c *Container) AddConnection(conn *Connection) {
 		c.conns = make(map[string]*ConnGroup)
 	}
 
+	conn.normalize()
+
 	var seen bool
 
 	grp, seen := c.conns[conn.Process]
@@ -58,7 +95,7 @@ func (c *Container) AddConnection(conn *Connection) {
 	case !conn.IsInbound():
 		grp.AddOutbound(conn)
 	default:
-		return
+		grp.AddInbound(conn)
 	}
 
 	if !seen {
@@ -75,12 +112,56 @@ func (c *Container) AddMany(conns []*Connection) {
 	}
 }
 
+// Merge adds connections from another scan sample of same container.
+func (c *Container) Merge(o *Container) {
+	if c.conns == nil {
+		c.conns = make(map[string]*ConnGroup)
+	}
+
+	for _, k := range o.connOrder {
+		if grp, ok := c.conns[k]; ok {
+			grp.Merge(o.conns[k])
+
+			continue
+		}
+
+		c.conns[k] = o.conns[k]
+		c.connOrder = append(c.connOrder, k)
+	}
+
+	slices.Sort(c.connOrder)
+
+	c.TimedOut = c.TimedOut || o.TimedOut
+	c.Extend(o.Timespan)
+	c.SortConnections()
+}
+
+// Stamp marks container and its connections as seen in scan at given time.
+func (c *Container) Stamp(at time.Time) {
+	span := node.Stamp(at)
+	stamp := func(conn *Connection) {
+		conn.Timespan = span
+	}
+
+	c.Timespan = span
+
+	c.IterListeners(stamp)
+	c.IterOutbounds(stamp)
+	c.IterInbounds(stamp)
+}
+
 func (c *Container) IterOutbounds(it func(*Connection)) {
 	for _, k := range c.connOrder {
 		c.conns[k].IterOutbounds(it)
 	}
 }
 
+func (c *Container) IterInbounds(it func(*Connection)) {
+	for _, k := range c.connOrder {
+		c.conns[k].IterInbounds(it)
+	}
+}
+
 func (c *Container) IterListeners(it func(*Connection)) {
 	for _, k := range c.connOrder {
 		c.conns[k].IterListeners(it)
@@ -95,6 +176,7 @@ func (c *Container) SortConnections() {
 
 func (c *Container) ToNode() (rv *node.Node) {
 	rv = &node.Node{
+		Timespan: c.Timespan,
 		ID:       c.ID,
 		Name:     c.Name,
 		Image:    c.Image,
@@ -130,6 +212,10 @@ func (c *Container) ToNode() (rv *node.Node) {
 	}
 
 	rv.Container.Labels = c.Labels
+	rv.Container.TimedOut = c.TimedOut
+	rv.Container.Strategy = c.Strategy
+	rv.Container.Host = c.Host
+	rv.Container.Exposed = c.exposed()
 
 	if c.Info != nil {
 		rv.Container.Cmd = c.Info.Cmd
@@ -138,3 +224,37 @@ func (c *Container) ToNode() (rv *node.Node) {
 
 	return rv
 }
+
+// exposed returns unique host-published ports, as same binding may be expanded to several host addresses.
+func (c *Container) exposed() (rv []*node.Exposed) {
+	for _, p := range c.Published {
+		rv = append(rv, &node.Exposed{
+			Kind:   p.Proto.String(),
+			Port:   p.Port,
+			Target: p.Target,
+		})
+	}
+
+	slices.SortFunc(rv, func(a, b *node.Exposed) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Port, b.Port),
+			cmp.Compare(a.Target, b.Target),
+		)
+	})
+
+	return slices.CompactFunc(rv, func(a, b *node.Exposed) bool {
+		return *a == *b
+	})
+}
+
+func (n *Network) toNode(host string) *node.Network {
+	return &node.Network{
+		Name:     n.Name,
+		Driver:   n.Driver,
+		Host:     host,
+		Subnets:  n.Subnets,
+		Gateways: n.Gateways,
+		Internal: n.Internal,
+	}
+}
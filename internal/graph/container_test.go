@@ -1,6 +1,7 @@
 package graph_test
 
 import (
+	"net"
 	"testing"
 
 	"github.com/s0rg/decompose/internal/graph"
@@ -106,3 +107,24 @@ func TestContainerCount(t *testing.T) {
 		}
 	}
 }
+
+func TestContainerInbounds(t *testing.T) {
+	t.Parallel()
+
+	c := &graph.Container{}
+	c.AddMany([]*graph.Connection{
+		{SrcPort: 1, DstPort: 2, DstIP: net.ParseIP("10.0.0.1")},
+		{SrcPort: 1, DstPort: 3, DstIP: net.ParseIP("10.0.0.1")},
+		{SrcPort: 1, DstPort: 2, DstIP: net.ParseIP("10.0.0.2")},
+	})
+
+	var res int
+
+	c.IterInbounds(func(_ *graph.Connection) {
+		res++
+	})
+
+	if res != 2 || c.ConnectionsCount() != 0 {
+		t.Fatal(res, c.ConnectionsCount())
+	}
+}
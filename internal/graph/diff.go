@@ -0,0 +1,226 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+// Diff compares two streams: baseline and current one, and builds merged graph, where
+// added and removed nodes, edges and listen ports are marked.
+type Diff struct {
+	cfg  *Config
+	base *diffSide
+	cur  *diffSide
+}
+
+// diffSide collects graph of one stream, as its loader builds it.
+type diffSide struct {
+	ldr      *Loader
+	nodes    map[string]*node.Node
+	edges    map[string][]*node.Edge
+	networks []*node.Network
+}
+
+func NewDiff(cfg *Config) *Diff {
+	return &Diff{
+		cfg:  cfg,
+		base: newDiffSide(cfg),
+		cur:  newDiffSide(cfg),
+	}
+}
+
+func newDiffSide(cfg *Config) (rv *diffSide) {
+	rv = &diffSide{
+		nodes: make(map[string]*node.Node),
+		edges: make(map[string][]*node.Edge),
+	}
+
+	side := *cfg
+	side.Builder = rv
+
+	rv.ldr = NewLoader(&side)
+
+	return rv
+}
+
+func (s *diffSide) AddNode(n *node.Node) error {
+	s.nodes[n.ID] = n
+
+	return nil
+}
+
+func (s *diffSide) AddEdge(e *node.Edge) {
+	key := diffKey(e)
+
+	s.edges[key] = append(s.edges[key], e)
+}
+
+func (s *diffSide) AddNetwork(n *node.Network) {
+	s.networks = append(s.networks, n)
+}
+
+// Baseline loads stream to compare with.
+func (d *Diff) Baseline(r io.Reader) error {
+	return d.base.ldr.FromReader(r)
+}
+
+// Current loads stream to compare.
+func (d *Diff) Current(r io.Reader) error {
+	return d.cur.ldr.FromReader(r)
+}
+
+// Build writes merged graph to configured builder and returns report of changes.
+func (d *Diff) Build() (rv *DiffReport, err error) {
+	for _, side := range []*diffSide{d.base, d.cur} {
+		if err = side.ldr.Build(); err != nil {
+			return nil, fmt.Errorf("load: %w", err)
+		}
+	}
+
+	rv = newDiffReport()
+
+	nodes, err := d.buildNodes(rv)
+	if err != nil {
+		return nil, err
+	}
+
+	d.buildEdges(rv, nodes)
+
+	if nb, ok := d.cfg.Builder.(NetworkBuilder); ok {
+		for _, n := range d.cur.networks {
+			nb.AddNetwork(n)
+		}
+	}
+
+	rv.sort()
+
+	return rv, nil
+}
+
+func (d *Diff) buildNodes(rep *DiffReport) (rv map[string]*node.Node, err error) {
+	ids := slices.Sorted(maps.Keys(d.base.nodes))
+
+	for id := range d.cur.nodes {
+		if _, ok := d.base.nodes[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.Sort(ids)
+
+	rv = make(map[string]*node.Node, len(ids))
+
+	for _, id := range ids {
+		base, inBase := d.base.nodes[id]
+		cur, inCur := d.cur.nodes[id]
+
+		var n *node.Node
+
+		switch {
+		case !inBase:
+			n = markNode(cur, node.ChangeAdded)
+			rep.AddedNodes = append(rep.AddedNodes, n.Name)
+		case !inCur:
+			n = markNode(base, node.ChangeRemoved)
+			rep.RemovedNodes = append(rep.RemovedNodes, n.Name)
+		default:
+			n = cur
+			n.Ports = diffPorts(rep, n.Name, base.Ports, cur.Ports)
+		}
+
+		if err = d.cfg.Builder.AddNode(n); err != nil {
+			return nil, fmt.Errorf("node %s: %w", n.Name, err)
+		}
+
+		rv[id] = n
+	}
+
+	return rv, nil
+}
+
+func (d *Diff) buildEdges(rep *DiffReport, nodes map[string]*node.Node) {
+	keys := slices.Sorted(maps.Keys(d.base.edges))
+
+	for key := range d.cur.edges {
+		if _, ok := d.base.edges[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		base, inBase := d.base.edges[key]
+		cur, inCur := d.cur.edges[key]
+
+		edges, change := cur, ""
+
+		switch {
+		case !inBase:
+			change = node.ChangeAdded
+			rep.AddedEdges = append(rep.AddedEdges, newDiffEdge(cur[0], nodes))
+		case !inCur:
+			edges, change = base, node.ChangeRemoved
+			rep.RemovedEdges = append(rep.RemovedEdges, newDiffEdge(base[0], nodes))
+		}
+
+		for _, e := range edges {
+			e.Change = change
+
+			d.cfg.Builder.AddEdge(e)
+		}
+	}
+}
+
+// diffKey identifies edge between streams: its nodes, port, kind and state.
+func diffKey(e *node.Edge) string {
+	return strings.Join([]string{e.SrcID, e.DstID, e.Kind, e.State, e.Port.Label()}, "|")
+}
+
+func markNode(n *node.Node, change string) *node.Node {
+	n.Change = change
+
+	n.Ports.Iter(func(_ string, ports []*node.Port) {
+		for _, p := range ports {
+			p.Change = change
+		}
+	})
+
+	return n
+}
+
+// diffPorts merges listen ports of node from both streams, ports are matched by their labels.
+func diffPorts(rep *DiffReport, name string, base, cur *node.Ports) (rv *node.Ports) {
+	rv = &node.Ports{}
+
+	cur.Iter(func(process string, ports []*node.Port) {
+		for _, p := range ports {
+			if _, ok := base.Get(p); !ok {
+				p.Change = node.ChangeAdded
+				rep.AddedPorts = append(rep.AddedPorts, &DiffPort{Node: name, Process: process, Port: p.Label()})
+			}
+
+			rv.Add(process, p)
+		}
+	})
+
+	base.Iter(func(process string, ports []*node.Port) {
+		for _, p := range ports {
+			if _, ok := cur.Get(p); !ok {
+				p.Change = node.ChangeRemoved
+				rep.RemovedPorts = append(rep.RemovedPorts, &DiffPort{Node: name, Process: process, Port: p.Label()})
+
+				rv.Add(process, p)
+			}
+		}
+	})
+
+	rv.Compact()
+
+	return rv
+}
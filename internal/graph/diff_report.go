@@ -0,0 +1,184 @@
+package graph
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+// DiffReport lists changes between two streams.
+type DiffReport struct {
+	AddedNodes   []string    `json:"added_nodes"`
+	RemovedNodes []string    `json:"removed_nodes"`
+	AddedEdges   []*DiffEdge `json:"added_edges"`
+	RemovedEdges []*DiffEdge `json:"removed_edges"`
+	AddedPorts   []*DiffPort `json:"added_ports"`
+	RemovedPorts []*DiffPort `json:"removed_ports"`
+}
+
+// DiffEdge is a changed connection between nodes.
+type DiffEdge struct {
+	Src   string `json:"src"`
+	Dst   string `json:"dst"`
+	Port  string `json:"port"`
+	Kind  string `json:"kind,omitempty"`
+	State string `json:"state,omitempty"`
+}
+
+// DiffPort is a changed listen port of node, that is present in both streams.
+type DiffPort struct {
+	Node    string `json:"node"`
+	Process string `json:"process"`
+	Port    string `json:"port"`
+}
+
+func newDiffReport() *DiffReport {
+	return &DiffReport{
+		AddedNodes:   []string{},
+		RemovedNodes: []string{},
+		AddedEdges:   []*DiffEdge{},
+		RemovedEdges: []*DiffEdge{},
+		AddedPorts:   []*DiffPort{},
+		RemovedPorts: []*DiffPort{},
+	}
+}
+
+func newDiffEdge(e *node.Edge, nodes map[string]*node.Node) *DiffEdge {
+	name := func(id string) string {
+		if n, ok := nodes[id]; ok {
+			return n.Name
+		}
+
+		return id
+	}
+
+	return &DiffEdge{
+		Src:   name(e.SrcID),
+		Dst:   name(e.DstID),
+		Port:  e.Port.Label(),
+		Kind:  e.Kind,
+		State: e.State,
+	}
+}
+
+func (e *DiffEdge) String() (rv string) {
+	rv = e.Src + " -> " + e.Dst + ": " + e.Port
+
+	for _, s := range []string{e.Kind, e.State} {
+		if s != "" {
+			rv += " (" + s + ")"
+		}
+	}
+
+	return rv
+}
+
+func (p *DiffPort) String() string {
+	return p.Node + " [" + p.Process + "]: " + p.Port
+}
+
+// Empty reports whether streams are same.
+func (r *DiffReport) Empty() (yes bool) {
+	return len(r.AddedNodes)+len(r.RemovedNodes)+
+		len(r.AddedEdges)+len(r.RemovedEdges)+
+		len(r.AddedPorts)+len(r.RemovedPorts) == 0
+}
+
+// Summary returns one-line changes counts.
+func (r *DiffReport) Summary() string {
+	return fmt.Sprintf("nodes +%d -%d, edges +%d -%d, listen ports +%d -%d",
+		len(r.AddedNodes), len(r.RemovedNodes),
+		len(r.AddedEdges), len(r.RemovedEdges),
+		len(r.AddedPorts), len(r.RemovedPorts),
+	)
+}
+
+func (r *DiffReport) WriteJSON(w io.Writer) error {
+	jw := json.NewEncoder(w)
+	jw.SetIndent("", "  ")
+
+	if err := jw.Encode(r); err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DiffReport) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintln(w, "# Changes")
+	fmt.Fprintln(w, "")
+
+	if r.Empty() {
+		fmt.Fprintln(w, "No changes.")
+
+		return nil
+	}
+
+	fmt.Fprintln(w, "| | added | removed |")
+	fmt.Fprintln(w, "| --- | ---: | ---: |")
+	fmt.Fprintf(w, "| nodes | %d | %d |\n", len(r.AddedNodes), len(r.RemovedNodes))
+	fmt.Fprintf(w, "| edges | %d | %d |\n", len(r.AddedEdges), len(r.RemovedEdges))
+	fmt.Fprintf(w, "| listen ports | %d | %d |\n", len(r.AddedPorts), len(r.RemovedPorts))
+
+	writeSection(w, "Nodes", r.AddedNodes, r.RemovedNodes)
+	writeSection(w, "Edges", stringsOf(r.AddedEdges), stringsOf(r.RemovedEdges))
+	writeSection(w, "Listen ports", stringsOf(r.AddedPorts), stringsOf(r.RemovedPorts))
+
+	return nil
+}
+
+func (r *DiffReport) sort() {
+	slices.Sort(r.AddedNodes)
+	slices.Sort(r.RemovedNodes)
+
+	for _, edges := range [][]*DiffEdge{r.AddedEdges, r.RemovedEdges} {
+		slices.SortStableFunc(edges, func(a, b *DiffEdge) int {
+			return cmp.Or(
+				cmp.Compare(a.Src, b.Src),
+				cmp.Compare(a.Dst, b.Dst),
+				cmp.Compare(a.Port, b.Port),
+			)
+		})
+	}
+
+	for _, ports := range [][]*DiffPort{r.AddedPorts, r.RemovedPorts} {
+		slices.SortStableFunc(ports, func(a, b *DiffPort) int {
+			return cmp.Or(
+				cmp.Compare(a.Node, b.Node),
+				cmp.Compare(a.Port, b.Port),
+			)
+		})
+	}
+}
+
+func writeSection(w io.Writer, title string, added, removed []string) {
+	if len(added)+len(removed) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "##", title)
+	fmt.Fprintln(w, "")
+
+	for _, s := range added {
+		fmt.Fprintf(w, "- added: `%s`\n", s)
+	}
+
+	for _, s := range removed {
+		fmt.Fprintf(w, "- removed: `%s`\n", s)
+	}
+}
+
+func stringsOf[T fmt.Stringer](items []T) (rv []string) {
+	rv = make([]string, len(items))
+
+	for i, v := range items {
+		rv[i] = v.String()
+	}
+
+	return rv
+}
@@ -0,0 +1,186 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/graph"
+	"github.com/s0rg/decompose/internal/node"
+)
+
+const (
+	diffBase = `{"name": "app", "listen": {"app": [
+    {"kind": "tcp", "value": "80"},
+    {"kind": "tcp", "value": "81"}
+  ]}, "connected": {"db": [{"src": "app", "dst": "pg", "port": {"kind": "tcp", "value": "5432"}}]}}
+{"name": "db", "listen": {"pg": [{"kind": "tcp", "value": "5432"}]}, "connected": {}}`
+	diffCur = `{"name": "app", "listen": {"app": [
+    {"kind": "tcp", "value": "80"},
+    {"kind": "tcp", "value": "82"}
+  ]}, "connected": {"cache": [{"src": "app", "dst": "redis", "port": {"kind": "tcp", "value": "6379"}}]}}
+{"name": "cache", "listen": {"redis": [{"kind": "tcp", "value": "6379"}]}, "connected": {}}`
+)
+
+func makeDiff(t *testing.T, bld graph.Builder, base, cur string) *graph.DiffReport {
+	t.Helper()
+
+	d := graph.NewDiff(&graph.Config{
+		Builder: bld,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	})
+
+	if err := d.Baseline(bytes.NewBufferString(base)); err != nil {
+		t.Fatal("baseline:", err)
+	}
+
+	if err := d.Current(bytes.NewBufferString(cur)); err != nil {
+		t.Fatal("current:", err)
+	}
+
+	rep, err := d.Build()
+	if err != nil {
+		t.Fatal("build:", err)
+	}
+
+	return rep
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	bld := &testComposeBuilder{}
+	rep := makeDiff(t, bld, diffBase, diffCur)
+
+	if rep.Empty() {
+		t.Fatal("empty")
+	}
+
+	if len(bld.Nodes) != 3 || len(bld.Edges) != 2 {
+		t.Fatal("nodes/edges:", len(bld.Nodes), len(bld.Edges))
+	}
+
+	changes := make(map[string]string)
+
+	for _, n := range bld.Nodes {
+		changes[n.Name] = n.Change
+	}
+
+	if changes["app"] != "" || changes["cache"] != node.ChangeAdded || changes["db"] != node.ChangeRemoved {
+		t.Fatal("node changes:", changes)
+	}
+
+	app := bld.Nodes[0]
+
+	ports := make(map[string]string)
+
+	app.Ports.Iter(func(_ string, pl []*node.Port) {
+		for _, p := range pl {
+			ports[p.Label()] = p.Change
+		}
+	})
+
+	if len(ports) != 3 || ports["tcp:80"] != "" ||
+		ports["tcp:82"] != node.ChangeAdded || ports["tcp:81"] != node.ChangeRemoved {
+		t.Fatal("port changes:", ports)
+	}
+
+	for _, e := range bld.Edges {
+		want := node.ChangeRemoved
+		if e.Port.Value == "6379" {
+			want = node.ChangeAdded
+		}
+
+		if e.Change != want {
+			t.Fatal("edge change:", e.Port.Label(), e.Change)
+		}
+	}
+
+	if rep.Summary() != "nodes +1 -1, edges +1 -1, listen ports +1 -1" {
+		t.Fatal("summary:", rep.Summary())
+	}
+
+	if e := rep.AddedEdges[0]; e.String() != "app -> cache: tcp:6379" {
+		t.Fatal("added edge:", e)
+	}
+
+	if p := rep.RemovedPorts[0]; p.String() != "app [app]: tcp:81" {
+		t.Fatal("removed port:", p)
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+	t.Parallel()
+
+	bld := &testComposeBuilder{}
+	rep := makeDiff(t, bld, diffBase, diffBase)
+
+	if !rep.Empty() {
+		t.Fatal("not empty:", rep.Summary())
+	}
+
+	if len(bld.Nodes) != 2 || len(bld.Edges) != 1 {
+		t.Fatal("nodes/edges:", len(bld.Nodes), len(bld.Edges))
+	}
+
+	for _, n := range bld.Nodes {
+		if n.Change != "" {
+			t.Fatal("node change:", n.Name, n.Change)
+		}
+	}
+
+	if bld.Edges[0].Change != "" {
+		t.Fatal("edge change:", bld.Edges[0].Change)
+	}
+
+	var buf bytes.Buffer
+
+	if err := rep.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "No changes.") {
+		t.Fatal("markdown:", buf.String())
+	}
+}
+
+func TestDiffReport(t *testing.T) {
+	t.Parallel()
+
+	rep := makeDiff(t, &testComposeBuilder{}, diffBase, diffCur)
+
+	var md bytes.Buffer
+
+	if err := rep.WriteMarkdown(&md); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"| nodes | 1 | 1 |",
+		"- added: `cache`",
+		"- removed: `app -> db: tcp:5432`",
+		"- added: `app [app]: tcp:82`",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Fatal("markdown: no", want)
+		}
+	}
+
+	var js bytes.Buffer
+
+	if err := rep.WriteJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+
+	var got graph.DiffReport
+
+	if err := json.Unmarshal(js.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Summary() != rep.Summary() || got.RemovedNodes[0] != "db" {
+		t.Fatal("json:", got.Summary())
+	}
+}
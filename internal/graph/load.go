@@ -2,31 +2,58 @@ package graph
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 
 	"github.com/s0rg/decompose/internal/node"
 )
 
-const idSuffix = "-id"
+const (
+	idSuffix = "-id"
+
+	// legacySchema is a version of json streams, written before header was introduced, its
+	// records are same as current ones.
+	legacySchema = 1
+)
+
+var ErrSchema = errors.New("unsupported schema")
 
 type Loader struct {
-	nodes map[string]*node.Node
-	edges map[string]map[string][]*node.Connection
-	cfg   *Config
+	nodes    map[string]*node.Node
+	edges    map[string]map[string][]*node.Connection
+	networks map[string]*node.Network
+	seen     map[string]int // edge key -> number of snapshots, edge was seen in
+	cfg      *Config
+	total    int // number of loaded snapshots
+}
+
+// snapshot counts edges of single stream, stream of already merged snapshots weights as their total.
+type snapshot struct {
+	seen   map[string]int
+	weight int
 }
 
 func NewLoader(cfg *Config) *Loader {
 	return &Loader{
-		cfg:   cfg,
-		nodes: make(map[string]*node.Node),
-		edges: make(map[string]map[string][]*node.Connection),
+		cfg:      cfg,
+		nodes:    make(map[string]*node.Node),
+		edges:    make(map[string]map[string][]*node.Connection),
+		networks: make(map[string]*node.Network),
+		seen:     make(map[string]int),
 	}
 }
 
 func (l *Loader) FromReader(r io.Reader) error {
 	jr := json.NewDecoder(r)
 
+	var (
+		snap    = newSnapshot()
+		started bool
+	)
+
 	for jr.More() {
 		var n node.JSON
 
@@ -34,18 +61,58 @@ func (l *Loader) FromReader(r io.Reader) error {
 			return fmt.Errorf("decode: %w", err)
 		}
 
-		l.insert(&n)
+		// header starts stream, concatenated streams have several, each one is a snapshot
+		if n.Header != nil {
+			if v := n.Header.Schema; v < legacySchema || v > node.SchemaVersion {
+				return fmt.Errorf("%w: version %d", ErrSchema, v)
+			}
+
+			if started {
+				l.commit(snap)
+
+				snap = newSnapshot()
+			}
+
+			started = true
+
+			continue
+		}
+
+		started = true
+
+		if n.Network != nil {
+			l.insertNetwork(n.Network)
+
+			continue
+		}
+
+		l.insert(&n, snap)
 	}
 
+	l.commit(snap)
+
 	return nil
 }
 
+// commit adds snapshot counters to loaded ones.
+func (l *Loader) commit(snap *snapshot) {
+	l.total += snap.weight
+
+	for key, seen := range snap.seen {
+		l.seen[key] += seen
+	}
+}
+
 func (l *Loader) Build() error {
 	for id, node := range l.nodes {
 		if l.cfg.OnlyLocal && l.isExternal(id) {
 			continue
 		}
 
+		if !l.cfg.MatchTime(node.Timespan) {
+			continue
+		}
+
 		if err := l.cfg.Builder.AddNode(node); err != nil {
 			return fmt.Errorf("node %s: %w", node.Name, err)
 		}
@@ -56,19 +123,69 @@ func (l *Loader) Build() error {
 			continue
 		}
 
+		if !l.inWindow(srcID) {
+			continue
+		}
+
 		l.connect(srcID, dmap)
 	}
 
+	if nb, ok := l.cfg.Builder.(NetworkBuilder); ok {
+		l.buildNetworks(nb)
+	}
+
 	return nil
 }
 
+// insertNetwork merges network records, as same network may came from several streams.
+func (l *Loader) insertNetwork(n *node.Network) {
+	cur, ok := l.networks[n.Label()]
+	if !ok {
+		l.networks[n.Label()] = n
+
+		return
+	}
+
+	for _, m := range n.Members {
+		if !slices.ContainsFunc(cur.Members, func(v *node.NetworkMember) bool {
+			return v.Name == m.Name
+		}) {
+			cur.Members = append(cur.Members, m)
+		}
+	}
+}
+
+func (l *Loader) buildNetworks(nb NetworkBuilder) {
+	labels := slices.Sorted(maps.Keys(l.networks))
+
+	for _, label := range labels {
+		n := l.networks[label]
+		members := make([]*node.NetworkMember, 0, len(n.Members))
+
+		for _, m := range n.Members {
+			m.ID = m.Name + idSuffix
+
+			if _, ok := l.nodes[m.ID]; ok {
+				members = append(members, m)
+			}
+		}
+
+		n.Members = members
+
+		nb.AddNetwork(n)
+	}
+}
+
 func (l *Loader) createNode(id string, n *node.JSON) (rv *node.Node) {
 	rv = &node.Node{
 		ID:        id,
 		Name:      n.Name,
 		Container: n.Container,
+		Cluster:   n.Cluster,
+		Change:    n.Change,
 		Ports:     &node.Ports{},
 		Networks:  []string{},
+		Timespan:  n.Timespan,
 	}
 
 	if n.Image != nil {
@@ -99,7 +216,9 @@ func (l *Loader) loadNode(n *node.JSON) (id string, rv *node.Node) {
 	}
 
 	nod, ok := l.nodes[id]
-	if !ok {
+	if ok {
+		nod.Extend(n.Timespan)
+	} else {
 		nod = l.createNode(id, n)
 	}
 
@@ -110,7 +229,11 @@ func (l *Loader) loadNode(n *node.JSON) (id string, rv *node.Node) {
 	return id, nod
 }
 
-func (l *Loader) loadEdges(id string, n *node.JSON) (rv map[string][]*node.Connection, skip bool) {
+func (l *Loader) loadEdges(
+	id string,
+	n *node.JSON,
+	snap *snapshot,
+) (rv map[string][]*node.Connection, skip bool) {
 	var ok bool
 
 	if rv, ok = l.edges[id]; !ok {
@@ -124,18 +247,52 @@ func (l *Loader) loadEdges(id string, n *node.JSON) (rv map[string][]*node.Conne
 			continue
 		}
 
-		if !skip || l.cfg.MatchName(k) {
-			rv[k] = append(rv[k], p...)
-			skip = false
+		if skip && !l.cfg.MatchName(k) {
+			continue
+		}
+
+		skip = false
+
+		for _, c := range p {
+			snap.count(edgeID(id, k, c), c)
+
+			if idx := slices.IndexFunc(rv[k], c.Equal); idx >= 0 {
+				rv[k][idx].Extend(c.Timespan)
+			} else {
+				rv[k] = append(rv[k], c)
+			}
 		}
 	}
 
 	return rv, skip
 }
 
-func (l *Loader) insert(n *node.JSON) {
+func newSnapshot() *snapshot {
+	return &snapshot{
+		seen:   make(map[string]int),
+		weight: 1,
+	}
+}
+
+func (s *snapshot) count(key string, c *node.Connection) {
+	seen := 1
+
+	if c.Total > 0 {
+		seen = c.Seen
+		s.weight = max(s.weight, c.Total)
+	}
+
+	s.seen[key] = max(s.seen[key], seen)
+}
+
+// edgeID identifies edge for persistence counting: source, destination and port.
+func edgeID(srcID, dst string, c *node.Connection) string {
+	return srcID + "|" + dst + "|" + c.Port.Label()
+}
+
+func (l *Loader) insert(n *node.JSON, snap *snapshot) {
 	id, nod := l.loadNode(n)
-	cons, skip := l.loadEdges(id, n)
+	cons, skip := l.loadEdges(id, n, snap)
 
 	if skip {
 		return
@@ -149,6 +306,16 @@ func (l *Loader) insert(n *node.JSON) {
 	l.edges[id] = cons
 }
 
+// inWindow reports whether node was seen in configured time window, unknown nodes are always in.
+func (l *Loader) inWindow(id string) (yes bool) {
+	n, ok := l.nodes[id]
+	if !ok {
+		return true
+	}
+
+	return l.cfg.MatchTime(n.Timespan)
+}
+
 func (l *Loader) isExternal(id string) (yes bool) {
 	n, ok := l.nodes[id]
 	if !ok {
@@ -162,7 +329,9 @@ func (l *Loader) connect(
 	srcID string,
 	conns map[string][]*node.Connection,
 ) {
-	for dstID, cl := range conns {
+	for dst, cl := range conns {
+		dstID := dst
+
 		if l.isExternal(dstID) {
 			if l.cfg.OnlyLocal {
 				continue
@@ -171,20 +340,51 @@ func (l *Loader) connect(
 			dstID += idSuffix
 		}
 
+		if !l.inWindow(dstID) {
+			continue
+		}
+
 		for _, c := range cl {
-			if !l.cfg.MatchProto(c.Port.Kind) {
-				continue
+			if edge, ok := l.makeEdge(srcID, dst, c); ok {
+				edge.DstID = dstID
+
+				l.cfg.Builder.AddEdge(edge)
 			}
+		}
+	}
+}
 
-			l.cfg.Builder.AddEdge(&node.Edge{
-				SrcID:   srcID,
-				DstID:   dstID,
-				SrcName: c.Src,
-				DstName: c.Dst,
-				Port:    c.Port,
-			})
+func (l *Loader) makeEdge(srcID, dst string, c *node.Connection) (rv *node.Edge, ok bool) {
+	if c.Kind != node.EdgeSharesVolume && !l.cfg.MatchProto(c.Port.Kind) {
+		return nil, false
+	}
+
+	if !l.cfg.MatchState(c.State) || !l.cfg.MatchTime(c.Timespan) {
+		return nil, false
+	}
+
+	rv = &node.Edge{
+		Timespan: c.Timespan,
+		SrcID:    srcID,
+		SrcName:  c.Src,
+		DstName:  c.Dst,
+		Port:     c.Port,
+		Kind:     c.Kind,
+		State:    c.State,
+		Change:   c.Change,
+		Samples:  c.Samples,
+	}
+
+	// persistence is known only for several snapshots
+	if l.total > 1 {
+		rv.Seen, rv.Total = l.seen[edgeID(srcID, dst, c)], l.total
+
+		if rv.Confidence() < l.cfg.MinConfidence {
+			return nil, false
 		}
 	}
+
+	return rv, true
 }
 
 func loadListeners(
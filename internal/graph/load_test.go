@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/s0rg/set"
 
@@ -587,3 +588,418 @@ func TestLoaderLoops(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestLoaderNetworks(t *testing.T) {
+	t.Parallel()
+
+	bldr := &testNetBuilder{}
+	cfg := &graph.Config{
+		Builder: bldr,
+		Meta:    &testEnricher{},
+		Proto:   graph.ALL,
+	}
+
+	ldr := graph.NewLoader(cfg)
+
+	const nodes = `{"name": "a", "is_external": false, "listen": {}, "connected": {}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}
+{"name": "front", "network": {"name": "front", "driver": "bridge", "members": [{"name": "a", "ip": "1.1.1.1"}]}}`
+
+	const more = `{"name": "front", "network": {"name": "front", "driver": "bridge", "members": [
+    {"name": "a", "ip": "1.1.1.1"},
+    {"name": "b", "ip": "1.1.1.2"},
+    {"name": "gone", "ip": "1.1.1.3"}
+]}}`
+
+	for _, stream := range []string{nodes, more} {
+		if err := ldr.FromReader(bytes.NewBufferString(stream)); err != nil {
+			t.Fatal("load err=", err)
+		}
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Nodes != 2 || len(bldr.Networks) != 1 {
+		t.Fatal("nodes/networks:", bldr.Nodes, len(bldr.Networks))
+	}
+
+	n := bldr.Networks[0]
+
+	if n.Driver != "bridge" || len(n.Members) != 2 || n.Members[1].ID != "b-id" {
+		t.Fatal("network:", n)
+	}
+}
+
+func TestLoaderShares(t *testing.T) {
+	t.Parallel()
+
+	bldr := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bldr,
+		Meta:    &testEnricher{},
+		Proto:   graph.TCP,
+	}
+
+	ldr := graph.NewLoader(cfg)
+
+	buf := bytes.NewBufferString(`{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "/uploads", "dst": "/data", "kind": "shares-volume", "port": {"kind": "volume", "value": "/srv/up"}},
+    {"src": "app", "dst": "app", "port": {"kind": "udp", "value": "53"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`)
+
+	if err := ldr.FromReader(buf); err != nil {
+		t.Fatal("load err=", err)
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Edges != 1 {
+		t.Fatal("edges:", bldr.Edges)
+	}
+
+	if e := bldr.Last; !e.IsShare() || e.Port.Value != "/srv/up" || e.SrcName != "/uploads" {
+		t.Fatal("edge:", e)
+	}
+}
+
+func TestLoaderStates(t *testing.T) {
+	t.Parallel()
+
+	bldr := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bldr,
+		Meta:    &testEnricher{},
+		Proto:   graph.TCP,
+	}
+
+	ldr := graph.NewLoader(cfg)
+
+	buf := bytes.NewBufferString(`{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "state": "SYN_SENT", "port": {"kind": "tcp", "value": "5432"}},
+    {"src": "app", "dst": "db", "state": "TIME_WAIT", "port": {"kind": "tcp", "value": "5433"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`)
+
+	if err := ldr.FromReader(buf); err != nil {
+		t.Fatal("load err=", err)
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Edges != 0 {
+		t.Fatal("default edges:", bldr.Edges)
+	}
+
+	bldr.Reset()
+	cfg.States, _ = graph.ParseTCPStates("syn-sent")
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Edges != 1 {
+		t.Fatal("edges:", bldr.Edges)
+	}
+
+	if e := bldr.Last; !e.IsAttempt() || e.Port.Value != "5432" {
+		t.Fatal("edge:", e)
+	}
+}
+
+func TestLoaderCompose(t *testing.T) {
+	t.Parallel()
+
+	bldr := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder: bldr,
+		Meta:    &testEnricher{},
+		Proto:   graph.TCP,
+	}
+
+	ldr := graph.NewLoader(cfg)
+
+	buf := bytes.NewBufferString(`{"name": "shop-web", "is_external": false, "cluster": "shop",
+"container": {"labels": {}, "replicas": 3}, "listen": {}, "connected": {}}`)
+
+	if err := ldr.FromReader(buf); err != nil {
+		t.Fatal("load err=", err)
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if len(bldr.Nodes) != 1 {
+		t.Fatal("nodes:", len(bldr.Nodes))
+	}
+
+	if n := bldr.Nodes[0]; n.Cluster != "shop" || n.Container.Replicas != 3 {
+		t.Fatal("node:", n.Cluster, n.Container.Replicas)
+	}
+}
+
+func TestLoaderConfidence(t *testing.T) {
+	t.Parallel()
+
+	const (
+		first = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"}},
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "81"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`
+		second = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`
+	)
+
+	bldr := &testBuilder{}
+	cfg := &graph.Config{
+		Builder: bldr,
+		Meta:    &testEnricher{},
+		Proto:   graph.TCP,
+	}
+
+	ldr := graph.NewLoader(cfg)
+
+	for _, s := range []string{first, second} {
+		if err := ldr.FromReader(bytes.NewBufferString(s)); err != nil {
+			t.Fatal("load err=", err)
+		}
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Edges != 2 {
+		t.Fatal("edges:", bldr.Edges)
+	}
+
+	bldr.Reset()
+	cfg.MinConfidence = 0.6
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Edges != 1 {
+		t.Fatal("filtered edges:", bldr.Edges)
+	}
+
+	if e := bldr.Last; e.Port.Value != "80" || e.Seen != 2 || e.Total != 2 {
+		t.Fatal("edge:", e)
+	}
+
+	// merged output keeps its counters on reload
+	bldr.Reset()
+	cfg.MinConfidence = 0.3
+
+	ldr = graph.NewLoader(cfg)
+
+	buf := bytes.NewBufferString(`{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "seen": 1, "total": 4, "port": {"kind": "tcp", "value": "81"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`)
+
+	if err := ldr.FromReader(buf); err != nil {
+		t.Fatal("load err=", err)
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if bldr.Edges != 0 {
+		t.Fatal("reload edges:", bldr.Edges, bldr.Last)
+	}
+}
+
+func TestLoaderTimespan(t *testing.T) {
+	t.Parallel()
+
+	const (
+		week1 = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"},
+     "first_seen": "2024-01-01T10:00:00Z", "last_seen": "2024-01-01T10:00:00Z"},
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "81"},
+     "first_seen": "2024-01-01T10:00:00Z", "last_seen": "2024-01-01T10:00:00Z"}
+]}, "first_seen": "2024-01-01T10:00:00Z", "last_seen": "2024-01-01T10:00:00Z"}
+{"name": "b", "is_external": false, "listen": {}, "connected": {},
+ "first_seen": "2024-01-01T10:00:00Z", "last_seen": "2024-01-01T10:00:00Z"}`
+		week2 = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"},
+     "first_seen": "2024-01-08T10:00:00Z", "last_seen": "2024-01-08T10:00:00Z"}
+]}, "first_seen": "2024-01-08T10:00:00Z", "last_seen": "2024-01-08T10:00:00Z"}
+{"name": "b", "is_external": false, "listen": {}, "connected": {},
+ "first_seen": "2024-01-08T10:00:00Z", "last_seen": "2024-01-08T10:00:00Z"}
+{"name": "c", "is_external": false, "listen": {}, "connected": {},
+ "first_seen": "2024-01-08T10:00:00Z", "last_seen": "2024-01-08T10:00:00Z"}`
+	)
+
+	bldr := &testComposeBuilder{}
+	cfg := &graph.Config{
+		Builder: bldr,
+		Meta:    &testEnricher{},
+		Proto:   graph.TCP,
+	}
+
+	ldr := graph.NewLoader(cfg)
+
+	for _, s := range []string{week1, week2} {
+		if err := ldr.FromReader(bytes.NewBufferString(s)); err != nil {
+			t.Fatal("load err=", err)
+		}
+	}
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if len(bldr.Nodes) != 3 || len(bldr.Edges) != 2 {
+		t.Fatal("nodes/edges:", len(bldr.Nodes), len(bldr.Edges))
+	}
+
+	for _, e := range bldr.Edges {
+		first, last := "2024-01-01", "2024-01-08"
+		if e.Port.Value == "81" {
+			last = first
+		}
+
+		if e.FirstSeen.Format(time.DateOnly) != first || e.LastSeen.Format(time.DateOnly) != last {
+			t.Fatal("edge timespan:", e.Port.Value, e.FirstSeen, e.LastSeen)
+		}
+	}
+
+	bldr.Nodes, bldr.Edges = nil, nil
+	cfg.Since = time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if len(bldr.Nodes) != 3 || len(bldr.Edges) != 1 || bldr.Edges[0].Port.Value != "80" {
+		t.Fatal("since nodes/edges:", len(bldr.Nodes), len(bldr.Edges))
+	}
+
+	bldr.Nodes, bldr.Edges = nil, nil
+	cfg.Since, cfg.Until = time.Time{}, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	if err := ldr.Build(); err != nil {
+		t.Fatal("build err=", err)
+	}
+
+	if len(bldr.Nodes) != 2 || len(bldr.Edges) != 2 {
+		t.Fatal("until nodes/edges:", len(bldr.Nodes), len(bldr.Edges))
+	}
+}
+
+func TestLoaderConcatenated(t *testing.T) {
+	t.Parallel()
+
+	const (
+		header = `{"header": {"schema": 2}}`
+		first  = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"}},
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "81"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`
+		second = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`
+	)
+
+	for _, stream := range []string{
+		header + first + header + second,
+		first + header + second,
+	} {
+		bldr := &testBuilder{}
+		ldr := graph.NewLoader(&graph.Config{
+			Builder:       bldr,
+			Meta:          &testEnricher{},
+			Proto:         graph.TCP,
+			MinConfidence: 0.6,
+		})
+
+		if err := ldr.FromReader(bytes.NewBufferString(stream)); err != nil {
+			t.Fatal("load err=", err)
+		}
+
+		if err := ldr.Build(); err != nil {
+			t.Fatal("build err=", err)
+		}
+
+		if bldr.Edges != 1 {
+			t.Fatal("edges:", bldr.Edges)
+		}
+
+		if e := bldr.Last; e.Port.Value != "80" || e.Seen != 2 || e.Total != 2 {
+			t.Fatal("edge:", e)
+		}
+	}
+}
+
+func TestLoaderHeader(t *testing.T) {
+	t.Parallel()
+
+	const nodes = `{"name": "a", "is_external": false, "listen": {}, "connected": {"b": [
+    {"src": "app", "dst": "db", "port": {"kind": "tcp", "value": "80"}}
+]}}
+{"name": "b", "is_external": false, "listen": {}, "connected": {}}`
+
+	testCases := []struct {
+		Name   string
+		Stream string
+		Err    bool
+	}{
+		{Name: "legacy", Stream: nodes},
+		{Name: "current", Stream: `{"header": {"schema": 2, "version": "v1.0.0", "mode": "nsenter"}}` + nodes},
+		{Name: "legacy-header", Stream: `{"header": {"schema": 1}}` + nodes},
+		{Name: "future", Stream: `{"header": {"schema": 3}}` + nodes, Err: true},
+		{Name: "invalid", Stream: `{"header": {}}` + nodes, Err: true},
+		{Name: "concatenated", Stream: nodes + `{"header": {"schema": 2}}` + nodes},
+		{Name: "concatenated-future", Stream: nodes + `{"header": {"schema": 3}}` + nodes, Err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			bldr := &testBuilder{}
+			ldr := graph.NewLoader(&graph.Config{
+				Builder: bldr,
+				Meta:    &testEnricher{},
+				Proto:   graph.TCP,
+			})
+
+			err := ldr.FromReader(bytes.NewBufferString(tc.Stream))
+			if tc.Err {
+				if !errors.Is(err, graph.ErrSchema) {
+					t.Fatal("unexpected error:", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal("load err=", err)
+			}
+
+			if err = ldr.Build(); err != nil {
+				t.Fatal("build err=", err)
+			}
+
+			if bldr.Nodes != 2 || bldr.Edges != 1 {
+				t.Fatal("nodes/edges:", bldr.Nodes, bldr.Edges)
+			}
+		})
+	}
+}
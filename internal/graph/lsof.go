@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/s0rg/set"
+)
+
+const (
+	lsofCmd         = "lsof"
+	lsofListen      = "(LISTEN)"
+	lsofEstablished = "(ESTABLISHED)"
+	lsofArrow       = "->"
+)
+
+func LsofCMD(p NetProto) (rv []string) {
+	rv = []string{lsofCmd, "-n", "-P"}
+
+	switch {
+	case p.Has(TCP | UDP):
+		rv = append(rv, "-i")
+	case p.Has(TCP):
+		rv = append(rv, "-iTCP")
+	case p.Has(UDP):
+		rv = append(rv, "-iUDP")
+	}
+
+	return rv
+}
+
+func ParseLsof(r io.Reader, states set.Unordered[string], cb func(*Connection)) (err error) {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+
+	var (
+		conn *Connection
+		ok   bool
+	)
+
+	for s.Scan() {
+		if conn, ok = parseLsofConnection(s.Text(), states); !ok {
+			continue
+		}
+
+		cb(conn)
+	}
+
+	if err = s.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+func parseLsofConnection(s string, states set.Unordered[string]) (conn *Connection, ok bool) {
+	const (
+		minFields = 9
+		nProto    = 7
+		nName     = 8
+		nState    = 9
+	)
+
+	parts := strings.Fields(s)
+	if len(parts) < minFields {
+		return nil, false
+	}
+
+	conn = &Connection{
+		Process: parts[0],
+	}
+
+	switch parts[nProto] {
+	case "TCP":
+		conn.Proto = TCP
+	case "UDP":
+		conn.Proto = UDP
+	default: // header or unknown protocol
+		return nil, false
+	}
+
+	src, dst, connected := strings.Cut(parts[nName], lsofArrow)
+
+	if conn.SrcIP, conn.SrcPort, ok = splitAddr(src); !ok {
+		return nil, false
+	}
+
+	if connected {
+		if conn.DstIP, conn.DstPort, ok = splitAddr(dst); !ok {
+			return nil, false
+		}
+	}
+
+	if conn.Proto == TCP {
+		var state string
+
+		if len(parts) > nState {
+			state = parts[nState]
+		}
+
+		switch state {
+		case lsofListen:
+			conn.Listen = true
+		case lsofEstablished:
+		default:
+			state = strings.Trim(state, "()")
+
+			if !connected || !isTCPState(state) {
+				return nil, false
+			}
+
+			conn.State = state
+		}
+	} else {
+		conn.Listen = (conn.DstPort > 0 && conn.SrcPort < conn.DstPort) || conn.DstPort == 0
+	}
+
+	if !matchState(states, conn.State) {
+		return nil, false
+	}
+
+	return conn, true
+}
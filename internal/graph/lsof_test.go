@@ -0,0 +1,102 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+func TestParseLsof(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`COMMAND  PID USER   FD   TYPE DEVICE SIZE/OFF NODE NAME
+nginx      1 root    6u  IPv4  12345      0t0  TCP *:80 (LISTEN)
+nginx      1 root    7u  IPv6  12346      0t0  TCP [::]:443 (LISTEN)
+nginx      7 root    9u  IPv4  12347      0t0  TCP 1.1.1.1:34567->1.1.1.2:5432 (ESTABLISHED)
+nginx      7 root   10u  IPv4  12348      0t0  TCP 1.1.1.1:34568->1.1.1.2:5432 (TIME_WAIT)
+nginx      7 root   11u  IPv4  12349      0t0  TCP 1.1.1.1:34569->1.1.1.2:5432
+nginx      7 root   12u  IPv4  12354      0t0  TCP 1.1.1.1:34570->1.1.1.3:6379 (SYN_SENT)
+nginx      7 root   13u  IPv4  12355      0t0  TCP *:8080 (CLOSE)
+ntpd      11 root    3u  IPv4  12350      0t0  UDP 127.0.0.1:123
+ntpd      11 root    4u  IPv4  12351      0t0  UDP 1.1.1.1:41234->10.0.0.1:123
+app       12 root    3u  IPv4  12352      0t0  TCP bad:80 (LISTEN)
+app       12 root    3u  IPv4  12352      0t0  TCP 1.1.1.1:1->bad (ESTABLISHED)
+app       12 root    3u  unix  12353      0t0  /tmp/app.sock type=STREAM
+`)
+
+	con := graph.Container{}
+
+	var names []string
+
+	states, _ := graph.ParseTCPStates("syn_sent")
+
+	if err := graph.ParseLsof(b, states, func(c *graph.Connection) {
+		names = append(names, c.Process)
+		con.AddConnection(c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if con.ConnectionsCount() != 6 {
+		t.Log("total:", con.ConnectionsCount())
+		t.Fail()
+	}
+
+	var nlisten, noutbound int
+
+	con.IterListeners(func(_ *graph.Connection) {
+		nlisten++
+	})
+	con.IterOutbounds(func(c *graph.Connection) {
+		if c.DstPort == 6379 && c.State != "SYN_SENT" {
+			t.Fatal("state:", c.State)
+		}
+
+		noutbound++
+	})
+
+	if nlisten != 3 || noutbound != 3 {
+		t.Log("listen/outbound:", nlisten, noutbound)
+		t.Fail()
+	}
+
+	if !slices.Contains(names, "ntpd") || slices.Contains(names, "app") {
+		t.Log("names:", names)
+		t.Fail()
+	}
+}
+
+func TestParseLsofError(t *testing.T) {
+	t.Parallel()
+
+	myErr := errors.New("test-err")
+	reader := &failReader{Err: myErr}
+
+	err := graph.ParseLsof(reader, nil, func(*graph.Connection) {})
+	if err == nil {
+		t.Fatal("err == nil")
+	}
+
+	if !errors.Is(err, myErr) {
+		t.Fail()
+	}
+}
+
+func TestLsofCMD(t *testing.T) {
+	t.Parallel()
+
+	if cmd := graph.LsofCMD(graph.ALL); cmd[len(cmd)-1] != "-i" {
+		t.Fail()
+	}
+
+	if cmd := graph.LsofCMD(graph.TCP); cmd[len(cmd)-1] != "-iTCP" {
+		t.Fail()
+	}
+
+	if cmd := graph.LsofCMD(graph.UDP); cmd[len(cmd)-1] != "-iUDP" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,324 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+const (
+	meshName = "mesh"
+
+	// pod labels, set by podman and cri clients.
+	PodmanPodLabel    = "io.podman.pod"
+	CRIPodLabel       = "io.kubernetes.pod.name"
+	CRINamespaceLabel = "io.kubernetes.pod.namespace"
+)
+
+// DefaultMeshProxies holds process, container and image (repository basename) names of well-known mesh sidecars.
+var DefaultMeshProxies = []string{
+	"envoy",
+	"istio-proxy",
+	"proxyv2",
+	"linkerd-proxy",
+	"linkerd2-proxy",
+}
+
+type meshEndpoint struct {
+	Port *node.Port
+	ID   string
+	Name string
+}
+
+// MeshCollapser rewrites app -> proxy -> proxy -> app chains of service mesh into direct
+// app -> app edges with real application ports, such edges are marked as "via-mesh".
+type MeshCollapser struct {
+	b       NamedBuilderWriter
+	proxies []string
+	nodes   []*node.Node
+	edges   []*node.Edge
+	nets    []*node.Network
+}
+
+func NewMeshCollapser(b NamedBuilderWriter, proxies []string) *MeshCollapser {
+	return &MeshCollapser{
+		b:       b,
+		proxies: proxies,
+	}
+}
+
+func (m *MeshCollapser) Name() string {
+	return m.b.Name() + " " + meshName
+}
+
+func (m *MeshCollapser) AddNode(n *node.Node) error {
+	m.nodes = append(m.nodes, n)
+
+	return nil
+}
+
+func (m *MeshCollapser) AddEdge(e *node.Edge) {
+	m.edges = append(m.edges, e)
+}
+
+func (m *MeshCollapser) AddNetwork(n *node.Network) {
+	m.nets = append(m.nets, n)
+}
+
+func (m *MeshCollapser) Write(w io.Writer) (err error) {
+	st := m.newState()
+
+	for _, n := range m.nodes {
+		if st.Dropped.Has(n.ID) {
+			continue
+		}
+
+		if err = m.b.AddNode(n); err != nil {
+			return fmt.Errorf("mesh add-node [%s]: %w", m.b.Name(), err)
+		}
+	}
+
+	for _, e := range st.Collapse(m.edges) {
+		m.b.AddEdge(e)
+	}
+
+	if nb, ok := m.b.(NetworkBuilder); ok {
+		for _, n := range m.nets {
+			n.Members = slices.DeleteFunc(n.Members, func(nm *node.NetworkMember) bool {
+				return st.Dropped.Has(nm.ID)
+			})
+
+			nb.AddNetwork(n)
+		}
+	}
+
+	if err = m.b.Write(w); err != nil {
+		return fmt.Errorf("mesh write [%s]: %w", m.b.Name(), err)
+	}
+
+	return nil
+}
+
+func (m *MeshCollapser) isProxyNode(n *node.Node) bool {
+	if n.IsExternal() {
+		return false
+	}
+
+	image := imageName(n.Image)
+
+	return slices.ContainsFunc(m.proxies, func(p string) bool {
+		return n.Name == p || image == p
+	})
+}
+
+func (m *MeshCollapser) newState() (st *meshState) {
+	st = &meshState{
+		Processes: make(set.Unordered[string]),
+		Proxies:   make(set.Unordered[string]),
+		Dropped:   make(set.Unordered[string]),
+		Ports:     make(set.Unordered[string]),
+		Pod:       make(map[string]string, len(m.nodes)),
+		Apps:      make(map[string][]*node.Node),
+		Egress:    make(map[string][]*meshEndpoint),
+		Ingress:   make(map[string][]*meshEndpoint),
+	}
+
+	set.Load(st.Processes, m.proxies...)
+
+	for _, n := range m.nodes {
+		pod := podKey(n)
+
+		st.Pod[n.ID] = pod
+
+		if m.isProxyNode(n) {
+			st.Proxies.Add(n.ID)
+		} else {
+			st.Apps[pod] = append(st.Apps[pod], n)
+		}
+	}
+
+	// sidecars are hidden, standalone proxies (i.e. ingress gateways) are kept
+	for _, n := range m.nodes {
+		pod := st.Pod[n.ID]
+
+		if !st.Proxies.Has(n.ID) || len(st.Apps[pod]) == 0 {
+			continue
+		}
+
+		st.Dropped.Add(n.ID)
+
+		n.Ports.Iter(func(_ string, ports []*node.Port) {
+			for _, p := range ports {
+				st.Ports.Add(pod + "|" + p.Label())
+			}
+		})
+	}
+
+	return st
+}
+
+type meshState struct {
+	Processes set.Unordered[string]
+	Proxies   set.Unordered[string]
+	Dropped   set.Unordered[string]
+	Ports     set.Unordered[string]      // pod and port, sidecars listen on
+	Pod       map[string]string          // nodeID -> pod
+	Apps      map[string][]*node.Node    // pod -> non-proxy nodes
+	Egress    map[string][]*meshEndpoint // pod -> apps, that talk to local proxy
+	Ingress   map[string][]*meshEndpoint // pod -> apps with ports, served by local proxy
+}
+
+func (st *meshState) isProxy(id, process string) bool {
+	return st.Dropped.Has(id) || (st.Processes.Has(process) && len(st.Apps[st.Pod[id]]) > 0)
+}
+
+// isLocalProxy reports whether edge goes to sidecar port in same pod, such connections are
+// attributed to source container, as they are made over loopback.
+func (st *meshState) isLocalProxy(e *node.Edge) bool {
+	pod := st.Pod[e.SrcID]
+
+	return pod == st.Pod[e.DstID] && st.Ports.Has(pod+"|"+e.Port.Label())
+}
+
+// Collapse returns edges, where proxy hops are replaced by direct app to app edges.
+func (st *meshState) Collapse(edges []*node.Edge) (rv []*node.Edge) {
+	var (
+		hops []*node.Edge
+		seen = make(map[string]*node.Edge)
+	)
+
+	emit := func(e *node.Edge) {
+		key := strings.Join([]string{e.SrcID, e.DstID, e.SrcName, e.DstName, e.Kind, e.Port.Label()}, "|")
+
+		// several hops may collapse into same edge
+		if cur, ok := seen[key]; ok {
+			cur.Merge(e)
+
+			return
+		}
+
+		seen[key] = e
+		rv = append(rv, e)
+	}
+
+	for _, e := range edges {
+		sp, dp := st.isProxy(e.SrcID, e.SrcName), st.isProxy(e.DstID, e.DstName) || st.isLocalProxy(e)
+		pod := st.Pod[e.SrcID]
+
+		switch {
+		case !sp && !dp:
+			emit(e)
+		case pod != st.Pod[e.DstID]:
+			hops = append(hops, e)
+		case !sp: // app -> local proxy
+			st.Egress[pod] = append(st.Egress[pod], &meshEndpoint{ID: e.SrcID, Name: e.SrcName})
+		case !dp: // local proxy -> app
+			st.Ingress[pod] = append(st.Ingress[pod], &meshEndpoint{ID: e.DstID, Name: e.DstName, Port: e.Port})
+		}
+	}
+
+	for _, e := range hops {
+		srcs := []*meshEndpoint{{ID: e.SrcID, Name: e.SrcName}}
+		if st.isProxy(e.SrcID, e.SrcName) {
+			srcs = st.sources(st.Pod[e.SrcID])
+		}
+
+		dsts := []*meshEndpoint{{ID: e.DstID, Name: e.DstName, Port: e.Port}}
+		if st.isProxy(e.DstID, e.DstName) {
+			dsts = st.targets(st.Pod[e.DstID], e.Port)
+		}
+
+		for _, src := range srcs {
+			for _, dst := range dsts {
+				// state and persistence counters are carried from proxy hop
+				ce := *e
+
+				ce.SrcID, ce.SrcName = src.ID, src.Name
+				ce.DstID, ce.DstName = dst.ID, dst.Name
+				ce.Port, ce.Kind = dst.Port, node.EdgeViaMesh
+
+				emit(&ce)
+			}
+		}
+	}
+
+	return rv
+}
+
+// sources returns apps, that send traffic through pod proxy.
+func (st *meshState) sources(pod string) (rv []*meshEndpoint) {
+	if rv = st.Egress[pod]; len(rv) > 0 {
+		return rv
+	}
+
+	for _, n := range st.Apps[pod] {
+		rv = append(rv, &meshEndpoint{ID: n.ID, Name: ProcessUnknown})
+	}
+
+	return rv
+}
+
+// targets returns apps, that receive traffic for port from pod proxy.
+func (st *meshState) targets(pod string, port *node.Port) (rv []*meshEndpoint) {
+	ingress := st.Ingress[pod]
+
+	for _, ep := range ingress {
+		if ep.Port.Equal(port) {
+			rv = append(rv, ep)
+		}
+	}
+
+	switch {
+	case len(rv) > 0:
+		return rv
+	case len(ingress) == 1:
+		return ingress
+	}
+
+	// no ingress seen: app, that listens on port, or all apps with unresolved port
+	for _, n := range st.Apps[pod] {
+		if name, ok := n.Ports.Get(port); ok {
+			rv = append(rv, &meshEndpoint{ID: n.ID, Name: name, Port: port})
+		}
+	}
+
+	if len(rv) > 0 {
+		return rv
+	}
+
+	for _, n := range st.Apps[pod] {
+		rv = append(rv, &meshEndpoint{ID: n.ID, Name: ProcessUnknown, Port: port})
+	}
+
+	return rv
+}
+
+// imageName returns image repository basename, i.e. "envoy" for "docker.io/envoyproxy/envoy:v1.29".
+func imageName(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+
+	return image[strings.LastIndex(image, "/")+1:]
+}
+
+// podKey groups containers, that share network namespace, nodes without pod are on their own.
+func podKey(n *node.Node) string {
+	labels := n.Container.Labels
+
+	switch {
+	case labels[CRIPodLabel] != "":
+		return n.Container.Host + "/" + labels[CRINamespaceLabel] + "/" + labels[CRIPodLabel]
+	case labels[PodmanPodLabel] != "":
+		return n.Container.Host + "/" + labels[PodmanPodLabel]
+	}
+
+	return n.ID
+}
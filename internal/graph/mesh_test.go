@@ -0,0 +1,295 @@
+package graph_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s0rg/decompose/internal/graph"
+	"github.com/s0rg/decompose/internal/node"
+)
+
+func makePodNode(pod, name, image string, ports ...*node.Port) *node.Node {
+	rv := &node.Node{
+		ID:    pod + "/" + name,
+		Name:  name,
+		Image: image,
+		Ports: &node.Ports{},
+		Container: node.Container{
+			Labels: map[string]string{
+				graph.CRIPodLabel:       pod,
+				graph.CRINamespaceLabel: "default",
+			},
+		},
+	}
+
+	for _, p := range ports {
+		rv.Ports.Add(name, p)
+	}
+
+	return rv
+}
+
+func TestMeshCollapser(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p8080  = &node.Port{Kind: "tcp", Value: "8080", Number: 8080}
+		p15001 = &node.Port{Kind: "tcp", Value: "15001", Number: 15001}
+		p443   = &node.Port{Kind: "tcp", Value: "443", Number: 443}
+	)
+
+	tb := &testComposeBuilder{}
+	mc := graph.NewMeshCollapser(tb, graph.DefaultMeshProxies)
+
+	if !strings.Contains(mc.Name(), tb.Name()) {
+		t.Fail()
+	}
+
+	appA := makePodNode("a", "client", "client:latest")
+	proxyA := makePodNode("a", "istio-proxy", "istio/proxyv2:1.20", p15001)
+	appB := makePodNode("b", "server", "server:latest", p8080)
+	proxyB := makePodNode("b", "istio-proxy", "istio/proxyv2:1.20", p15001)
+	gateway := &node.Node{ID: "gw", Name: "gateway", Image: "envoyproxy/envoy", Ports: &node.Ports{}}
+	ext := node.External("1.1.1.1")
+
+	for _, n := range []*node.Node{appA, proxyA, appB, proxyB, gateway, ext} {
+		_ = mc.AddNode(n)
+	}
+
+	// app -> local sidecar, over loopback
+	mc.AddEdge(&node.Edge{SrcID: appA.ID, SrcName: "client", DstID: appA.ID, DstName: graph.ProcessUnknown, Port: p15001})
+	// sidecar -> sidecar
+	mc.AddEdge(&node.Edge{SrcID: proxyA.ID, SrcName: "envoy", DstID: proxyB.ID, DstName: "envoy", Port: p8080})
+	// sidecar -> local app, over loopback
+	mc.AddEdge(&node.Edge{SrcID: proxyB.ID, SrcName: "envoy", DstID: proxyB.ID, DstName: graph.ProcessUnknown, Port: p8080})
+	// sidecar -> outside world
+	mc.AddEdge(&node.Edge{SrcID: proxyA.ID, SrcName: "envoy", DstID: ext.ID, DstName: graph.ProcessRemote, Port: p443})
+	// gateway -> sidecar
+	mc.AddEdge(&node.Edge{SrcID: gateway.ID, SrcName: "envoy", DstID: proxyB.ID, DstName: "envoy", Port: p8080})
+
+	mc.AddNetwork(&node.Network{
+		Name: "pods",
+		Members: []*node.NetworkMember{
+			{ID: appA.ID, Name: appA.Name},
+			{ID: proxyA.ID, Name: proxyA.Name},
+		},
+	})
+
+	if err := mc.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Nodes) != 4 {
+		t.Fatal("nodes:", len(tb.Nodes))
+	}
+
+	for _, n := range tb.Nodes {
+		if n == proxyA || n == proxyB {
+			t.Fatal("sidecar:", n.ID)
+		}
+	}
+
+	want := []string{
+		"a/client:client -> b/server:server tcp:8080",
+		"a/client:client -> 1.1.1.1:[remote] tcp:443",
+		"gw:envoy -> b/server:server tcp:8080",
+	}
+
+	if len(tb.Edges) != len(want) {
+		t.Fatal("edges:", len(tb.Edges))
+	}
+
+	for i, e := range tb.Edges {
+		if !e.IsMesh() {
+			t.Fatal("kind:", e.Kind)
+		}
+
+		if got := e.SrcID + ":" + e.SrcName + " -> " + e.DstID + ":" + e.DstName + " " + e.Port.Label(); got != want[i] {
+			t.Fatal("edge:", i, got)
+		}
+	}
+
+	if m := tb.Networks[0].Members; len(m) != 1 || m[0].ID != appA.ID {
+		t.Fatal("members:", m)
+	}
+}
+
+func TestMeshCollapserProcess(t *testing.T) {
+	t.Parallel()
+
+	p80 := &node.Port{Kind: "tcp", Value: "80", Number: 80}
+
+	tb := &testComposeBuilder{}
+	mc := graph.NewMeshCollapser(tb, []string{"linkerd2-proxy"})
+
+	// proxy runs as process inside app containers
+	a := &node.Node{ID: "a", Name: "a-app", Ports: &node.Ports{}}
+	b := &node.Node{ID: "b", Name: "b-app", Ports: &node.Ports{}}
+	b.Ports.Add("nginx", p80)
+
+	_ = mc.AddNode(a)
+	_ = mc.AddNode(b)
+
+	mc.AddEdge(&node.Edge{SrcID: "a", SrcName: "curl", DstID: "a", DstName: "linkerd2-proxy", Port: p80})
+	mc.AddEdge(&node.Edge{SrcID: "a", SrcName: "linkerd2-proxy", DstID: "b", DstName: "linkerd2-proxy", Port: p80})
+	mc.AddEdge(&node.Edge{SrcID: "b", SrcName: "linkerd2-proxy", DstID: "b", DstName: "nginx", Port: p80})
+	mc.AddEdge(&node.Edge{SrcID: "b", SrcName: "nginx", DstID: "a", DstName: "curl", Port: p80})
+
+	if err := mc.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Nodes) != 2 || len(tb.Edges) != 2 {
+		t.Fatal("nodes/edges:", len(tb.Nodes), len(tb.Edges))
+	}
+
+	if e := tb.Edges[0]; e.IsMesh() || e.SrcName != "nginx" {
+		t.Fatal("plain edge:", e)
+	}
+
+	if e := tb.Edges[1]; !e.IsMesh() || e.SrcName != "curl" || e.DstID != "b" || e.DstName != "nginx" {
+		t.Fatal("mesh edge:", e)
+	}
+}
+
+func TestMeshCollapserError(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test-err")
+	tb := &testComposeBuilder{Err: testErr}
+	mc := graph.NewMeshCollapser(tb, graph.DefaultMeshProxies)
+
+	_ = mc.AddNode(&node.Node{ID: "a", Name: "a", Ports: &node.Ports{}})
+
+	if err := mc.Write(io.Discard); !errors.Is(err, testErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestMeshCollapserExactNames(t *testing.T) {
+	t.Parallel()
+
+	p8080 := &node.Port{Kind: "tcp", Value: "8080", Number: 8080}
+
+	tb := &testComposeBuilder{}
+	mc := graph.NewMeshCollapser(tb, graph.DefaultMeshProxies)
+
+	// names and images, that only contain proxy name, are apps
+	app := makePodNode("a", "envoy-config-api", "myorg/envoyish:1.0", p8080)
+	proxy := makePodNode("a", "sidecar", "docker.io/envoyproxy/envoy:v1.29@sha256:0123", p8080)
+
+	_ = mc.AddNode(app)
+	_ = mc.AddNode(proxy)
+
+	if err := mc.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Nodes) != 1 || tb.Nodes[0].ID != app.ID {
+		t.Fatal("nodes:", tb.Nodes)
+	}
+}
+
+func TestMeshCollapserNoIngress(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p8080  = &node.Port{Kind: "tcp", Value: "8080", Number: 8080}
+		p9090  = &node.Port{Kind: "tcp", Value: "9090", Number: 9090}
+		p15006 = &node.Port{Kind: "tcp", Value: "15006", Number: 15006}
+	)
+
+	tb := &testComposeBuilder{}
+	mc := graph.NewMeshCollapser(tb, graph.DefaultMeshProxies)
+
+	appA := makePodNode("a", "client", "client:latest")
+	proxyA := makePodNode("a", "istio-proxy", "istio/proxyv2:1.20")
+	appB := makePodNode("b", "server", "server:latest", p8080)
+	proxyB := makePodNode("b", "istio-proxy", "istio/proxyv2:1.20", p15006)
+	appC := makePodNode("c", "worker", "worker:latest")
+	proxyC := makePodNode("c", "istio-proxy", "istio/proxyv2:1.20", p9090)
+
+	for _, n := range []*node.Node{appA, proxyA, appB, proxyB, appC, proxyC} {
+		_ = mc.AddNode(n)
+	}
+
+	// no proxy -> app edges in b and c: ingress is not seen
+	mc.AddEdge(&node.Edge{SrcID: appA.ID, SrcName: "client", DstID: proxyA.ID, DstName: "envoy", Port: p8080})
+	mc.AddEdge(&node.Edge{SrcID: proxyA.ID, SrcName: "envoy", DstID: proxyB.ID, DstName: "envoy", Port: p15006})
+	mc.AddEdge(&node.Edge{SrcID: proxyA.ID, SrcName: "envoy", DstID: proxyC.ID, DstName: "envoy", Port: p9090})
+
+	if err := mc.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	// port is not resolved to app listeners, edge keeps proxy port
+	want := []string{
+		"b/server:[unknown] tcp:15006",
+		"c/worker:[unknown] tcp:9090",
+	}
+
+	if len(tb.Edges) != len(want) {
+		t.Fatal("edges:", len(tb.Edges))
+	}
+
+	for i, e := range tb.Edges {
+		if got := e.DstID + ":" + e.DstName + " " + e.Port.Label(); !e.IsMesh() || got != want[i] {
+			t.Fatal("mesh edge:", i, got)
+		}
+	}
+}
+
+func TestMeshCollapserCounters(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p8080  = &node.Port{Kind: "tcp", Value: "8080", Number: 8080}
+		p15001 = &node.Port{Kind: "tcp", Value: "15001", Number: 15001}
+	)
+
+	tb := &testComposeBuilder{}
+	mc := graph.NewMeshCollapser(tb, graph.DefaultMeshProxies)
+
+	appA := makePodNode("a", "client", "client:latest")
+	proxyA := makePodNode("a", "istio-proxy", "istio/proxyv2:1.20", p15001)
+	appB := makePodNode("b", "server", "server:latest", p8080)
+	proxyB := makePodNode("b", "istio-proxy", "istio/proxyv2:1.20", p15001)
+
+	for _, n := range []*node.Node{appA, proxyA, appB, proxyB} {
+		_ = mc.AddNode(n)
+	}
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := first.Add(time.Hour)
+
+	mc.AddEdge(&node.Edge{
+		SrcID: proxyA.ID, SrcName: "envoy", DstID: proxyB.ID, DstName: "envoy", Port: p8080,
+		Timespan: node.Stamp(first), State: node.StateSynSent, Samples: 2, Seen: 1, Total: 3,
+	})
+	// same connection from another proxy process
+	mc.AddEdge(&node.Edge{
+		SrcID: proxyA.ID, SrcName: "pilot-agent", DstID: proxyB.ID, DstName: "envoy", Port: p8080,
+		Timespan: node.Stamp(last), State: node.StateSynSent, Samples: 3, Seen: 2, Total: 3,
+	})
+
+	if err := mc.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tb.Edges) != 1 {
+		t.Fatal("edges:", len(tb.Edges))
+	}
+
+	e := tb.Edges[0]
+
+	if !e.IsMesh() || !e.IsAttempt() || e.Samples != 3 || e.Seen != 2 || e.Total != 3 {
+		t.Fatal("counters:", e.State, e.Samples, e.Seen, e.Total)
+	}
+
+	if !e.FirstSeen.Equal(first) || !e.LastSeen.Equal(last) {
+		t.Fatal("timespan:", e.Timespan)
+	}
+}
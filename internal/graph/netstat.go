@@ -7,6 +7,8 @@ import (
 	"net"
 	"strconv"
 	"strings"
+
+	"github.com/s0rg/set"
 )
 
 const (
@@ -35,7 +37,9 @@ func NetstatCMD(p NetProto) []string {
 	}
 }
 
-func ParseNetstat(r io.Reader, cb func(*Connection)) (err error) {
+// ParseNetstat parses netstat output, transient tcp sockets are reported only for given states,
+// ownerless ones are attributed to ProcessUnknown.
+func ParseNetstat(r io.Reader, states set.Unordered[string], cb func(*Connection)) (err error) {
 	s := bufio.NewScanner(r)
 	s.Split(bufio.ScanLines)
 
@@ -51,7 +55,7 @@ func ParseNetstat(r io.Reader, cb func(*Connection)) (err error) {
 			continue
 		}
 
-		if conn, ok = parseConnection(s.Text()); !ok {
+		if conn, ok = parseConnection(s.Text(), states); !ok {
 			continue
 		}
 
@@ -65,7 +69,7 @@ func ParseNetstat(r io.Reader, cb func(*Connection)) (err error) {
 	return nil
 }
 
-func parseConnection(s string) (conn *Connection, ok bool) {
+func parseConnection(s string, states set.Unordered[string]) (conn *Connection, ok bool) {
 	const minFields = 6
 
 	parts := strings.Fields(s)
@@ -82,11 +86,11 @@ func parseConnection(s string) (conn *Connection, ok bool) {
 		Proto: proto,
 	}
 
-	if conn.SrcIP, conn.SrcPort, ok = splitIP(parts[3]); !ok {
+	if conn.SrcIP, conn.SrcPort, ok = splitAddr(parts[3]); !ok {
 		return nil, false
 	}
 
-	if conn.DstIP, conn.DstPort, ok = splitIP(parts[4]); !ok {
+	if conn.DstIP, conn.DstPort, ok = splitAddr(parts[4]); !ok {
 		return nil, false
 	}
 
@@ -99,17 +103,30 @@ func parseConnection(s string) (conn *Connection, ok bool) {
 		case stateListen:
 			conn.Listen = true
 		case stateEstablished:
-		default: // skip all other states
-			return nil, false
+		default:
+			if !isTCPState(parts[5]) {
+				return nil, false
+			}
+
+			conn.State = parts[5]
 		}
 	} else {
 		conn.Listen = (conn.DstPort > 0 && conn.SrcPort < conn.DstPort) || conn.DstPort == 0
 	}
 
-	if conn.Process, ok = splitName(parts[nProcField]); !ok {
+	if !matchState(states, conn.State) {
 		return nil, false
 	}
 
+	if conn.Process, ok = splitName(parts[nProcField]); !ok {
+		// transient sockets (i.e. TIME_WAIT) usually have no owner
+		if conn.State == "" {
+			return nil, false
+		}
+
+		conn.Process = ProcessUnknown
+	}
+
 	return conn, true
 }
 
@@ -142,6 +159,8 @@ func splitIP(v string) (ip net.IP, port int, ok bool) {
 		return
 	}
 
+	ip = NormalizeIP(ip)
+
 	if sport != "*" {
 		uval, err := strconv.ParseUint(sport, 10, 16)
 		if err != nil {
@@ -3,6 +3,7 @@ package graph_test
 import (
 	"bytes"
 	"errors"
+	"net"
 	"testing"
 
 	"github.com/s0rg/decompose/internal/graph"
@@ -51,7 +52,7 @@ some       garbage
 
 	con := graph.Container{}
 
-	if err := graph.ParseNetstat(b, func(c *graph.Connection) {
+	if err := graph.ParseNetstat(b, nil, func(c *graph.Connection) {
 		con.AddConnection(c)
 	}); err != nil {
 		t.Fatal(err)
@@ -77,13 +78,85 @@ some       garbage
 	}
 }
 
+func TestParseNetstatStates(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp        0      0 172.20.4.209:48020      172.20.4.198:3306       TIME_WAIT   -
+tcp        0      0 172.20.4.209:48022      172.20.4.198:3307       TIME_WAIT   2/bar
+tcp        1      0 172.20.4.209:43534      172.20.4.129:53         CLOSE_WAIT  2/bar
+tcp        0      0 172.20.4.209:48021      172.20.4.198:3306       ESTABLISHED -
+`)
+
+	states, _ := graph.ParseTCPStates("time_wait")
+
+	var conns []*graph.Connection
+
+	if err := graph.ParseNetstat(b, states, func(c *graph.Connection) {
+		conns = append(conns, c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conns) != 2 {
+		t.Fatal("total:", len(conns))
+	}
+
+	if c := conns[0]; c.Process != graph.ProcessUnknown || c.State != "TIME_WAIT" || c.DstPort != 3306 {
+		t.Fatal("ownerless:", c)
+	}
+
+	if c := conns[1]; c.Process != "bar" || c.DstPort != 3307 {
+		t.Fatal("owned:", c)
+	}
+}
+
+func TestParseNetstatIPv6(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp6       0      0 fd00::2:8080            :::*                    LISTEN 1/foo
+tcp6       0      0 fd00::2:40000           fd00::3:5432            ESTABLISHED 1/foo
+tcp6       0      0 ::ffff:172.20.0.2:40001 ::ffff:172.20.0.4:6379  ESTABLISHED 1/foo
+tcp6       0      0 fe80::1%eth0:40002      fe80::2%eth0:53         ESTABLISHED 1/foo
+`)
+
+	var conns []*graph.Connection
+
+	if err := graph.ParseNetstat(b, nil, func(c *graph.Connection) {
+		conns = append(conns, c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conns) != 4 {
+		t.Fatal("total:", len(conns))
+	}
+
+	if l := conns[0]; !l.Listen || l.SrcIP.String() != "fd00::2" || l.SrcPort != 8080 {
+		t.Fatal("listener:", l)
+	}
+
+	for i, want := range []string{"fd00::3", "172.20.0.4", "fe80::2"} {
+		if got := conns[i+1].DstIP.String(); got != want {
+			t.Fatal("dst:", i, got)
+		}
+	}
+
+	if len(conns[2].SrcIP) != net.IPv4len {
+		t.Fatal("mapped address not normalized:", conns[2].SrcIP)
+	}
+}
+
 func TestParseNetstatError(t *testing.T) {
 	t.Parallel()
 
 	myErr := errors.New("test-err")
 	reader := &failReader{Err: myErr}
 
-	err := graph.ParseNetstat(reader, func(*graph.Connection) {})
+	err := graph.ParseNetstat(reader, nil, func(*graph.Connection) {})
 	if err == nil {
 		t.Fatal("err == nil")
 	}
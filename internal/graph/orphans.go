@@ -3,6 +3,7 @@ package graph
 import (
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/s0rg/decompose/internal/node"
 
@@ -16,6 +17,7 @@ type OrphansInspector struct {
 	o set.Unordered[string]
 	n []*node.Node
 	e []*node.Edge
+	w []*node.Network
 }
 
 func NewOrphansInspector(b NamedBuilderWriter) *OrphansInspector {
@@ -42,6 +44,10 @@ func (o *OrphansInspector) AddEdge(e *node.Edge) {
 	o.o.Del(e.DstID)
 }
 
+func (o *OrphansInspector) AddNetwork(n *node.Network) {
+	o.w = append(o.w, n)
+}
+
 func (o *OrphansInspector) Write(w io.Writer) (err error) {
 	for _, n := range o.n {
 		if o.o.Has(n.ID) {
@@ -57,6 +63,16 @@ func (o *OrphansInspector) Write(w io.Writer) (err error) {
 		o.b.AddEdge(e)
 	}
 
+	if nb, ok := o.b.(NetworkBuilder); ok {
+		for _, n := range o.w {
+			n.Members = slices.DeleteFunc(n.Members, func(m *node.NetworkMember) bool {
+				return o.o.Has(m.ID)
+			})
+
+			nb.AddNetwork(n)
+		}
+	}
+
 	if err = o.b.Write(w); err != nil {
 		return fmt.Errorf("no-orphans write: %w", err)
 	}
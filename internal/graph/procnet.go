@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/s0rg/set"
+)
+
+const (
+	procNetCmd    = "cat"
+	procNetRoot   = "/proc/net/"
+	procNetHeader = "sl"
+	procNetUDP    = "drops" // only udp tables have this column
+
+	// from net/tcp_states.h.
+	procNetEstablished = "01"
+	procNetListen      = "0A"
+)
+
+func ProcNetCMD(p NetProto) (rv []string) {
+	rv = []string{procNetCmd}
+
+	if p.Has(TCP) {
+		rv = append(rv, procNetRoot+"tcp", procNetRoot+"tcp6")
+	}
+
+	if p.Has(UDP) {
+		rv = append(rv, procNetRoot+"udp", procNetRoot+"udp6")
+	}
+
+	return rv
+}
+
+// ParseProcNet parses concatenated /proc/net/{tcp,udp}{,6} tables, as they have
+// no process info, all connections are attributed to ProcessUnknown.
+func ParseProcNet(r io.Reader, states set.Unordered[string], cb func(*Connection)) (err error) {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+
+	var (
+		proto = TCP
+		conn  *Connection
+		ok    bool
+	)
+
+	for s.Scan() {
+		line := s.Text()
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == procNetHeader {
+			proto = TCP
+
+			if fields[len(fields)-1] == procNetUDP {
+				proto = UDP
+			}
+
+			continue
+		}
+
+		if conn, ok = parseProcNetLine(proto, fields); !ok || !matchState(states, conn.State) {
+			continue
+		}
+
+		cb(conn)
+	}
+
+	if err = s.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+func parseProcNetLine(proto NetProto, fields []string) (conn *Connection, ok bool) {
+	const (
+		minFields = 10
+		nInode    = 9
+	)
+
+	if len(fields) < minFields {
+		return nil, false
+	}
+
+	conn = &Connection{
+		Proto:   proto,
+		Process: ProcessUnknown,
+	}
+
+	if conn.SrcIP, conn.SrcPort, ok = splitHexAddr(fields[1]); !ok {
+		return nil, false
+	}
+
+	if conn.DstIP, conn.DstPort, ok = splitHexAddr(fields[2]); !ok {
+		return nil, false
+	}
+
+	if proto == TCP {
+		switch fields[3] {
+		case procNetListen:
+			conn.Listen = true
+		case procNetEstablished:
+		default:
+			code, err := strconv.ParseUint(fields[3], 16, 8)
+			if err != nil {
+				return nil, false
+			}
+
+			if conn.State, ok = TCPState(code); !ok {
+				return nil, false
+			}
+		}
+	} else {
+		conn.Listen = (conn.DstPort > 0 && conn.SrcPort < conn.DstPort) || conn.DstPort == 0
+	}
+
+	conn.Inode, _ = strconv.ParseUint(fields[nInode], 10, 64)
+
+	return conn, true
+}
+
+// splitHexAddr decodes "0100007F:0050" address form, ip is stored as
+// sequence of host-endian (little-endian for most of platforms) 32-bit words.
+func splitHexAddr(v string) (ip net.IP, port int, ok bool) {
+	const wordSize = 4
+
+	saddr, sport, found := strings.Cut(v, ":")
+	if !found {
+		return
+	}
+
+	raw, err := hex.DecodeString(saddr)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return
+	}
+
+	ip = make(net.IP, len(raw))
+
+	for i := 0; i < len(raw); i += wordSize {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+
+	uval, err := strconv.ParseUint(sport, 16, 16)
+	if err != nil {
+		return
+	}
+
+	return NormalizeIP(ip), int(uval), true
+}
@@ -0,0 +1,118 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+func TestParseProcNet(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0201A8C0:D431 0301A8C0:1538 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 0201A8C0:D432 0301A8C0:1538 06 00000000:00000000 03:00001770 00000000     0        0 0 3 0000000000000000
+   4: XX01A8C0:D432 0301A8C0:1538 01 00000000:00000000 03:00001770 00000000     0        0 0 3 0000000000000000
+   5: 0201A8C0:D432 0301A8C0:XXXX 01 00000000:00000000 03:00001770 00000000     0        0 0 3 0000000000000000
+   6: 0201A8C0 0301A8C0:1538 01
+  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1BBD 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00000201A8C0:0050 0000000000000000FFFF00000401A8C0:E4C2 01 00000000:00000000 00:00000000 00000000     0        0 1005 1 0000000000000000 20 4 30 10 -1
+   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  10: 00000000:007B 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1006 2 0000000000000000 0
+cat: /proc/net/udp6: No such file or directory
+`)
+
+	con := graph.Container{}
+
+	var conns []*graph.Connection
+
+	states, _ := graph.ParseTCPStates("time_wait")
+
+	if err := graph.ParseProcNet(b, states, func(c *graph.Connection) {
+		conns = append(conns, c)
+		con.AddConnection(c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conns) != 7 || conns[3].State != "TIME_WAIT" {
+		t.Fatal("total:", len(conns))
+	}
+
+	var nlisten, noutbound int
+
+	con.IterListeners(func(c *graph.Connection) {
+		if c.Process != graph.ProcessUnknown {
+			t.Fail()
+		}
+
+		nlisten++
+	})
+	con.IterOutbounds(func(c *graph.Connection) {
+		if c.DstIP.String() != "192.168.1.3" || c.DstPort != 5432 || c.SrcPort != 54321 || c.Inode != 1003 {
+			t.Log(c)
+			t.Fail()
+		}
+
+		noutbound++
+	})
+
+	if nlisten != 4 || noutbound != 1 {
+		t.Log("listen/outbound:", nlisten, noutbound)
+		t.Fail()
+	}
+
+	if !slices.ContainsFunc(conns, func(c *graph.Connection) bool {
+		return c.Proto == graph.UDP && c.SrcPort == 123 && c.Listen
+	}) {
+		t.Fail()
+	}
+
+	if !slices.ContainsFunc(conns, func(c *graph.Connection) bool {
+		return c.Proto == graph.TCP && c.SrcPort == 8080 && c.SrcIP.IsLoopback()
+	}) {
+		t.Fail()
+	}
+
+	if !slices.ContainsFunc(conns, func(c *graph.Connection) bool {
+		return c.SrcPort == 80 && len(c.SrcIP) == net.IPv4len && c.SrcIP.String() == "192.168.1.2" &&
+			len(c.DstIP) == net.IPv4len && c.DstIP.String() == "192.168.1.4"
+	}) {
+		t.Fail()
+	}
+}
+
+func TestParseProcNetError(t *testing.T) {
+	t.Parallel()
+
+	myErr := errors.New("test-err")
+	reader := &failReader{Err: myErr}
+
+	err := graph.ParseProcNet(reader, nil, func(*graph.Connection) {})
+	if err == nil {
+		t.Fatal("err == nil")
+	}
+
+	if !errors.Is(err, myErr) {
+		t.Fail()
+	}
+}
+
+func TestProcNetCMD(t *testing.T) {
+	t.Parallel()
+
+	if cmd := graph.ProcNetCMD(graph.TCP | graph.UDP); len(cmd) != 5 || cmd[0] != "cat" {
+		t.Fail()
+	}
+
+	if cmd := graph.ProcNetCMD(graph.UDP); len(cmd) != 3 || cmd[1] != "/proc/net/udp" {
+		t.Fail()
+	}
+}
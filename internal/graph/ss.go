@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/s0rg/set"
+)
+
+const (
+	ssCmd         = "ss"
+	ssArg         = "-anp"
+	ssListen      = "LISTEN"
+	ssEstablished = "ESTAB"
+	ssUsersPrefix = "users:((\""
+)
+
+// SsCMD always asks for both tcp and udp sockets, as with single protocol ss leaves out
+// Netid column, protocols are filtered by caller.
+func SsCMD(_ NetProto) []string {
+	return []string{
+		ssCmd,
+		ssArg + netstatArgFor(TCP|UDP),
+	}
+}
+
+// ParseSS parses ss output, transient tcp sockets are reported only for given states,
+// ownerless ones are attributed to ProcessUnknown.
+func ParseSS(r io.Reader, states set.Unordered[string], cb func(*Connection)) (err error) {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanLines)
+
+	var (
+		conn *Connection
+		ok   bool
+	)
+
+	for s.Scan() {
+		if conn, ok = parseSSConnection(s.Text(), states); !ok {
+			continue
+		}
+
+		cb(conn)
+	}
+
+	if err = s.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+func parseSSConnection(s string, states set.Unordered[string]) (conn *Connection, ok bool) {
+	const minFields = 6
+
+	parts := strings.Fields(s)
+	if len(parts) < minFields {
+		return nil, false
+	}
+
+	conn = &Connection{}
+
+	switch parts[0] {
+	case sTCP:
+		conn.Proto = TCP
+	case sUDP:
+		conn.Proto = UDP
+	default: // header or unknown protocol
+		return nil, false
+	}
+
+	if conn.SrcIP, conn.SrcPort, ok = splitAddr(parts[4]); !ok {
+		return nil, false
+	}
+
+	if conn.DstIP, conn.DstPort, ok = splitAddr(parts[5]); !ok {
+		return nil, false
+	}
+
+	if conn.Proto == TCP {
+		switch parts[1] {
+		case ssListen:
+			conn.Listen = true
+		case ssEstablished:
+		default:
+			state, found := ssStates[parts[1]]
+			if !found {
+				return nil, false
+			}
+
+			conn.State = state
+		}
+	} else {
+		conn.Listen = (conn.DstPort > 0 && conn.SrcPort < conn.DstPort) || conn.DstPort == 0
+	}
+
+	if !matchState(states, conn.State) {
+		return nil, false
+	}
+
+	if conn.Process, ok = splitSSUsers(strings.Join(parts[6:], " ")); !ok {
+		// transient sockets (i.e. TIME_WAIT) usually have no owner
+		if conn.State == "" {
+			return nil, false
+		}
+
+		conn.Process = ProcessUnknown
+	}
+
+	return conn, true
+}
+
+// splitAddr handles all ss/lsof address forms: "1.2.3.4:80", "[::1]:80", "*:80",
+// ":::80" and interface-bound "0.0.0.0%eth0:68".
+func splitAddr(v string) (ip net.IP, port int, ok bool) {
+	idx := strings.LastIndexByte(v, ':')
+	if idx < 0 {
+		return
+	}
+
+	addr, sport := v[:idx], v[idx:]
+
+	if zone := strings.IndexByte(addr, '%'); zone >= 0 {
+		addr = addr[:zone]
+	}
+
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+
+	if addr == "*" {
+		addr = net.IPv4zero.String()
+	}
+
+	return splitIP(addr + sport)
+}
+
+func splitSSUsers(v string) (name string, ok bool) {
+	rest, found := strings.CutPrefix(v, ssUsersPrefix)
+	if !found {
+		return
+	}
+
+	idx := strings.IndexByte(rest, '"')
+	if idx <= 0 {
+		return
+	}
+
+	return rest[:idx], true
+}
@@ -0,0 +1,131 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+func TestParseSS(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`Netid State     Recv-Q Send-Q        Local Address:Port     Peer Address:Port Process
+udp   UNCONN    0      0           127.0.0.11:56688          0.0.0.0:*     users:(("ntpd",pid=11,fd=3))
+udp   UNCONN    0      0        0.0.0.0%eth0:68              0.0.0.0:*     users:(("dhclient",pid=12,fd=6))
+tcp   LISTEN    0      4096           0.0.0.0:2333           0.0.0.0:*     users:(("foo",pid=1,fd=3))
+tcp   LISTEN    0      4096                 *:1666                 *:*     users:(("bar",pid=2,fd=3))
+tcp   TIME-WAIT 0      0         172.20.4.209:48020     172.20.4.198:3306
+tcp   ESTAB     0      0         172.20.4.209:48021     172.20.4.198:3306  users:(("foo",pid=1,fd=7),("foo",pid=3,fd=7))
+tcp   CLOSE-WAIT 1     0         172.20.4.209:43534     172.20.4.129:53    users:(("bar",pid=2,fd=9))
+tcp   LISTEN    0      4096              [::]:6501              [::]:*     users:(("bar",pid=2,fd=4))
+tcp   LISTEN    0      4096                 :::1234                :::*    users:(("foo bar",pid=1,fd=5))
+tcp   ESTAB     0      0    [::ffff:127.0.0.1]:6501  [::ffff:127.0.0.1]:43706 users:(("bar",pid=2,fd=8))
+tcp   ESTAB     0      0         172.20.4.209:43634     172.20.4.129:53
+tcp   ESTAB     0      0         172.20.4.209:43635     172.20.4.129:53    users:((bad
+tcp   ESTAB     0      0              invalid     172.20.4.198:3306  users:(("foo",pid=1,fd=7))
+tcp   ESTAB     0      0         172.20.4.198:bad   172.20.4.198:3306  users:(("foo",pid=1,fd=7))
+tcp   ESTAB     0      0         172.20.4.198:3306  invalid           users:(("foo",pid=1,fd=7))
+u_str ESTAB     0      0                    * 38047            * 0      users:(("init",pid=1,fd=3))
+
+some       garbage
+`)
+
+	con := graph.Container{}
+
+	var names []string
+
+	if err := graph.ParseSS(b, nil, func(c *graph.Connection) {
+		names = append(names, c.Process)
+		con.AddConnection(c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if con.ConnectionsCount() != 7 {
+		t.Log("total:", con.ConnectionsCount())
+		t.Fail()
+	}
+
+	var nlisten, noutbound int
+
+	con.IterListeners(func(_ *graph.Connection) {
+		nlisten++
+	})
+	con.IterOutbounds(func(_ *graph.Connection) {
+		noutbound++
+	})
+
+	if nlisten != 6 || noutbound != 1 {
+		t.Log("listen/outbound:", nlisten, noutbound)
+		t.Fail()
+	}
+
+	if !slices.Contains(names, "foo bar") || !slices.Contains(names, "dhclient") {
+		t.Log("names:", names)
+		t.Fail()
+	}
+}
+
+func TestParseSSStates(t *testing.T) {
+	t.Parallel()
+
+	b := bytes.NewBufferString(`Netid State     Recv-Q Send-Q        Local Address:Port     Peer Address:Port Process
+tcp   TIME-WAIT 0      0         172.20.4.209:48020     172.20.4.198:3306
+tcp   SYN-RECV  0      0         172.20.4.209:5432      172.20.4.130:40100
+tcp   CLOSE-WAIT 1     0         172.20.4.209:43534     172.20.4.129:53    users:(("bar",pid=2,fd=9))
+tcp   ESTAB     0      0         172.20.4.209:43634     172.20.4.129:53
+`)
+
+	states, _ := graph.ParseTCPStates("time-wait,close-wait")
+
+	var conns []*graph.Connection
+
+	if err := graph.ParseSS(b, states, func(c *graph.Connection) {
+		conns = append(conns, c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conns) != 2 {
+		t.Fatal("total:", len(conns))
+	}
+
+	if c := conns[0]; c.Process != graph.ProcessUnknown || c.State != "TIME_WAIT" {
+		t.Fatal("ownerless:", c)
+	}
+
+	if c := conns[1]; c.Process != "bar" || c.State != "CLOSE_WAIT" {
+		t.Fatal("owned:", c)
+	}
+}
+
+func TestParseSSError(t *testing.T) {
+	t.Parallel()
+
+	myErr := errors.New("test-err")
+	reader := &failReader{Err: myErr}
+
+	err := graph.ParseSS(reader, nil, func(*graph.Connection) {})
+	if err == nil {
+		t.Fatal("err == nil")
+	}
+
+	if !errors.Is(err, myErr) {
+		t.Fail()
+	}
+}
+
+func TestSsCMD(t *testing.T) {
+	t.Parallel()
+
+	for _, proto := range []graph.NetProto{graph.TCP | graph.UDP, graph.TCP, graph.UDP} {
+		cmd := graph.SsCMD(proto)
+
+		if len(cmd) != 2 || cmd[0] != "ss" || cmd[1] != "-anptu" {
+			t.Fatal("cmd:", proto, cmd)
+		}
+	}
+}
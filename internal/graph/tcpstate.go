@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/s0rg/set"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+const sAllStates = "ALL"
+
+var (
+	// tcpStates holds transient tcp states (besides LISTEN and ESTABLISHED) in netstat form, such
+	// connections are reported only on demand, see Config.States.
+	tcpStates = []string{
+		node.StateSynSent,
+		node.StateSynRecv,
+		"FIN_WAIT1",
+		"FIN_WAIT2",
+		"TIME_WAIT",
+		"CLOSE_WAIT",
+		"LAST_ACK",
+		"CLOSING",
+	}
+
+	// ssStates maps ss state names to netstat ones.
+	ssStates = map[string]string{
+		"SYN-SENT":   node.StateSynSent,
+		"SYN-RECV":   node.StateSynRecv,
+		"FIN-WAIT-1": "FIN_WAIT1",
+		"FIN-WAIT-2": "FIN_WAIT2",
+		"TIME-WAIT":  "TIME_WAIT",
+		"CLOSE-WAIT": "CLOSE_WAIT",
+		"LAST-ACK":   "LAST_ACK",
+		"CLOSING":    "CLOSING",
+	}
+
+	// kernelStates maps state codes from net/tcp_states.h.
+	kernelStates = map[uint64]string{
+		2:  node.StateSynSent,
+		3:  node.StateSynRecv,
+		4:  "FIN_WAIT1",
+		5:  "FIN_WAIT2",
+		6:  "TIME_WAIT",
+		8:  "CLOSE_WAIT",
+		9:  "LAST_ACK",
+		11: "CLOSING",
+	}
+)
+
+// TCPState returns name for transient tcp state code from net/tcp_states.h.
+func TCPState(code uint64) (name string, ok bool) {
+	name, ok = kernelStates[code]
+
+	return name, ok
+}
+
+func isTCPState(v string) bool {
+	return slices.Contains(tcpStates, v)
+}
+
+// matchState reports whether connection should be reported by parsers, transient ones
+// are kept only on demand.
+func matchState(states set.Unordered[string], state string) bool {
+	return state == "" || states.Has(state)
+}
+
+// ParseTCPStates parses comma-separated list of transient tcp states to keep, names are
+// case-insensitive and accepted both in netstat (time_wait) and ss (time-wait) forms.
+func ParseTCPStates(val string) (rv set.Unordered[string], ok bool) {
+	rv = make(set.Unordered[string])
+
+	for v := range strings.SplitSeq(val, comma) {
+		v = strings.ToUpper(strings.TrimSpace(v))
+
+		if s, found := ssStates[v]; found {
+			v = s
+		}
+
+		switch {
+		case v == sAllStates:
+			set.Load(rv, tcpStates...)
+		case isTCPState(v):
+			rv.Add(v)
+		default:
+			return nil, false
+		}
+	}
+
+	return rv, true
+}
@@ -0,0 +1,54 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/s0rg/decompose/internal/graph"
+)
+
+func TestParseTCPStates(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Val   string
+		Want  []string
+		Valid bool
+	}{
+		{Val: "syn_sent", Valid: true, Want: []string{"SYN_SENT"}},
+		{Val: "time-wait,CLOSE_WAIT", Valid: true, Want: []string{"TIME_WAIT", "CLOSE_WAIT"}},
+		{Val: "fin-wait-1", Valid: true, Want: []string{"FIN_WAIT1"}},
+		{Val: "all", Valid: true, Want: []string{"SYN_SENT", "SYN_RECV", "TIME_WAIT", "CLOSING"}},
+		{Val: "established", Valid: false},
+		{Val: "syn_sent,bad", Valid: false},
+	}
+
+	for i, tc := range testCases {
+		got, ok := graph.ParseTCPStates(tc.Val)
+
+		if ok != tc.Valid {
+			t.Fatalf("case[%d] failed for '%s' want: %t got: %t", i, tc.Val, tc.Valid, ok)
+		}
+
+		if !ok {
+			continue
+		}
+
+		for _, w := range tc.Want {
+			if !got.Has(w) {
+				t.Fatalf("case[%d] failed for '%s' want: %s", i, tc.Val, w)
+			}
+		}
+	}
+}
+
+func TestTCPState(t *testing.T) {
+	t.Parallel()
+
+	if name, ok := graph.TCPState(2); !ok || name != "SYN_SENT" {
+		t.Fatal("syn-sent:", name, ok)
+	}
+
+	if _, ok := graph.TCPState(7); ok { // CLOSE, has no peer
+		t.Fatal("close")
+	}
+}
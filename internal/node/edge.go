@@ -1,9 +1,75 @@
 package node
 
+// EdgeSharesVolume marks edges between containers, that mount same volume or bind path.
+const EdgeSharesVolume = "shares-volume"
+
+// EdgeViaMesh marks app to app edges, collapsed from service mesh proxy hops.
+const EdgeViaMesh = "via-mesh"
+
+// EdgeConntrack marks edges, restored from conntrack flows.
+const EdgeConntrack = "conntrack"
+
+// Changes of elements in diff of two streams, unchanged elements have no change.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+)
+
+// TCP states of connections, that were never established, such edges are attempts.
+const (
+	StateSynSent = "SYN_SENT"
+	StateSynRecv = "SYN_RECV"
+)
+
 type Edge struct {
+	Timespan
 	Port    *Port
 	SrcID   string
 	SrcName string
 	DstID   string
 	DstName string
+	Kind    string
+	State   string // tcp state for transient connections, empty for established ones
+	Change  string // set in diff of two streams
+	Samples int    // number of scan samples, edge was seen in, zero if not sampled
+	Seen    int    // number of merged snapshots, edge was seen in
+	Total   int    // number of merged snapshots, zero if not merged
+}
+
+func (e *Edge) IsShare() bool {
+	return e.Kind == EdgeSharesVolume
+}
+
+func (e *Edge) IsMesh() bool {
+	return e.Kind == EdgeViaMesh
+}
+
+func (e *Edge) IsConntrack() bool {
+	return e.Kind == EdgeConntrack
+}
+
+func (e *Edge) IsAttempt() bool {
+	return e.State == StateSynSent || e.State == StateSynRecv
+}
+
+// Confidence returns share of merged snapshots, edge was seen in, edges without snapshots are certain.
+func (e *Edge) Confidence() float64 {
+	if e.Total == 0 {
+		return 1
+	}
+
+	return float64(e.Seen) / float64(e.Total)
+}
+
+// Merge folds counters of another edge, that stands for same connection, into this one:
+// established state wins over transient, counters keep maximum, timespan is extended.
+func (e *Edge) Merge(o *Edge) {
+	if o.State == "" {
+		e.State = ""
+	}
+
+	e.Samples = max(e.Samples, o.Samples)
+	e.Seen = max(e.Seen, o.Seen)
+	e.Total = max(e.Total, o.Total)
+	e.Extend(o.Timespan)
 }
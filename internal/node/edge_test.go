@@ -0,0 +1,35 @@
+package node_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+func TestEdgeMerge(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	e := &node.Edge{Timespan: node.Stamp(day(5)), State: node.StateSynSent, Samples: 1, Seen: 3, Total: 4}
+
+	e.Merge(&node.Edge{Timespan: node.Stamp(day(2)), State: node.StateSynRecv, Samples: 2, Seen: 1, Total: 4})
+
+	if e.State != node.StateSynSent {
+		t.Fatal("state:", e.State)
+	}
+
+	if e.Samples != 2 || e.Seen != 3 || e.Total != 4 || !e.FirstSeen.Equal(day(2)) || !e.LastSeen.Equal(day(5)) {
+		t.Fatal("counters:", e)
+	}
+
+	// established connection is not an attempt
+	e.Merge(&node.Edge{})
+
+	if e.IsAttempt() {
+		t.Fatal("state:", e.State)
+	}
+}
@@ -0,0 +1,22 @@
+package node
+
+import "time"
+
+// SchemaVersion is a version of json stream format, streams without header are of version 1.
+const SchemaVersion = 2
+
+// Header is an optional leading record of json stream: its format version and scan provenance.
+type Header struct {
+	Scanned time.Time `json:"scanned,omitzero"`  // scan start time, zero for merged streams
+	Version string    `json:"version,omitempty"` // decompose version
+	Mode    string    `json:"mode,omitempty"`    // connections source: nsenter, in-container, sidecar, cri or bare
+	Proto   string    `json:"proto,omitempty"`   // scanned protocols
+	Hosts   []string  `json:"hosts,omitempty"`   // scanned hosts
+	Schema  int       `json:"schema"`
+	Deep    bool      `json:"deep,omitempty"` // process-based introspection
+}
+
+// HeaderJSON is a header record in json stream, it decodes as JSON with Header set.
+type HeaderJSON struct {
+	Header *Header `json:"header"`
+}
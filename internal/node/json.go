@@ -1,9 +1,21 @@
 package node
 
 type Container struct {
-	Cmd    []string          `json:"cmd,omitempty"`
-	Env    []string          `json:"env,omitempty"`
-	Labels map[string]string `json:"labels"`
+	Cmd      []string          `json:"cmd,omitempty"`
+	Env      []string          `json:"env,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Strategy string            `json:"strategy,omitempty"`
+	Host     string            `json:"host,omitempty"`
+	Exposed  []*Exposed        `json:"exposed,omitempty"`
+	Replicas int               `json:"replicas,omitempty"` // set for grouped compose services
+	TimedOut bool              `json:"timed_out,omitempty"`
+}
+
+// Exposed is a container port, published on host.
+type Exposed struct {
+	Kind   string `json:"kind"`
+	Port   int    `json:"port"`
+	Target int    `json:"target"`
 }
 
 type Volume struct {
@@ -20,19 +32,46 @@ type Meta struct {
 }
 
 type Connection struct {
-	Port *Port  `json:"port"`
-	Src  string `json:"src"`
-	Dst  string `json:"dst"`
+	Port    *Port  `json:"port"`
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+	Kind    string `json:"kind,omitempty"`    // empty for network connections
+	State   string `json:"state,omitempty"`   // tcp state, empty for established connections
+	Change  string `json:"change,omitempty"`  // set in diff of two streams
+	Samples int    `json:"samples,omitempty"` // number of scan samples, connection was seen in
+	Seen    int    `json:"seen,omitempty"`    // number of merged snapshots, connection was seen in
+	Total   int    `json:"total,omitempty"`   // number of merged snapshots
+	Timespan
+}
+
+// Equal reports whether connections are same, regardless of their counters and timestamps.
+func (c *Connection) Equal(v *Connection) (yes bool) {
+	return c.Src == v.Src &&
+		c.Dst == v.Dst &&
+		c.Kind == v.Kind &&
+		c.State == v.State &&
+		c.Port.Equal(v.Port)
 }
 
 type JSON struct {
 	Name       string                   `json:"name"`
 	IsExternal bool                     `json:"is_external"`
 	Image      *string                  `json:"image,omitempty"`
+	Cluster    string                   `json:"cluster,omitempty"`
+	Change     string                   `json:"change,omitempty"` // set in diff of two streams
 	Networks   []string                 `json:"networks"`
 	Tags       []string                 `json:"tags"`
 	Volumes    []*Volume                `json:"volumes"`
 	Container  Container                `json:"container"`
 	Listen     map[string][]*Port       `json:"listen"`
 	Connected  map[string][]*Connection `json:"connected"`
+	Network    *Network                 `json:"network,omitempty"` // set for network records only
+	Header     *Header                  `json:"header,omitempty"`  // set for header record only
+	Timespan
+}
+
+// NetworkJSON is a network record in json stream, it decodes as JSON with Network set.
+type NetworkJSON struct {
+	Name    string   `json:"name"`
+	Network *Network `json:"network"`
 }
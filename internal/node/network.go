@@ -0,0 +1,48 @@
+package node
+
+import (
+	"github.com/s0rg/set"
+)
+
+// Network is a network inventory record: its settings and attached nodes.
+type Network struct {
+	Name     string           `json:"name"`
+	Driver   string           `json:"driver"`
+	Host     string           `json:"host,omitempty"`
+	Subnets  []string         `json:"subnets"`
+	Gateways []string         `json:"gateways"`
+	Members  []*NetworkMember `json:"members"`
+	Internal bool             `json:"internal"`
+}
+
+type NetworkMember struct {
+	ID      string   `json:"-"`
+	Name    string   `json:"name"`
+	IP      string   `json:"ip"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+func (n *Network) Label() string {
+	if n.Host == "" {
+		return n.Name
+	}
+
+	return n.Host + "/" + n.Name
+}
+
+// MultiHomed returns ids of nodes, that are attached to more than one network,
+// thus bridge them.
+func MultiHomed(nets []*Network) (rv set.Unordered[string]) {
+	seen := make(set.Unordered[string])
+	rv = make(set.Unordered[string])
+
+	for _, n := range nets {
+		for _, m := range n.Members {
+			if !seen.Add(m.ID) {
+				rv.Add(m.ID)
+			}
+		}
+	}
+
+	return rv
+}
@@ -6,6 +6,7 @@ import (
 )
 
 type Node struct {
+	Timespan
 	Container Container
 	Meta      *Meta
 	Ports     *Ports
@@ -13,6 +14,7 @@ type Node struct {
 	Name      string
 	Image     string
 	Cluster   string
+	Change    string // set in diff of two streams
 	Networks  []string
 	Volumes   []*Volume
 }
@@ -35,10 +37,13 @@ func (n *Node) ToJSON() (rv *JSON) {
 		IsExternal: n.IsExternal(),
 		Networks:   n.Networks,
 		Container:  n.Container,
+		Cluster:    n.Cluster,
+		Change:     n.Change,
 		Listen:     make(map[string][]*Port),
 		Volumes:    []*Volume{},
 		Tags:       []string{},
 		Connected:  make(map[string][]*Connection),
+		Timespan:   n.Timespan,
 	}
 
 	if n.Meta != nil {
@@ -67,6 +72,7 @@ func (n *Node) ToView() (rv *View) {
 		Name:       n.Name,
 		Image:      n.Image,
 		Listen:     n.Ports,
+		Labels:     n.Container.Labels,
 		IsExternal: n.IsExternal(),
 	}
 
@@ -6,15 +6,20 @@ import (
 	"strconv"
 )
 
+// KindVolume is a port kind for shared volumes, its value is a mount source.
+const KindVolume = "volume"
+
 type portJSON struct {
-	Kind  string `json:"kind"`
-	Value string `json:"value"`
-	Local bool   `json:"local"`
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Change string `json:"change,omitempty"`
+	Local  bool   `json:"local"`
 }
 
 type Port struct {
 	Kind   string `json:"kind"`
 	Value  string `json:"value"`
+	Change string `json:"change,omitempty"` // set in diff of two streams
 	Number int    `json:"-"`
 	Local  bool   `json:"local"`
 }
@@ -37,7 +42,7 @@ func (p *Port) UnmarshalJSON(b []byte) (err error) {
 
 	const sUNIX = "unix"
 
-	if v.Kind != sUNIX {
+	if v.Kind != sUNIX && v.Kind != KindVolume {
 		if p.Number, err = strconv.Atoi(v.Value); err != nil {
 			return fmt.Errorf("invalid port: '%s' atoi: %w", v.Value, err)
 		}
@@ -46,6 +51,11 @@ func (p *Port) UnmarshalJSON(b []byte) (err error) {
 	p.Kind = v.Kind
 	p.Value = v.Value
 	p.Local = v.Local
+	p.Change = v.Change
 
 	return nil
 }
+
+func (e *Exposed) Label() string {
+	return e.Kind + ":" + strconv.Itoa(e.Port) + "->" + strconv.Itoa(e.Target)
+}
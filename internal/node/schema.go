@@ -0,0 +1,150 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+	schemaRefs  = "#/$defs/"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+type schemaGen struct {
+	defs map[string]any
+}
+
+// Schema returns JSON Schema of json stream record, generated from stream types.
+func Schema() (rv []byte, err error) {
+	gen := &schemaGen{
+		defs: make(map[string]any),
+	}
+
+	records := []any{
+		gen.ref(reflect.TypeFor[HeaderJSON]()),
+		gen.ref(reflect.TypeFor[JSON]()),
+		gen.ref(reflect.TypeFor[NetworkJSON]()),
+	}
+
+	schema := map[string]any{
+		"$schema":     schemaDraft,
+		"title":       "decompose json stream record",
+		"description": fmt.Sprintf("schema version %d: optional header, then nodes and networks", SchemaVersion),
+		"oneOf":       records,
+		"$defs":       gen.defs,
+	}
+
+	if rv, err = json.MarshalIndent(schema, "", "  "); err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return rv, nil
+}
+
+// ref returns reference to named struct definition, creating it on first use.
+func (g *schemaGen) ref(t reflect.Type) map[string]any {
+	name := t.Name()
+
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = nil // placeholder for recursive types
+		g.defs[name] = g.object(t)
+	}
+
+	return map[string]any{"$ref": schemaRefs + name}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	required := []string{}
+
+	g.fields(t, props, &required)
+
+	rv := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+
+	if len(required) > 0 {
+		rv["required"] = required
+	}
+
+	return rv
+}
+
+// fields collects json properties of struct, embedded structs are flattened.
+func (g *schemaGen) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			g.fields(f.Type, props, required)
+
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = g.value(f.Type)
+
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func (g *schemaGen) value(t reflect.Type) any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.value(t.Elem()))
+	case reflect.Slice:
+		return nullable(map[string]any{"type": "array", "items": g.value(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]any{"type": "object", "additionalProperties": g.value(t.Elem())})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		return g.ref(t)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// nullable allows null in place of value, as go encodes nil pointers, slices and maps.
+func nullable(v any) any {
+	return map[string]any{
+		"anyOf": []any{v, map[string]any{"type": "null"}},
+	}
+}
@@ -0,0 +1,63 @@
+package node_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	raw, err := node.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"$defs"`
+		OneOf []json.RawMessage `json:"oneOf"`
+	}
+
+	if err = json.Unmarshal(raw, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schema.OneOf) != 3 {
+		t.Fatal("records:", len(schema.OneOf))
+	}
+
+	hdr, ok := schema.Defs["Header"]
+	if !ok || len(hdr.Required) != 1 || hdr.Required[0] != "schema" {
+		t.Fatal("header:", hdr)
+	}
+
+	conn, ok := schema.Defs["Connection"]
+	if !ok {
+		t.Fatal("no connection")
+	}
+
+	// embedded timespan is flattened
+	if _, ok = conn.Properties["first_seen"]; !ok {
+		t.Fatal("connection:", conn.Properties)
+	}
+
+	if _, ok = schema.Defs["Port"].Properties["number"]; ok {
+		t.Fatal("hidden field in schema")
+	}
+
+	published, err := os.ReadFile("../../examples/stream.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(published), raw) {
+		t.Fatal("published schema is outdated, run 'make schema'")
+	}
+}
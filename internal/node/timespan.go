@@ -0,0 +1,42 @@
+package node
+
+import "time"
+
+// Timespan is a period, node or connection was seen in scans, zero for streams without timestamps.
+type Timespan struct {
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastSeen  time.Time `json:"last_seen,omitzero"`
+}
+
+// Stamp returns timespan of single scan at given time.
+func Stamp(at time.Time) Timespan {
+	return Timespan{
+		FirstSeen: at,
+		LastSeen:  at,
+	}
+}
+
+// Extend widens timespan to cover another one.
+func (t *Timespan) Extend(o Timespan) {
+	if !o.FirstSeen.IsZero() && (t.FirstSeen.IsZero() || o.FirstSeen.Before(t.FirstSeen)) {
+		t.FirstSeen = o.FirstSeen
+	}
+
+	if o.LastSeen.After(t.LastSeen) {
+		t.LastSeen = o.LastSeen
+	}
+}
+
+// Within reports whether timespan overlaps [since, until] window, zero bounds are open,
+// zero timespan is always within.
+func (t *Timespan) Within(since, until time.Time) (yes bool) {
+	if t.FirstSeen.IsZero() && t.LastSeen.IsZero() {
+		return true
+	}
+
+	if !since.IsZero() && t.LastSeen.Before(since) {
+		return false
+	}
+
+	return until.IsZero() || !t.FirstSeen.After(until)
+}
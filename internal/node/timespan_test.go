@@ -0,0 +1,59 @@
+package node_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s0rg/decompose/internal/node"
+)
+
+func TestTimespanExtend(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	var ts node.Timespan
+
+	ts.Extend(node.Timespan{})
+
+	if !ts.FirstSeen.IsZero() || !ts.LastSeen.IsZero() {
+		t.Fatal("zero:", ts)
+	}
+
+	ts.Extend(node.Stamp(day(5)))
+	ts.Extend(node.Stamp(day(2)))
+	ts.Extend(node.Stamp(day(9)))
+	ts.Extend(node.Timespan{})
+
+	if !ts.FirstSeen.Equal(day(2)) || !ts.LastSeen.Equal(day(9)) {
+		t.Fatal("extend:", ts)
+	}
+
+	testCases := []struct {
+		Since, Until time.Time
+		Want         bool
+	}{
+		{Want: true},
+		{Since: day(1), Want: true},
+		{Since: day(9), Want: true},
+		{Since: day(10), Want: false},
+		{Until: day(2), Want: true},
+		{Until: day(1), Want: false},
+		{Since: day(3), Until: day(4), Want: true},
+		{Since: day(10), Until: day(12), Want: false},
+	}
+
+	for i, tc := range testCases {
+		if got := ts.Within(tc.Since, tc.Until); got != tc.Want {
+			t.Fatalf("case %d: got %v, want %v", i, got, tc.Want)
+		}
+	}
+
+	var unknown node.Timespan
+
+	if !unknown.Within(day(10), day(12)) {
+		t.Fatal("unknown timespan filtered")
+	}
+}
@@ -7,6 +7,7 @@ type PortMatcher interface {
 
 type View struct {
 	Listen     PortMatcher
+	Labels     map[string]string
 	Name       string
 	Image      string
 	Cmd        string
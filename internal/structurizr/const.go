@@ -14,4 +14,14 @@ const (
 	headerWorkspace = "workspace"
 	headerModel     = "model"
 	headerViews     = "views"
+
+	kindColor = "#006400"
 )
+
+const tagRemoved = "removed"
+
+// changeColors are colors of elements and relations, tagged as added or removed in diff.
+var changeColors = map[string]string{
+	"added":    "#2e8b57",
+	tagRemoved: "#a9a9a9",
+}
@@ -1,7 +1,20 @@
 package srtructurizr
 
+import "cmp"
+
 type Relation struct {
 	Src  string
 	Dst  string
+	Kind string // styled relation kind, i.e. "shares-volume"
 	Tags []string
 }
+
+// relKey identifies relation destination, relations of different kinds are kept apart.
+type relKey struct {
+	ID   string
+	Kind string
+}
+
+func compareKeys(a, b relKey) int {
+	return cmp.Or(cmp.Compare(a.ID, b.ID), cmp.Compare(a.Kind, b.Kind))
+}
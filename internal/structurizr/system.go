@@ -7,7 +7,7 @@ import (
 
 type System struct {
 	containers    map[string]*Container
-	relationships map[string]map[string]*Relation
+	relationships map[string]map[relKey]*Relation
 	ID            string
 	Name          string
 	Description   string
@@ -20,7 +20,7 @@ func NewSystem(name string) *System {
 		ID:            SafeID(name),
 		Name:          name,
 		containers:    make(map[string]*Container),
-		relationships: make(map[string]map[string]*Relation),
+		relationships: make(map[string]map[relKey]*Relation),
 	}
 }
 
@@ -43,7 +43,7 @@ func (s *System) AddContainer(id, name string) (c *Container, ok bool) {
 	return c, true
 }
 
-func (s *System) AddRelation(srcID, dstID, srcName, dstName string) (rv *Relation, ok bool) {
+func (s *System) AddRelation(srcID, dstID, srcName, dstName, kind string) (rv *Relation, ok bool) {
 	src, ok := s.containers[SafeID(srcID)]
 	if !ok {
 		return nil, false
@@ -54,7 +54,7 @@ func (s *System) AddRelation(srcID, dstID, srcName, dstName string) (rv *Relatio
 		return nil, false
 	}
 
-	if rv, ok = s.findRelation(src.ID, dst.ID); ok {
+	if rv, ok = s.findRelation(src.ID, dst.ID, kind); ok {
 		return rv, true
 	}
 
@@ -62,18 +62,21 @@ func (s *System) AddRelation(srcID, dstID, srcName, dstName string) (rv *Relatio
 
 	dest, ok := s.relationships[srcID]
 	if !ok {
-		dest = make(map[string]*Relation)
+		dest = make(map[relKey]*Relation)
 	}
 
-	rv, ok = dest[dstID]
+	key := relKey{ID: dstID, Kind: kind}
+
+	rv, ok = dest[key]
 	if !ok {
 		rv = &Relation{
-			Src: srcName,
-			Dst: dstName,
+			Src:  srcName,
+			Dst:  dstName,
+			Kind: kind,
 		}
 	}
 
-	dest[dstID] = rv
+	dest[key] = rv
 
 	s.relationships[srcID] = dest
 
@@ -111,24 +114,24 @@ func (s *System) WriteViews(w io.Writer, level int) {
 
 func (s *System) WriteRelations(w io.Writer, level int) {
 	for srcID, dest := range s.relationships {
-		for dstID, rel := range dest {
-			putRelation(w, level, srcID, dstID, rel.Tags)
+		for key, rel := range dest {
+			putRelation(w, level, srcID, key.ID, rel)
 			putEnd(w, level)
 		}
 	}
 }
 
-func (s *System) findRelation(src, dst string) (rv *Relation, found bool) {
+func (s *System) findRelation(src, dst, kind string) (rv *Relation, found bool) {
 	src, dst = SafeID(src), SafeID(dst)
 
 	if dest, ok := s.relationships[src]; ok {
-		if rel, ok := dest[dst]; ok {
+		if rel, ok := dest[relKey{ID: dst, Kind: kind}]; ok {
 			return rel, true
 		}
 	}
 
 	if dest, ok := s.relationships[dst]; ok {
-		if rel, ok := dest[src]; ok {
+		if rel, ok := dest[relKey{ID: src, Kind: kind}]; ok {
 			return rel, true
 		}
 	}
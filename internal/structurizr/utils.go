@@ -85,10 +85,14 @@ func putRelation(
 	w io.Writer,
 	level int,
 	src, dst string,
-	tags []string,
+	rel *Relation,
 ) {
 	fmt.Fprint(w, strings.Repeat(tab, level))
-	fmt.Fprintf(w, "%s -> %s \"%s\" {\n", src, dst, strings.Join(tags, ","))
+	fmt.Fprintf(w, "%s -> %s \"%s\" {\n", src, dst, strings.Join(rel.Tags, ","))
+
+	if rel.Kind != "" {
+		putKey(w, level+1, keyTags, rel.Kind)
+	}
 }
 
 func putEnd(w io.Writer, level int) {
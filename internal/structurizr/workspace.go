@@ -8,7 +8,7 @@ import (
 )
 
 type Workspace struct {
-	relationships map[string]map[string]*Relation
+	relationships map[string]map[relKey]*Relation
 	systems       map[string]*System
 	Name          string
 	Description   string
@@ -22,7 +22,7 @@ func NewWorkspace(name, defaultSystem string) *Workspace {
 		defaultSystem: defaultSystem,
 		systemsOrder:  []string{SafeID(defaultSystem)},
 		systems:       make(map[string]*System),
-		relationships: make(map[string]map[string]*Relation),
+		relationships: make(map[string]map[relKey]*Relation),
 	}
 }
 
@@ -50,7 +50,7 @@ func (ws *Workspace) HasSystem(name string) (yes bool) {
 	return
 }
 
-func (ws *Workspace) AddRelation(srcID, dstID, srcName, dstName string) (rv *Relation, ok bool) {
+func (ws *Workspace) AddRelation(srcID, dstID, srcName, dstName, kind string) (rv *Relation, ok bool) {
 	srcID, dstID = SafeID(srcID), SafeID(dstID)
 
 	if !ws.HasSystem(srcID) || !ws.HasSystem(dstID) {
@@ -59,20 +59,23 @@ func (ws *Workspace) AddRelation(srcID, dstID, srcName, dstName string) (rv *Rel
 
 	dmap, ok := ws.relationships[srcID]
 	if !ok {
-		dmap = make(map[string]*Relation)
+		dmap = make(map[relKey]*Relation)
 		ws.relationships[srcID] = dmap
 	}
 
-	if rv, ok = dmap[dstID]; ok {
+	key := relKey{ID: dstID, Kind: kind}
+
+	if rv, ok = dmap[key]; ok {
 		return rv, ok
 	}
 
 	rv = &Relation{
-		Src: srcName,
-		Dst: dstName,
+		Src:  srcName,
+		Dst:  dstName,
+		Kind: kind,
 	}
 
-	dmap[dstID] = rv
+	dmap[key] = rv
 
 	return rv, true
 }
@@ -134,18 +137,18 @@ func (ws *Workspace) writeRelations(w io.Writer, level int) {
 	for _, srcID := range relOrder {
 		dest := ws.relationships[srcID]
 
-		dstOrder := make([]string, 0, len(dest))
+		dstOrder := make([]relKey, 0, len(dest))
 
-		for dstID := range dest {
-			dstOrder = append(dstOrder, dstID)
+		for key := range dest {
+			dstOrder = append(dstOrder, key)
 		}
 
-		slices.SortFunc(dstOrder, cmp.Compare)
+		slices.SortFunc(dstOrder, compareKeys)
 
-		for _, dstID := range dstOrder {
-			rel := dest[dstID]
+		for _, key := range dstOrder {
+			rel := dest[key]
 
-			putRelation(w, level, srcID, dstID, rel.Tags)
+			putRelation(w, level, srcID, key.ID, rel)
 			putEnd(w, level)
 		}
 	}
@@ -215,6 +218,36 @@ func (ws *Workspace) writeViews(w io.Writer, level int) {
 
 	putEnd(w, level) // element
 
+	for _, tag := range ws.changeTags() {
+		putRaw(w, level, `element "`+tag+`" {`)
+
+		level++
+
+		putRaw(w, level, "color "+changeColors[tag])
+		putRaw(w, level, "stroke "+changeColors[tag])
+
+		if tag == tagRemoved {
+			putRaw(w, level, "border dashed")
+		}
+
+		level--
+
+		putEnd(w, level) // element
+	}
+
+	for _, kind := range ws.relationKinds() {
+		putRaw(w, level, `relationship "`+kind+`" {`)
+
+		level++
+
+		putRaw(w, level, "style dashed")
+		putRaw(w, level, "color "+cmp.Or(changeColors[kind], kindColor))
+
+		level--
+
+		putEnd(w, level) // relationship
+	}
+
 	level--
 
 	putEnd(w, level) // styles
@@ -223,3 +256,42 @@ func (ws *Workspace) writeViews(w io.Writer, level int) {
 
 	putEnd(w, level) // views
 }
+
+// changeTags returns change tags of containers, if any.
+func (ws *Workspace) changeTags() (rv []string) {
+	for _, sys := range ws.systems {
+		for _, c := range sys.containers {
+			for _, tag := range c.Tags {
+				if _, ok := changeColors[tag]; ok && !slices.Contains(rv, tag) {
+					rv = append(rv, tag)
+				}
+			}
+		}
+	}
+
+	slices.Sort(rv)
+
+	return rv
+}
+
+func (ws *Workspace) relationKinds() (rv []string) {
+	add := func(rels map[string]map[relKey]*Relation) {
+		for _, dest := range rels {
+			for _, rel := range dest {
+				if rel.Kind != "" && !slices.Contains(rv, rel.Kind) {
+					rv = append(rv, rel.Kind)
+				}
+			}
+		}
+	}
+
+	add(ws.relationships)
+
+	for _, sys := range ws.systems {
+		add(sys.relationships)
+	}
+
+	slices.Sort(rv)
+
+	return rv
+}
@@ -13,7 +13,7 @@ func TestWorkspaceRelation(t *testing.T) {
 
 	ws := srtructurizr.NewWorkspace("test", "1")
 
-	if _, ok := ws.AddRelation("foo", "bar", "foo", "bar"); ok {
+	if _, ok := ws.AddRelation("foo", "bar", "foo", "bar", ""); ok {
 		t.Fail()
 	}
 
@@ -22,15 +22,15 @@ func TestWorkspaceRelation(t *testing.T) {
 
 	s.Tags = append(s.Tags, "")
 
-	if _, ok := ws.AddRelation("1", "2", "1", "2"); !ok {
+	if _, ok := ws.AddRelation("1", "2", "1", "2", ""); !ok {
 		t.Fail()
 	}
 
-	if _, ok := ws.AddRelation("2", "1", "2", "1"); !ok {
+	if _, ok := ws.AddRelation("2", "1", "2", "1", ""); !ok {
 		t.Fail()
 	}
 
-	if _, ok := ws.AddRelation("1", "2", "1", "2"); !ok {
+	if _, ok := ws.AddRelation("1", "2", "1", "2", ""); !ok {
 		t.Fail()
 	}
 
@@ -42,3 +42,29 @@ func TestWorkspaceRelation(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestWorkspaceChangeStyles(t *testing.T) {
+	t.Parallel()
+
+	ws := srtructurizr.NewWorkspace("test", "1")
+	s := ws.System("1")
+
+	c, _ := s.AddContainer("a", "a")
+	c.Tags = append(c.Tags, "removed")
+
+	s.AddContainer("b", "b")
+
+	if _, ok := s.AddRelation("a", "b", "a", "b", "removed"); !ok {
+		t.Fail()
+	}
+
+	var b bytes.Buffer
+
+	ws.Write(&b)
+
+	for _, want := range []string{"stroke #a9a9a9", "border dashed", "color #a9a9a9"} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatal("no", want, "in:", b.String())
+		}
+	}
+}